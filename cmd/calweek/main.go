@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"os"
 	"time"
+
+	"github.com/ibihim/go-scripts/pkg/config"
 )
 
 func main() {
@@ -22,12 +24,21 @@ func main() {
 	flag.BoolVar(&showHelp, "help", false, "Show detailed help information")
 	flag.BoolVar(&numberOnly, "n", false, "Print only the week number (no text)")
 	flag.Parse()
+	settings := config.MustLoad("calweek", flag.CommandLine)
 
 	if showHelp {
 		printHelp()
 		return
 	}
 
+	if config.IsShowCommand(flag.Args()) {
+		if err := settings.Show(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error showing config: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	var t time.Time
 	var err error
 
@@ -10,11 +10,13 @@ import (
 	"fmt"
 	"io"
 	"iter"
-	"log"
 	"maps"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/ibihim/go-scripts/pkg/config"
+	"github.com/ibihim/go-scripts/pkg/logging"
 )
 
 // Options holds the raw command-line flag values.
@@ -23,6 +25,9 @@ type Options struct {
 	Dest            string
 	IncludePatterns []string
 	ExcludePatterns []string
+	Logging         *logging.Options
+	// Settings are the effective settings, including config file and environment
+	Settings *config.Config
 }
 
 // ParseOptions reads the command-line flags and returns an Options instance.
@@ -35,7 +40,10 @@ func ParseOptions() *Options {
 	destPtr := flag.String("dest", "", "Path to the destination directory for flattened files")
 	includePtr := flag.String("include", "", "Comma-separated list of glob patterns to include (e.g. '*.go')")
 	excludePtr := flag.String("exclude", "", "Comma-separated list of glob patterns to exclude (e.g. '*_test.go')")
+	loggingOpts := logging.NewOptions()
+	loggingOpts.AddFlags(flag.CommandLine)
 	flag.Parse()
+	settings := config.MustLoad("flatten", flag.CommandLine)
 
 	if *helpFlag {
 		flag.Usage()
@@ -47,6 +55,8 @@ func ParseOptions() *Options {
 		Dest:            *destPtr,
 		IncludePatterns: parseCommaSeparated(*includePtr),
 		ExcludePatterns: parseCommaSeparated(*excludePtr),
+		Logging:         loggingOpts,
+		Settings:        settings,
 	}
 	return opts
 }
@@ -73,7 +83,7 @@ func (o *Options) Validate() error {
 	if o.Source == "" {
 		return fmt.Errorf("source directory must be provided")
 	}
-	return nil
+	return o.Logging.Validate()
 }
 
 // Config holds the processed configuration for file flattening.
@@ -110,8 +120,15 @@ func (o *Options) Config() *Config {
 func main() {
 	// Parse and validate command-line options.
 	opts := ParseOptions()
+	logger := opts.Logging.Setup()
+	if config.IsShowCommand(flag.Args()) {
+		if err := opts.Settings.Show(os.Stdout); err != nil {
+			logging.Fatal(logger, "Failed to show config", err)
+		}
+		return
+	}
 	if err := opts.Validate(); err != nil {
-		log.Fatalf("Invalid options: %v", err)
+		logging.Fatal(logger, "Invalid options", err)
 	}
 
 	// Create a Config instance from Options.
@@ -119,7 +136,7 @@ func main() {
 
 	// Create the destination directory if it doesn't exist.
 	if err := os.MkdirAll(config.Dest, os.ModePerm); err != nil {
-		log.Fatalf("Failed to create destination directory: %v", err)
+		logging.Fatal(logger, "Failed to create destination directory", err)
 	}
 
 	// Walk the source directory.
@@ -172,14 +189,16 @@ func main() {
 		destPath = resolveCollision(destPath)
 
 		// Copy the file while preserving permissions.
+		logger.Debug("Copying file", "source", path, "dest", destPath)
 		if err := copyFile(path, destPath); err != nil {
 			return fmt.Errorf("failed to copy %s to %s: %w", path, destPath, err)
 		}
 		return nil
 	})
 	if err != nil {
-		log.Fatalf("Error processing files: %v", err)
+		logging.Fatal(logger, "Error processing files", err)
 	}
+	logger.Info("Flattened files", "source", config.Source, "dest", config.Dest)
 }
 
 // flattenName converts a relative path into a flat file name by replacing all
@@ -6,11 +6,13 @@ import (
 	"encoding/csv"
 	"flag"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
 	"text/template"
+
+	"github.com/ibihim/go-scripts/pkg/config"
+	"github.com/ibihim/go-scripts/pkg/logging"
 )
 
 // OReillyCsvAnnotation represents a single book annotation exported from O'Reilly Learning platform
@@ -37,11 +39,23 @@ func main() {
 	// Define command line flags
 	inputFile := flag.String("input", "", "Path to the CSV file exported from O'Reilly Learning")
 	outputFile := flag.String("output", "", "Path for the output Markdown file (optional)")
+	loggingOpts := logging.NewOptions()
+	loggingOpts.AddFlags(flag.CommandLine)
 	flag.Parse()
 
+	settings := config.MustLoad("oreilly-quotes", flag.CommandLine)
+	logger := loggingOpts.Setup()
+
+	if config.IsShowCommand(flag.Args()) {
+		if err := settings.Show(os.Stdout); err != nil {
+			logging.Fatal(logger, "Failed to show config", err)
+		}
+		return
+	}
+
 	if *inputFile == "" {
-		fmt.Println("Please specify an input CSV file using -input flag")
-		fmt.Println("Example: oreilly-md -input my_annotations.csv -output notes.md")
+		fmt.Fprintln(os.Stderr, "Please specify an input CSV file using -input flag")
+		fmt.Fprintln(os.Stderr, "Example: oreilly-md -input my_annotations.csv -output notes.md")
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
@@ -49,13 +63,13 @@ func main() {
 	// Read and parse the O'Reilly CSV file
 	annotations, err := parseOReillyCsvAnnotations(*inputFile)
 	if err != nil {
-		log.Fatalf("Error reading O'Reilly annotations CSV: %v", err)
+		logging.Fatal(logger, "Error reading O'Reilly annotations CSV", err)
 	}
 
 	// Generate personal markdown format
 	markdownContent, err := convertToPersonalMarkdownFormat(annotations)
 	if err != nil {
-		log.Fatalf("Error generating markdown in personal format: %v", err)
+		logging.Fatal(logger, "Error generating markdown in personal format", err)
 	}
 
 	// Determine output destination
@@ -68,7 +82,7 @@ func main() {
 	// Write the markdown to the output file
 	err = os.WriteFile(*outputFile, []byte(markdownContent), 0644)
 	if err != nil {
-		log.Fatalf("Error writing to output file: %v", err)
+		logging.Fatal(logger, "Error writing to output file", err)
 	}
 
 	fmt.Printf("Successfully converted %d O'Reilly annotations to personal markdown format.\n", 
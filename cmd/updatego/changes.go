@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ibihim/go-scripts/pkg/config"
+	"github.com/ibihim/go-scripts/pkg/gotools"
+)
+
+// changes prints what changed between two Go versions
+func changes(opts options, args []string) error {
+	fs := flag.NewFlagSet("changes", flag.ExitOnError)
+	refresh := fs.Bool("refresh", false, "Refresh the cached release history before looking up changes")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: updatego changes [options] <from> <to>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	config.MustLoad("updatego.changes", fs)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("expected two versions, got %d arguments", fs.NArg())
+	}
+
+	ctx, cancel := signalContext(2 * time.Minute)
+	defer cancel()
+
+	return printChanges(ctx, opts, os.Stdout, fs.Arg(0), fs.Arg(1), *refresh)
+}
+
+// printChanges writes a summary of the releases after from up to to
+func printChanges(ctx context.Context, opts options, w io.Writer, from, to string, refresh bool) error {
+	notes, err := gotools.NewReleaseNotes()
+	if err != nil {
+		return err
+	}
+	notes.Logger = opts.logger
+	notes.SetHTTPClient(opts.client)
+
+	releases, err := notes.Changes(ctx, from, to, refresh)
+	if err != nil {
+		return fmt.Errorf("failed to look up changes: %w", err)
+	}
+
+	fmt.Fprintf(w, "Changes from %s to %s:\n", from, to)
+	if len(releases) == 0 {
+		fmt.Fprintln(w, "  no releases in between")
+		return nil
+	}
+
+	for _, release := range releases {
+		security := ""
+		if release.Security {
+			security = " [security]"
+		}
+		fmt.Fprintf(w, "\n  go%s (%s)%s\n", release.Version, release.Date, security)
+
+		if !release.Patch() {
+			fmt.Fprintf(w, "    Release notes: %s\n", release.NotesURL())
+			continue
+		}
+
+		fmt.Fprintf(w, "    %s\n", release.Summary)
+		for _, issue := range release.Issues {
+			fmt.Fprintf(w, "    #%d %s\n", issue.Number, issue.Title)
+		}
+		if release.Issues == nil {
+			fmt.Fprintf(w, "    Fixed issues: %s\n", release.MilestoneURL())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ibihim/go-scripts/pkg/config"
+	"github.com/ibihim/go-scripts/pkg/gotools"
+)
+
+// Exit codes of the check command, 1 and 2 are taken by errors and usage
+const (
+	exitUpToDate        = 0
+	exitUpdateAvailable = 3
+	exitVulnerable      = 4
+)
+
+// check reports whether an update is available and, given a vulnerability
+// database, which known vulnerabilities the installed version has. The exit
+// code tells scripts whether to act.
+func check(opts options, args []string) (_ int, err error) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	vulnDB := fs.String("vulndb", localVulnDB(),
+		"Local Go vulnerability database, a directory or zip file (default $GOVULNDB if local)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: updatego check [options]")
+		fmt.Fprintln(fs.Output())
+		fmt.Fprintf(fs.Output(), "Exits with %d if Go is up to date, %d if an update is available and %d if\n",
+			exitUpToDate, exitUpdateAvailable, exitVulnerable)
+		fmt.Fprintln(fs.Output(), "the installed version has known vulnerabilities.")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	config.MustLoad("updatego.check", fs)
+
+	ctx, cancel := signalContext(time.Minute)
+	defer cancel()
+
+	updater, err := newUpdater(opts)
+	if err != nil {
+		return 0, err
+	}
+	metrics := newMetricsRecorder(opts, updater)
+	defer func() { metrics.write(err) }()
+
+	if *vulnDB != "" {
+		updater.VulnDB, err = gotools.OpenVulnDB(*vulnDB)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	status, err := updater.Check(ctx)
+	if err != nil {
+		return 0, err
+	}
+	metrics.setStatus(status)
+
+	fmt.Printf("Current version: %s\n", installedVersion(status))
+	fmt.Printf("Latest version: %s\n", status.Latest)
+	fmt.Printf("Update needed: %t\n", status.NeedsUpdate)
+
+	if updater.VulnDB != nil {
+		printVulnerabilities(status)
+	}
+
+	switch {
+	case status.Vulnerable():
+		return exitVulnerable, nil
+	case status.NeedsUpdate:
+		return exitUpdateAvailable, nil
+	default:
+		return exitUpToDate, nil
+	}
+}
+
+// installedVersion returns the installed version for display
+func installedVersion(status *gotools.Status) string {
+	if status.Installed == "" {
+		return "none"
+	}
+	return status.Installed
+}
+
+// localVulnDB returns $GOVULNDB unless it points to a remote database
+func localVulnDB() string {
+	db := os.Getenv("GOVULNDB")
+	if strings.Contains(db, "://") && !strings.HasPrefix(db, "file://") {
+		return ""
+	}
+	return db
+}
+
+func printVulnerabilities(status *gotools.Status) {
+	if !status.Vulnerable() {
+		fmt.Println("Known vulnerabilities: none")
+		return
+	}
+
+	fmt.Printf("Known vulnerabilities: your Go %s has %d known vulnerabilities", status.Installed, len(status.Vulnerabilities))
+	if status.NeedsUpdate {
+		fmt.Printf(", update to %s as soon as possible", status.Latest)
+	}
+	fmt.Println()
+
+	for _, vuln := range status.Vulnerabilities {
+		ids := vuln.ID
+		if len(vuln.Aliases) > 0 {
+			ids += " (" + strings.Join(vuln.Aliases, ", ") + ")"
+		}
+		fixed := "no fix released"
+		if vuln.Fixed != "" {
+			fixed = "fixed in " + vuln.Fixed
+		}
+		fmt.Printf("  %s: %s [%s]\n", ids, vuln.Summary, fixed)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibihim/go-scripts/pkg/gotools"
+)
+
+// TestCheckOffline runs check like the command line does while go.dev can't
+// be reached. It must fall back to the cached release metadata once the
+// revalidation timed out, before the retries use up the deadline.
+func TestCheckOffline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("GO_SCRIPTS_CONFIG", "")
+	cacheHome := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheHome)
+	t.Setenv("GOVULNDB", "")
+
+	// The cached release is newer than any installed Go.
+	cache, err := json.Marshal(gotools.ReleaseCache{
+		URL:      gotools.DefaultReleasesURL,
+		Fetched:  time.Now().Add(-30 * 24 * time.Hour),
+		Releases: json.RawMessage(`[{"version": "go1.999.0", "stable": true}]`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cachePath := filepath.Join(cacheHome, "go-scripts", "releases.json")
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cachePath, cache, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing listens on port 1, every request is refused.
+	client, err := gotools.NewHTTPClientWithOptions(gotools.HTTPOptions{
+		Proxies: map[string]string{"*": "http://127.0.0.1:1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs := &strings.Builder{}
+	opts := options{
+		installDir:        t.TempDir(),
+		binDir:            t.TempDir(),
+		connections:       4,
+		retry:             gotools.RetryPolicy{Interval: 10 * time.Millisecond, Timeout: 5 * time.Second},
+		revalidateTimeout: 100 * time.Millisecond,
+		logger:            slog.New(slog.NewTextHandler(logs, nil)),
+		client:            client,
+	}
+
+	start := time.Now()
+	code, err := check(opts, nil)
+	if err != nil {
+		t.Fatalf("check() error = %v\n%s", err, logs)
+	}
+	if code != exitUpdateAvailable {
+		t.Errorf("check() = %d, want %d", code, exitUpdateAvailable)
+	}
+	if !strings.Contains(logs.String(), "using cached copy") {
+		t.Errorf("check() didn't warn about the cached copy:\n%s", logs)
+	}
+	if elapsed := time.Since(start); elapsed >= opts.retry.Timeout {
+		t.Errorf("check() took %s, the retries used up its deadline", elapsed)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/ibihim/go-scripts/pkg/config"
+	"github.com/ibihim/go-scripts/pkg/gotools"
+)
+
+// cleanup removes the temporary download directories of runs that died
+// before they could remove them
+func cleanup(opts options, args []string) error {
+	fs := flag.NewFlagSet("cleanup", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "Only show what would be removed")
+	minAge := fs.Duration("min-age", time.Hour, "Minimum age of directories without a known owner")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: updatego cleanup [-dry-run] [-min-age duration]")
+		fmt.Fprintln(fs.Output())
+		fmt.Fprintf(fs.Output(), "Removes %s directories in the temporary directory whose run is gone.\n", gotools.TempPattern)
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	config.MustLoad("updatego.cleanup", fs)
+
+	artifacts, err := gotools.FindTempArtifacts(opts.tempDir, *minAge)
+	if err != nil {
+		return err
+	}
+	if len(artifacts) == 0 {
+		fmt.Println("No temporary files left behind")
+		return nil
+	}
+
+	for _, artifact := range artifacts {
+		fmt.Printf("%s (%s)\n", artifact.Path, artifact.ModTime.Format(time.DateTime))
+	}
+
+	if *dryRun {
+		fmt.Printf("Would free %s\n", gotools.TempArtifactsSize(artifacts))
+		return nil
+	}
+
+	opts.logger.Info("Removing temporary files", "count", len(artifacts))
+	if err := gotools.RemoveTempArtifacts(artifacts); err != nil {
+		return err
+	}
+	fmt.Printf("Freed %s\n", gotools.TempArtifactsSize(artifacts))
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ibihim/go-scripts/pkg/config"
+	"github.com/ibihim/go-scripts/pkg/gotools"
+)
+
+// inventory lists all Go toolchains on the system and optionally removes the
+// unused ones from the module cache
+func inventory(opts options, args []string) error {
+	fs := flag.NewFlagSet("inventory", flag.ExitOnError)
+	gc := fs.Bool("gc", false, "Remove module cache toolchains that nothing references")
+	dryRun := fs.Bool("dry-run", false, "With -gc, only show what would be removed")
+	var projects pathFlags
+	fs.Var(&projects, "project", "Directory to search for go.mod and go.work files (repeatable, default: the current directory).\n"+
+		"Required by -gc without -dry-run.")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: updatego inventory [-project dir]... [-gc [-dry-run]]")
+		fmt.Fprintln(fs.Output())
+		fmt.Fprintln(fs.Output(), "Lists the Go toolchains in GOROOTs, ~/sdk and the module cache with their size and users.")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	config.MustLoad("updatego.inventory", fs)
+
+	// Toolchains pinned by projects outside the scanned directories would be
+	// removed, so removing needs the projects to be named.
+	if *gc && !*dryRun && len(projects) == 0 {
+		return fmt.Errorf("-gc needs -project with the directories of all projects whose toolchains to keep, e.g. -project ~/src")
+	}
+	if len(projects) == 0 {
+		wd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to determine current directory: %w", err)
+		}
+		projects = pathFlags{wd}
+	}
+
+	ctx, cancel := signalContext(10 * time.Minute)
+	defer cancel()
+
+	installer, err := newInstaller(opts)
+	if err != nil {
+		return err
+	}
+
+	inv, err := gotools.NewInventory(installer)
+	if err != nil {
+		return fmt.Errorf("failed to create inventory: %w", err)
+	}
+	inv.Logger = opts.logger
+	inv.Projects = projects
+
+	toolchains, err := inv.Scan(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to scan toolchains: %w", err)
+	}
+
+	if err := gotools.PrintToolchains(os.Stdout, toolchains); err != nil {
+		return err
+	}
+	fmt.Printf("\n%d toolchains, %s in total\n", len(toolchains), gotools.TotalSize(toolchains))
+
+	if !*gc {
+		if unused, _ := inv.Collect(toolchains, true); len(unused) > 0 {
+			fmt.Printf("%d unused module cache toolchains (%s), run with -project and -gc to remove them\n", len(unused), gotools.TotalSize(unused))
+		}
+		return nil
+	}
+
+	removed, err := inv.Collect(toolchains, *dryRun)
+	for _, toolchain := range removed {
+		if *dryRun {
+			fmt.Printf("Would remove %s\n", toolchain.Root)
+		} else {
+			fmt.Printf("Removed %s\n", toolchain.Root)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove unused toolchains: %w", err)
+	}
+
+	switch {
+	case len(removed) == 0:
+		fmt.Println("No unused module cache toolchains")
+	case *dryRun:
+		fmt.Printf("Would free %s\n", gotools.TotalSize(removed))
+	default:
+		fmt.Printf("Freed %s\n", gotools.TotalSize(removed))
+	}
+
+	return nil
+}
@@ -2,66 +2,356 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/ibihim/go-scripts/pkg/config"
 	"github.com/ibihim/go-scripts/pkg/gotools"
+	"github.com/ibihim/go-scripts/pkg/logging"
 )
 
-func main() {
-	if err := app(); err != nil {
-		fmt.Println(err)
+// hookFlags collects repeated -hook point=command flags
+type hookFlags []string
+
+func (h *hookFlags) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *hookFlags) Set(value string) error {
+	if _, _, err := gotools.ParseHook(value); err != nil {
+		return err
 	}
+	*h = append(*h, value)
+	return nil
 }
 
-func app() error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
-	defer cancel()
+// toolFlags collects repeated -tool [goX.Y:]package@version flags
+type toolFlags []string
+
+func (t *toolFlags) String() string {
+	return strings.Join(*t, ", ")
+}
+
+func (t *toolFlags) Set(value string) error {
+	if _, err := gotools.ParseTool(value); err != nil {
+		return err
+	}
+	*t = append(*t, value)
+	return nil
+}
+
+// pathFlags collects repeated path flags
+type pathFlags []string
+
+func (p *pathFlags) String() string {
+	return strings.Join(*p, ", ")
+}
+
+func (p *pathFlags) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
+// proxyFlags collects repeated -proxy host=proxy flags
+type proxyFlags map[string]string
+
+func (p proxyFlags) String() string {
+	defs := make([]string, 0, len(p))
+	for host, proxy := range p {
+		defs = append(defs, host+"="+proxy)
+	}
+	slices.Sort(defs)
+	return strings.Join(defs, ", ")
+}
 
-	checker := gotools.NewChecker()
-	currentVersion := checker.GetInstalledVersion()
-	latestVersion, err := checker.GetLatestVersion(ctx)
+func (p proxyFlags) Set(value string) error {
+	host, proxy, err := gotools.ParseProxyOverride(value)
 	if err != nil {
-		return fmt.Errorf("failed to get latest version: %w", err)
+		return err
 	}
+	p[host] = proxy
+	return nil
+}
 
-	needsUpdate, err := checker.NeedsUpdate(currentVersion, latestVersion)
+// options holds the flags shared by all commands
+type options struct {
+	hooks hookFlags
+	tools toolFlags
+	// installDir, binDir and tempDir override the directories of the
+	// installer and downloader, empty keeps the defaults
+	installDir string
+	binDir     string
+	tempDir    string
+	wait       bool
+	logging    *logging.Options
+	caFiles    pathFlags
+	cert       string
+	key        string
+	proxies    proxyFlags
+	maxAge     time.Duration
+	// connections is the number of concurrent range requests per download
+	connections int
+	metricsFile string
+	// retry and revalidateTimeout override the retry policy of the checker
+	// and downloader and the revalidation timeout of the release cache, zero
+	// keeps the defaults
+	retry             gotools.RetryPolicy
+	revalidateTimeout time.Duration
+
+	logger   *slog.Logger
+	settings *config.Config
+	// client is the HTTP client configured by the TLS and proxy flags
+	client *http.Client
+}
+
+func main() {
+	opts := options{logging: logging.NewOptions(), proxies: proxyFlags{}}
+	opts.logging.AddFlags(flag.CommandLine)
+	flag.Var(&opts.hooks, "hook", "Run a command at a pipeline point, as point=command (repeatable).\n"+
+		"Points: before-download, after-verify, before-swap, after-install, on-failure.\n"+
+		"A command prefixed with '-' may fail without aborting the install.")
+	flag.Var(&opts.tools, "tool", "Install a tool with go install after updating, as [goX.Y:]package@version (repeatable).\n"+
+		"A goX.Y prefix pins the version for those toolchains.")
+	flag.StringVar(&opts.installDir, "install-dir", "", "Directory Go is installed to as go/ (default ~/.local/lib)")
+	flag.StringVar(&opts.binDir, "bin-dir", "", "Directory the go and gofmt links and tools are put in (default ~/.local/bin)")
+	flag.StringVar(&opts.tempDir, "temp-dir", "", "Directory for temporary downloads (default $TMPDIR or /tmp)")
+	flag.BoolVar(&opts.wait, "wait", false, "Wait for a concurrent run to finish instead of failing")
+	flag.DurationVar(&opts.maxAge, "max-age", 0, "Use cached release metadata up to this age without asking the server")
+	flag.StringVar(&opts.metricsFile, "metrics-file", "", "Write Prometheus metrics of update and check runs to this file,\n"+
+		"e.g. updatego.prom in the node_exporter textfile collector directory")
+	flag.IntVar(&opts.connections, "connections", 4, "Download archives with this many concurrent range requests, 1 uses a single one")
+	flag.Var(&opts.caFiles, "ca-file", "PEM file with additional root CAs to trust (repeatable)")
+	flag.StringVar(&opts.cert, "client-cert", "", "PEM client certificate for mirrors requiring mTLS")
+	flag.StringVar(&opts.key, "client-key", "", "PEM key of the client certificate")
+	flag.Var(opts.proxies, "proxy", "Proxy for a host as host=proxy (repeatable). The host may be .domain or *,\n"+
+		"the proxy a URL or 'direct'. Hosts without one use HTTPS_PROXY, NO_PROXY always applies.")
+	flag.Usage = usage
+	flag.Parse()
+
+	opts.settings = config.MustLoad("updatego", flag.CommandLine)
+	opts.logger = opts.logging.Setup()
+
+	// Scheduled runs and hooks don't start in the current directory.
+	for _, dir := range []*string{&opts.installDir, &opts.binDir, &opts.tempDir} {
+		if *dir == "" {
+			continue
+		}
+		abs, err := filepath.Abs(*dir)
+		if err != nil {
+			logging.Fatal(opts.logger, "Invalid directory", err)
+		}
+		*dir = abs
+	}
+
+	var (
+		code int
+		err  error
+	)
+	opts.client, err = gotools.NewHTTPClientWithOptions(gotools.HTTPOptions{
+		CAFiles:    opts.caFiles,
+		ClientCert: opts.cert,
+		ClientKey:  opts.key,
+		Proxies:    opts.proxies,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to check if update is needed: %w", err)
+		logging.Fatal(opts.logger, "Invalid HTTP settings", err)
 	}
 
-	fmt.Printf("Current version: %s\n", currentVersion)
-	fmt.Printf("Latest version: %s\n", latestVersion)
-	fmt.Printf("Update needed: %t\n", needsUpdate)
+	switch command := flag.Arg(0); command {
+	case "", "update":
+		err = app(opts)
+	case "check":
+		code, err = check(opts, flag.Args()[1:])
+	case "watch":
+		err = watch(opts, flag.Args()[1:])
+	case "verify-installation":
+		err = verifyInstallation(opts, flag.Args()[1:])
+	case "changes":
+		err = changes(opts, flag.Args()[1:])
+	case "install-source":
+		err = installSource(opts, flag.Args()[1:])
+	case "tools":
+		err = tools(opts, flag.Args()[1:])
+	case "inventory":
+		err = inventory(opts, flag.Args()[1:])
+	case "cleanup":
+		err = cleanup(opts, flag.Args()[1:])
+	case "schedule":
+		err = scheduleCmd(opts, flag.Args()[1:])
+	case "selfupdate":
+		err = selfUpdate(opts, flag.Args()[1:])
+	case "config":
+		if !config.IsShowCommand(flag.Args()) {
+			err = fmt.Errorf("unknown config command, expected config show")
+			break
+		}
+		err = opts.settings.Show(os.Stdout)
+	default:
+		err = fmt.Errorf("unknown command %q", command)
+	}
 
-	if !needsUpdate {
-		return nil
+	if err != nil {
+		logging.Fatal(opts.logger, "updatego failed", err)
 	}
+	os.Exit(code)
+}
 
-	downloader := gotools.NewDownloader()
-	path, err := downloader.Download(ctx, latestVersion)
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "Usage: updatego [options] [command]")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Commands:")
+	fmt.Fprintln(out, "  update               Update Go to the latest stable release (default)")
+	fmt.Fprintln(out, "  check                Check for updates and known vulnerabilities")
+	fmt.Fprintln(out, "  watch                Notify about new releases without installing them")
+	fmt.Fprintln(out, "  verify-installation  Compare the installed Go with its release archive")
+	fmt.Fprintln(out, "  changes <from> <to>  Show release notes and fixes between two versions")
+	fmt.Fprintln(out, "  install-source       Build Go from source and install it next to the managed Go")
+	fmt.Fprintln(out, "  tools                Install or upgrade the -tool commands with the managed Go")
+	fmt.Fprintln(out, "  inventory            List all Go toolchains and remove unused module cache ones")
+	fmt.Fprintln(out, "  cleanup              Remove temporary downloads left behind by earlier runs")
+	fmt.Fprintln(out, "  schedule             Install, show or remove unattended runs")
+	fmt.Fprintln(out, "  selfupdate           Rebuild the go-scripts commands from a newer version")
+	fmt.Fprintln(out, "  config show          Show the effective settings and where they come from")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Options:")
+	flag.PrintDefaults()
+}
+
+// signalContext returns a context that is cancelled on SIGINT or SIGTERM and
+// after timeout, so interrupted runs still clean up after themselves. A
+// second signal terminates the process right away, a zero timeout never
+// expires.
+func signalContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	// Once the first signal arrived, the default handling of the next one
+	// terminates the process.
+	go func() {
+		<-sigCtx.Done()
+		stop()
+	}()
+	ctx, cancel := sigCtx, context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(sigCtx, timeout)
+	}
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// newInstaller creates an installer configured by the global flags
+func newInstaller(opts options) (*gotools.Installer, error) {
+	installer, err := gotools.NewInstaller()
 	if err != nil {
-		return fmt.Errorf("failed to download latest version: %w", err)
+		return nil, fmt.Errorf("failed to create installer: %w", err)
 	}
+	installer.Logger = opts.logger
+	if opts.installDir != "" {
+		installer.InstallDir = opts.installDir
+	}
+	if opts.binDir != "" {
+		installer.BinDir = opts.binDir
+	}
+
+	return installer, nil
+}
 
-	verified, err := downloader.VerifyChecksum(ctx, path, latestVersion)
+// newUpdater creates an updater configured by the global flags
+func newUpdater(opts options) (*gotools.Updater, error) {
+	updater, err := gotools.NewUpdater()
 	if err != nil {
-		return fmt.Errorf("failed to verify downloaded version: %w", err)
+		return nil, err
 	}
-	if !verified {
-		return fmt.Errorf("downloaded version could not be verified")
-	} else {
-		fmt.Println("Downloaded version verified")
+	if updater.Installer, err = newInstaller(opts); err != nil {
+		return nil, err
+	}
+	updater.Downloader.TempDir = opts.tempDir
+	updater.SetLogger(opts.logger)
+	updater.SetHTTPClient(opts.client)
+	updater.Downloader.Connections = opts.connections
+	for _, def := range opts.tools {
+		tool, err := gotools.ParseTool(def)
+		if err != nil {
+			return nil, err
+		}
+		updater.Tools = append(updater.Tools, tool)
 	}
 
-	fmt.Printf("Version %s downloaded and verified at path %s\n", latestVersion, path)
+	updater.Checker.Retry = opts.retry
+	updater.Downloader.Retry = opts.retry
+	updater.Checker.MaxAge = opts.maxAge
+	updater.Checker.RevalidateTimeout = opts.revalidateTimeout
+	if updater.Checker.CachePath, err = gotools.DefaultReleaseCachePath(); err != nil {
+		opts.logger.Warn("Not caching release metadata", "error", err)
+	}
 
-	installer, err := gotools.NewInstaller()
-	if err := installer.Install(ctx, path); err != nil {
-		return fmt.Errorf("failed to install Go: %w", err)
+	return updater, nil
+}
+
+func app(opts options) (err error) {
+	// Update applies its own timeouts, the tools it builds afterwards take
+	// minutes.
+	ctx, cancel := signalContext(0)
+	defer cancel()
+
+	updater, err := newUpdater(opts)
+	if err != nil {
+		return err
+	}
+	metrics := newMetricsRecorder(opts, updater)
+	defer func() { metrics.write(err) }()
+
+	updater.WaitForLock = opts.wait
+	for _, def := range opts.hooks {
+		point, hook, err := gotools.ParseHook(def)
+		if err != nil {
+			return err
+		}
+		updater.Hooks.Add(point, hook)
+	}
+
+	checkCtx, cancelCheck := context.WithTimeout(ctx, time.Minute)
+	defer cancelCheck()
+	status, err := updater.Check(checkCtx)
+	if err != nil {
+		return err
+	}
+	metrics.setStatus(status)
+
+	fmt.Printf("Current version: %s\n", installedVersion(status))
+	fmt.Printf("Latest version: %s\n", status.Latest)
+	fmt.Printf("Update needed: %t\n", status.NeedsUpdate)
+
+	if !status.NeedsUpdate {
+		return nil
+	}
+
+	// The summary is informational, the update must neither depend on it nor
+	// wait long for it when offline. A first install has nothing to compare.
+	if status.Installed != "" {
+		changesCtx, cancelChanges := context.WithTimeout(checkCtx, 15*time.Second)
+		if err := printChanges(changesCtx, opts, os.Stdout, status.Installed, status.Latest, false); err != nil {
+			opts.logger.Warn("Failed to show changes", "error", err)
+		}
+		cancelChanges()
+	}
+
+	if err := updater.Update(ctx, status); err != nil {
+		return err
 	}
 
 	fmt.Println("Go installed successfully")
+	printToolResults(os.Stdout, status.Tools)
 
 	return nil
 }
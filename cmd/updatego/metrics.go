@@ -0,0 +1,59 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/ibihim/go-scripts/pkg/gotools"
+)
+
+// metricsRecorder writes the metrics of a run to -metrics-file
+type metricsRecorder struct {
+	path    string
+	logger  *slog.Logger
+	updater *gotools.Updater
+	started time.Time
+	status  *gotools.Status
+	checked time.Time
+}
+
+func newMetricsRecorder(opts options, updater *gotools.Updater) *metricsRecorder {
+	return &metricsRecorder{
+		path:    opts.metricsFile,
+		logger:  opts.logger,
+		updater: updater,
+		started: time.Now(),
+	}
+}
+
+// setStatus records the result of the release check
+func (r *metricsRecorder) setStatus(status *gotools.Status) {
+	r.status = status
+	r.checked = time.Now()
+}
+
+// write writes the metrics of the run that ended with err. Metrics are
+// informational, failing to write them only logs a warning.
+func (r *metricsRecorder) write(err error) {
+	if r.path == "" {
+		return
+	}
+
+	metrics := gotools.RunMetrics{
+		Finished:      time.Now(),
+		Duration:      time.Since(r.started),
+		DownloadBytes: r.updater.Downloader.Received(),
+		Err:           err,
+	}
+	if installed, err := r.updater.Installer.InstalledVersion(); err == nil {
+		metrics.Installed = installed
+	}
+	if r.status != nil {
+		metrics.Latest = r.status.Latest
+		metrics.Checked = r.checked
+	}
+
+	if err := gotools.WriteMetrics(r.path, metrics); err != nil {
+		r.logger.Warn("Failed to write metrics", "path", r.path, "error", err)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/ibihim/go-scripts/pkg/config"
+	"github.com/ibihim/go-scripts/pkg/schedule"
+)
+
+// scheduleName names the systemd units and the crontab entry
+const scheduleName = "updatego"
+
+// scheduleCmd manages unattended updatego runs
+func scheduleCmd(opts options, args []string) error {
+	fs := flag.NewFlagSet("schedule", flag.ExitOnError)
+	backend := fs.String("backend", string(schedule.BackendAuto), "Scheduler: auto, systemd or cron")
+	interval := fs.String("interval", string(schedule.Daily), "How often to run: hourly, daily or weekly")
+	command := fs.String("command", "update", "Command to run: update or check")
+	vulnDB := fs.String("vulndb", "", "Vulnerability database passed to the check command")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: updatego [options] schedule [schedule options] install|status|remove")
+		fmt.Fprintln(fs.Output())
+		fmt.Fprintln(fs.Output(), "install runs updatego with the given updatego options, e.g. hooks, on a systemd")
+		fmt.Fprintln(fs.Output(), "user timer, or a cron job if there is no systemd user manager.")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	config.MustLoad("updatego.schedule", fs)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected one of install, status or remove")
+	}
+
+	ctx, cancel := signalContext(time.Minute)
+	defer cancel()
+
+	scheduler, err := schedule.New(ctx, schedule.Backend(*backend))
+	if err != nil {
+		return err
+	}
+
+	switch action := fs.Arg(0); action {
+	case "install":
+		spec, err := scheduleSpec(opts, *interval, *command, *vulnDB)
+		if err != nil {
+			return err
+		}
+		if err := scheduler.Install(ctx, spec); err != nil {
+			return fmt.Errorf("failed to install schedule: %w", err)
+		}
+		fmt.Printf("Scheduled %q %s using %s\n", spec.Description, spec.Interval, scheduler.Backend)
+	case "status":
+		status, err := scheduler.Status(ctx, scheduleName)
+		if errors.Is(err, schedule.ErrNotScheduled) {
+			fmt.Printf("updatego is not scheduled (%s)\n", scheduler.Backend)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get schedule status: %w", err)
+		}
+		fmt.Print(status)
+	case "remove":
+		if err := scheduler.Remove(ctx, scheduleName); err != nil {
+			return fmt.Errorf("failed to remove schedule: %w", err)
+		}
+		fmt.Printf("Removed updatego schedule (%s)\n", scheduler.Backend)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown schedule action %q", action)
+	}
+
+	return nil
+}
+
+// scheduleSpec builds the scheduled command line from this executable and
+// the options updatego was called with
+func scheduleSpec(opts options, interval, command, vulnDB string) (schedule.Spec, error) {
+	parsedInterval, err := schedule.ParseInterval(interval)
+	if err != nil {
+		return schedule.Spec{}, err
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return schedule.Spec{}, fmt.Errorf("failed to locate updatego: %w", err)
+	}
+	executable, err = filepath.EvalSymlinks(executable)
+	if err != nil {
+		return schedule.Spec{}, fmt.Errorf("failed to locate updatego: %w", err)
+	}
+
+	// Settings from the config file and environment are picked up by the
+	// scheduled runs themselves, only flags are passed on. update and check
+	// always follow the stable releases, there is no channel or update
+	// policy to pass on.
+	fromFlag := func(name string) bool {
+		return opts.settings.Source(name) == config.SourceFlag
+	}
+
+	args := []string{executable}
+	if fromFlag("log-format") {
+		args = append(args, "-log-format", opts.logging.Format)
+	}
+	if fromFlag("verbose") && opts.logging.Verbose {
+		args = append(args, "-verbose")
+	}
+	if fromFlag("quiet") && opts.logging.Quiet {
+		args = append(args, "-quiet")
+	}
+	// Unattended runs wait for interactive ones instead of failing.
+	args = append(args, "-wait")
+	if fromFlag("max-age") {
+		args = append(args, "-max-age", opts.maxAge.String())
+	}
+	if fromFlag("metrics-file") && opts.metricsFile != "" {
+		metricsFile, err := filepath.Abs(opts.metricsFile)
+		if err != nil {
+			return schedule.Spec{}, err
+		}
+		args = append(args, "-metrics-file", metricsFile)
+	}
+	if fromFlag("connections") {
+		args = append(args, "-connections", strconv.Itoa(opts.connections))
+	}
+	if fromFlag("hook") {
+		for _, hook := range opts.hooks {
+			args = append(args, "-hook", hook)
+		}
+	}
+	if fromFlag("tool") {
+		for _, tool := range opts.tools {
+			args = append(args, "-tool", tool)
+		}
+	}
+	// The directories are absolute already, see main.
+	for _, dir := range []struct{ name, path string }{
+		{"install-dir", opts.installDir},
+		{"bin-dir", opts.binDir},
+		{"temp-dir", opts.tempDir},
+	} {
+		if fromFlag(dir.name) && dir.path != "" {
+			args = append(args, "-"+dir.name, dir.path)
+		}
+	}
+	// Scheduled runs don't start in the current directory, certificates are
+	// passed with absolute paths.
+	var certFlags [][2]string
+	if fromFlag("ca-file") {
+		for _, caFile := range opts.caFiles {
+			certFlags = append(certFlags, [2]string{"-ca-file", caFile})
+		}
+	}
+	if fromFlag("client-cert") && opts.cert != "" {
+		certFlags = append(certFlags, [2]string{"-client-cert", opts.cert})
+	}
+	if fromFlag("client-key") && opts.key != "" {
+		certFlags = append(certFlags, [2]string{"-client-key", opts.key})
+	}
+	for _, f := range certFlags {
+		path, err := filepath.Abs(f[1])
+		if err != nil {
+			return schedule.Spec{}, err
+		}
+		args = append(args, f[0], path)
+	}
+	if fromFlag("proxy") {
+		hosts := slices.Sorted(maps.Keys(opts.proxies))
+		for _, host := range hosts {
+			args = append(args, "-proxy", host+"="+opts.proxies[host])
+		}
+	}
+
+	description := "Update Go to the latest stable release"
+	var successExitCodes []int
+	switch command {
+	case "update":
+		args = append(args, "update")
+	case "check":
+		description = "Check for Go updates and known vulnerabilities"
+		// The check reports its result with exit codes, not a failure.
+		successExitCodes = []int{exitUpdateAvailable, exitVulnerable}
+		args = append(args, "check")
+		if vulnDB != "" {
+			if vulnDB, err = filepath.Abs(vulnDB); err != nil {
+				return schedule.Spec{}, err
+			}
+			args = append(args, "-vulndb", vulnDB)
+		}
+	default:
+		return schedule.Spec{}, fmt.Errorf("invalid command %q: must be update or check", command)
+	}
+
+	return schedule.Spec{
+		Name:             scheduleName,
+		Description:      description,
+		Command:          args,
+		Interval:         parsedInterval,
+		SuccessExitCodes: successExitCodes,
+	}, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ibihim/go-scripts/pkg/config"
+	"github.com/ibihim/go-scripts/pkg/selfupdate"
+)
+
+// selfUpdate rebuilds the go-scripts commands from a newer version of the
+// configured source
+func selfUpdate(opts options, args []string) error {
+	fs := flag.NewFlagSet("selfupdate", flag.ExitOnError)
+	source := fs.String("source", "", "Git checkout or module proxy directory to update from")
+	pull := fs.Bool("pull", false, "Fast-forward the git checkout before updating")
+	gobin := fs.String("gobin", "", "Directory to install the commands to (default: the directory of updatego)")
+	dryRun := fs.Bool("dry-run", false, "Only report what would change")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: updatego selfupdate [options]")
+		fmt.Fprintln(fs.Output())
+		fmt.Fprintln(fs.Output(), "Options can also be set in the [updatego.selfupdate] table of the config file.")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	config.MustLoad("updatego.selfupdate", fs)
+
+	if *source == "" {
+		fs.Usage()
+		return fmt.Errorf("no source configured")
+	}
+
+	if *gobin == "" {
+		executable, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("failed to locate updatego: %w", err)
+		}
+		if executable, err = filepath.EvalSymlinks(executable); err != nil {
+			return fmt.Errorf("failed to locate updatego: %w", err)
+		}
+		*gobin = filepath.Dir(executable)
+	}
+
+	src, err := selfupdate.NewSource(*source)
+	if err != nil {
+		return err
+	}
+	if git, ok := src.(*selfupdate.GitSource); ok {
+		git.Pull = *pull
+	}
+
+	current, err := selfupdate.Current()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signalContext(10 * time.Minute)
+	defer cancel()
+
+	opts.logger.Info("Checking for a newer go-scripts version", "source", *source)
+	result, err := selfupdate.Update(ctx, src, current, *gobin, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	result.Print(os.Stdout)
+	switch {
+	case result.Updated:
+		fmt.Printf("Installed go-scripts %s to %s\n", result.Latest, *gobin)
+	case result.Available:
+		fmt.Println("Update available, run without -dry-run to install it")
+	default:
+		fmt.Println("go-scripts is up to date")
+	}
+
+	return nil
+}
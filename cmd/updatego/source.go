@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/ibihim/go-scripts/pkg/config"
+	"github.com/ibihim/go-scripts/pkg/gotools"
+)
+
+// installSource builds Go from a source archive or checkout and installs it
+// next to the managed installation
+func installSource(opts options, args []string) error {
+	fs := flag.NewFlagSet("install-source", flag.ExitOnError)
+	name := fs.String("name", "", "Version to install the build as, e.g. go1.24-custom")
+	bootstrap := fs.String("bootstrap", "", "GOROOT_BOOTSTRAP toolchain (default: the managed installation)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: updatego install-source -name <version> <archive|checkout>")
+		fmt.Fprintln(fs.Output())
+		fmt.Fprintln(fs.Output(), "Builds Go with make.bash and installs it as <version> next to the managed Go.")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	config.MustLoad("updatego.install-source", fs)
+
+	if fs.NArg() != 1 || *name == "" {
+		fs.Usage()
+		return fmt.Errorf("install-source needs a -name and a source archive or checkout")
+	}
+
+	source, err := filepath.Abs(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid source %q: %w", fs.Arg(0), err)
+	}
+
+	// make.bash runs in the staged tree, a relative bootstrap would resolve there.
+	if *bootstrap != "" {
+		if *bootstrap, err = filepath.Abs(*bootstrap); err != nil {
+			return fmt.Errorf("invalid bootstrap %q: %w", *bootstrap, err)
+		}
+	}
+
+	ctx, cancel := signalContext(30 * time.Minute)
+	defer cancel()
+
+	installer, err := newInstaller(opts)
+	if err != nil {
+		return err
+	}
+
+	lock, err := installer.Lock(ctx, opts.wait)
+	if err != nil {
+		return fmt.Errorf("failed to lock installation: %w", err)
+	}
+	defer lock.Release()
+
+	root, err := installer.InstallSource(ctx, gotools.SourceBuild{Source: source, Name: *name, Bootstrap: *bootstrap})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Installed %s to %s\n", *name, root)
+	fmt.Printf("Run it as %s\n", filepath.Join(installer.BinDir, *name))
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ibihim/go-scripts/pkg/config"
+	"github.com/ibihim/go-scripts/pkg/gotools"
+)
+
+// tools installs or upgrades the -tool commands with the managed Go, e.g. on
+// a new machine or after changing the tool list
+func tools(opts options, args []string) error {
+	fs := flag.NewFlagSet("tools", flag.ExitOnError)
+	list := fs.Bool("list", false, "Only list the tools selected for the installed Go")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: updatego [-tool [goX.Y:]package@version]... tools [-list]")
+		fmt.Fprintln(fs.Output())
+		fmt.Fprintln(fs.Output(), "Installs or upgrades the tools with go install of the managed Go into its bin")
+		fmt.Fprintln(fs.Output(), "directory. update does the same after installing a new Go.")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	config.MustLoad("updatego.tools", fs)
+
+	updater, err := newUpdater(opts)
+	if err != nil {
+		return err
+	}
+	if len(updater.Tools) == 0 {
+		fmt.Println("No tools configured, add them with -tool or the tool key of the config file")
+		return nil
+	}
+
+	if *list {
+		version, err := updater.Installer.InstalledVersion()
+		if err != nil {
+			return fmt.Errorf("failed to determine installed version: %w", err)
+		}
+		for _, tool := range gotools.ToolsFor(updater.Tools, version) {
+			fmt.Printf("%s\t%s\n", tool.Name(), tool)
+		}
+		return nil
+	}
+
+	ctx, cancel := signalContext(gotools.DefaultToolsTimeout)
+	defer cancel()
+
+	lock, err := updater.Installer.Lock(ctx, opts.wait)
+	if err != nil {
+		return fmt.Errorf("failed to lock installation: %w", err)
+	}
+	defer lock.Release()
+
+	results, err := updater.Installer.InstallTools(ctx, updater.Tools)
+	printToolResults(os.Stdout, results)
+	if err != nil {
+		return fmt.Errorf("failed to install tools: %w", err)
+	}
+	if failed := gotools.FailedTools(results); len(failed) > 0 {
+		return fmt.Errorf("%d of %d tools failed to build", len(failed), len(results))
+	}
+
+	return nil
+}
+
+// printToolResults reports the installed tools and the build output of the
+// failed ones
+func printToolResults(w io.Writer, results []gotools.ToolResult) {
+	for _, result := range results {
+		if result.Err == nil {
+			fmt.Fprintf(w, "Installed %s (%s)\n", result.Tool.Name(), result.Tool)
+			continue
+		}
+		fmt.Fprintf(w, "Failed %s: %v\n", result.Tool.Name(), result.Err)
+		if result.Output != "" {
+			fmt.Fprintf(w, "    %s\n", strings.ReplaceAll(result.Output, "\n", "\n    "))
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/ibihim/go-scripts/pkg/config"
+	"github.com/ibihim/go-scripts/pkg/gotools"
+)
+
+// verifyInstallation compares the files of the installed Go with the official
+// release archive and optionally restores drifted files.
+func verifyInstallation(opts options, args []string) error {
+	fs := flag.NewFlagSet("verify-installation", flag.ExitOnError)
+	archivePath := fs.String("archive", "", "Path to a local release archive (downloaded if empty)")
+	repair := fs.Bool("repair", false, "Re-extract missing and modified files from the archive")
+	fs.Parse(args)
+	config.MustLoad("updatego.verify-installation", fs)
+
+	ctx, cancel := signalContext(5 * time.Minute)
+	defer cancel()
+
+	installer, err := newInstaller(opts)
+	if err != nil {
+		return err
+	}
+
+	// A concurrent update mustn't swap the installation while it is compared
+	// or repaired.
+	lock, err := installer.Lock(ctx, opts.wait)
+	if err != nil {
+		return fmt.Errorf("failed to lock installation: %w", err)
+	}
+	defer lock.Release()
+
+	version, err := installer.InstalledVersion()
+	if err != nil {
+		return fmt.Errorf("failed to determine installed version: %w", err)
+	}
+	fmt.Printf("Installed version: %s\n", version)
+
+	path := *archivePath
+	if path == "" {
+		downloader := gotools.NewDownloader()
+		downloader.Logger = opts.logger
+		downloader.SetHTTPClient(opts.client)
+		downloader.Connections = opts.connections
+		downloader.TempDir = opts.tempDir
+		if downloader.CacheDir, err = gotools.DefaultArchiveCacheDir(); err != nil {
+			opts.logger.Warn("Not caching release archives", "error", err)
+		}
+		defer downloader.Cleanup()
+
+		if path, err = releaseArchive(ctx, opts, downloader, version); err != nil {
+			return err
+		}
+	}
+
+	report, err := installer.VerifyIntegrity(ctx, path)
+	if err != nil {
+		return fmt.Errorf("failed to verify installation: %w", err)
+	}
+
+	for _, name := range report.Added {
+		fmt.Printf("added:    %s\n", name)
+	}
+	for _, name := range report.Missing {
+		fmt.Printf("missing:  %s\n", name)
+	}
+	for _, name := range report.Modified {
+		fmt.Printf("modified: %s\n", name)
+	}
+
+	if report.Clean() {
+		fmt.Println("Installation matches the release archive")
+		return nil
+	}
+
+	if !*repair {
+		return fmt.Errorf("installation differs from the release archive: %d added, %d missing, %d modified",
+			len(report.Added), len(report.Missing), len(report.Modified))
+	}
+
+	if err := installer.Repair(ctx, path, report); err != nil {
+		return fmt.Errorf("failed to repair installation: %w", err)
+	}
+	fmt.Printf("Restored %d files from the release archive\n", len(report.Drifted()))
+
+	return nil
+}
+
+// releaseArchive returns the verified release archive of version, the cached
+// copy if it is still intact or a new download that is cached for next time
+func releaseArchive(ctx context.Context, opts options, downloader *gotools.Downloader, version string) (string, error) {
+	if path, err := downloader.CachedArchive(version); err == nil {
+		verified, err := downloader.VerifyChecksum(ctx, path, version)
+		if err == nil && verified {
+			opts.logger.Debug("Using cached release archive", "path", path)
+			return path, nil
+		}
+		opts.logger.Warn("Cached release archive doesn't match its checksum, downloading it again", "path", path, "error", err)
+	}
+
+	path, err := downloader.Download(ctx, version)
+	if err != nil {
+		return "", fmt.Errorf("failed to download release archive: %w", err)
+	}
+
+	verified, err := downloader.VerifyChecksum(ctx, path, version)
+	if err != nil {
+		return "", fmt.Errorf("failed to verify release archive: %w", err)
+	}
+	if !verified {
+		return "", fmt.Errorf("release archive could not be verified")
+	}
+
+	if err := downloader.CacheArchive(path, version); err != nil {
+		opts.logger.Warn("Failed to cache release archive", "error", err)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/ibihim/go-scripts/pkg/config"
+	"github.com/ibihim/go-scripts/pkg/gotools"
+)
+
+// watch tells the user about new Go releases without installing them. Every
+// release is announced once, the state file remembers which ones were.
+func watch(opts options, args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	channel := fs.String("channel", string(gotools.ChannelStable), "Releases to announce: stable or prerelease (including betas and release candidates)")
+	every := fs.Duration("every", 24*time.Hour, "Minimum time between two checks, 0 checks on every run")
+	daemon := fs.Bool("daemon", false, "Keep running and check -every interval")
+	desktop := fs.Bool("desktop", false, "Show a desktop notification with notify-send")
+	motd := fs.String("motd", "", "Write the announcement to this file, e.g. one printed by your shell's rc file; it is removed once Go is up to date")
+	webhook := fs.String("webhook", "", "POST the announcement as JSON to this URL")
+	statePath := fs.String("state", "", "File remembering the announced releases (default $XDG_STATE_HOME/go-scripts/watch.json)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: updatego watch [options]")
+		fmt.Fprintln(fs.Output())
+		fmt.Fprintln(fs.Output(), "Checks for new Go releases without installing them and announces each one once,")
+		fmt.Fprintln(fs.Output(), "on stdout and with the chosen notifications. Options can also be set in the")
+		fmt.Fprintln(fs.Output(), "[updatego.watch] table of the config file.")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	config.MustLoad("updatego.watch", fs)
+
+	parsedChannel, err := gotools.ParseChannel(*channel)
+	if err != nil {
+		return err
+	}
+	if *daemon && *every <= 0 {
+		return fmt.Errorf("-daemon needs a positive -every interval")
+	}
+
+	updater, err := newUpdater(opts)
+	if err != nil {
+		return err
+	}
+
+	watcher := &gotools.Watcher{
+		Checker:   updater.Checker,
+		Installer: updater.Installer,
+		Channel:   parsedChannel,
+		StatePath: *statePath,
+		Interval:  *every,
+		Logger:    opts.logger,
+	}
+	if watcher.StatePath == "" {
+		if watcher.StatePath, err = gotools.DefaultWatchStatePath(); err != nil {
+			return err
+		}
+	}
+	if *desktop {
+		watcher.Notifiers = append(watcher.Notifiers, gotools.DesktopNotifier{})
+	}
+	if *motd != "" {
+		watcher.Notifiers = append(watcher.Notifiers, gotools.MOTDNotifier{Path: *motd})
+	}
+	if *webhook != "" {
+		watcher.Notifiers = append(watcher.Notifiers, gotools.NewWebhookNotifier(*webhook, opts.client))
+	}
+
+	ctx, cancel := signalContext(0)
+	defer cancel()
+
+	check := func() error {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+
+		announcement, err := watcher.Watch(ctx)
+		if announcement != nil {
+			fmt.Println(announcement.Message())
+		}
+		return err
+	}
+
+	if !*daemon {
+		return check()
+	}
+
+	ticker := time.NewTicker(*every)
+	defer ticker.Stop()
+	for {
+		if err := check(); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			opts.logger.Warn("Failed to check for new releases", "error", err)
+		}
+		// The ticker spaces the checks from now on, the state file only
+		// delays the first one.
+		watcher.Interval = 0
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Package config overlays the flags of the go-scripts commands with settings
+// from a shared config file and environment variables
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// EnvConfigPath overrides the location of the config file
+const EnvConfigPath = "GO_SCRIPTS_CONFIG"
+
+// envPrefix prefixes the environment variables of all settings
+const envPrefix = "GO_SCRIPTS_"
+
+// Source is where the effective value of a setting comes from, listed from
+// lowest to highest precedence
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "config"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Setting is the effective value of a flag
+type Setting struct {
+	Name  string
+	Value string
+	// Source is where Value comes from
+	Source Source
+	// Origin locates the value within the source, e.g. the line of the config
+	// file or the environment variable
+	Origin string
+}
+
+// Config holds the effective settings of a command
+type Config struct {
+	// Command is the name of the command and its section in the config file
+	Command string
+	// Path is the config file, it doesn't need to exist
+	Path     string
+	settings []Setting
+}
+
+// DefaultPath returns $GO_SCRIPTS_CONFIG or the config.toml in the go-scripts
+// directory of $XDG_CONFIG_HOME, ~/.config by default.
+func DefaultPath() (string, error) {
+	if path := os.Getenv(EnvConfigPath); path != "" {
+		return path, nil
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine config directory: %w", err)
+	}
+	return filepath.Join(configDir, "go-scripts", "config.toml"), nil
+}
+
+// EnvName returns the environment variable setting flag name of command,
+// e.g. GO_SCRIPTS_UPDATEGO_LOG_FORMAT. An empty command returns the variable
+// shared by all commands.
+func EnvName(command, name string) string {
+	if command != "" {
+		name = command + "_" + name
+	}
+	return envPrefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// Load applies the config file at the default path and the environment to the
+// flags of fs that weren't set on the command line. It must be called after
+// fs.Parse.
+func Load(command string, fs *flag.FlagSet) (*Config, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return LoadFile(path, command, fs)
+}
+
+// MustLoad is Load for use in main. Invalid settings are reported on stderr
+// and exit the process.
+func MustLoad(command string, fs *flag.FlagSet) *Config {
+	c, err := Load(command, fs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	return c
+}
+
+// LoadFile is Load with an explicit config file path. A missing file is not an
+// error. Settings are taken, from highest to lowest precedence, from:
+//
+//   - flags given on the command line
+//   - $GO_SCRIPTS_<COMMAND>_<FLAG>, then $GO_SCRIPTS_<FLAG>
+//   - the [<command>] table of the config file, then its top-level keys
+//   - the flag defaults
+//
+// Array values are applied by setting the flag once per element, so they
+// suit repeatable flags.
+func LoadFile(path, command string, fs *flag.FlagSet) (*Config, error) {
+	tables, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, v := range tables[command] {
+		if fs.Lookup(key) == nil {
+			return nil, fmt.Errorf("%s:%d: unknown setting %q for %s", path, v.line, key, command)
+		}
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	c := &Config{Command: command, Path: path}
+
+	var applyErr error
+	fs.VisitAll(func(f *flag.Flag) {
+		if applyErr != nil || f.Name == "help" {
+			return
+		}
+
+		setting := Setting{Name: f.Name, Source: SourceDefault}
+		if explicit[f.Name] {
+			setting.Source = SourceFlag
+		} else if env, v, ok := lookupEnv(command, f.Name); ok {
+			setting.Source, setting.Origin = SourceEnv, env
+			applyErr = set(fs, f.Name, []string{v}, env)
+		} else if v, section, ok := lookupFile(tables, command, f.Name); ok {
+			setting.Source, setting.Origin = SourceFile, fmt.Sprintf("%sline %d", section, v.line)
+			applyErr = set(fs, f.Name, v.values, fmt.Sprintf("%s:%d", path, v.line))
+		}
+
+		setting.Value = f.Value.String()
+		c.settings = append(c.settings, setting)
+	})
+	if applyErr != nil {
+		return nil, applyErr
+	}
+
+	return c, nil
+}
+
+// Settings returns the effective settings ordered by name
+func (c *Config) Settings() []Setting {
+	return c.settings
+}
+
+// Source returns where the effective value of the flag name comes from
+func (c *Config) Source(name string) Source {
+	for _, s := range c.settings {
+		if s.Name == name {
+			return s.Source
+		}
+	}
+	return SourceDefault
+}
+
+// Show writes the effective settings and their sources to w
+func (c *Config) Show(w io.Writer) error {
+	fmt.Fprintf(w, "Config file: %s\n\n", c.Path)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SETTING\tVALUE\tSOURCE")
+	for _, s := range c.settings {
+		source := string(s.Source)
+		if s.Origin != "" {
+			source += " (" + s.Origin + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%q\t%s\n", s.Name, s.Value, source)
+	}
+	return tw.Flush()
+}
+
+// IsShowCommand reports whether args ask for the "config show" command
+func IsShowCommand(args []string) bool {
+	return len(args) == 2 && args[0] == "config" && args[1] == "show"
+}
+
+func readFile(path string) (map[string]table, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]table{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+
+	tables, err := parseTOML(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return tables, nil
+}
+
+// lookupEnv returns the command specific or shared environment variable of a setting
+func lookupEnv(command, name string) (string, string, bool) {
+	for _, env := range []string{EnvName(command, name), EnvName("", name)} {
+		if v, ok := os.LookupEnv(env); ok {
+			return env, v, true
+		}
+	}
+	return "", "", false
+}
+
+// lookupFile returns a setting from the command's table or the top-level keys
+// of the config file, along with a description of where it was found
+func lookupFile(tables map[string]table, command, name string) (value, string, bool) {
+	if v, ok := tables[command][name]; ok {
+		return v, "[" + command + "] ", true
+	}
+	if v, ok := tables[""][name]; ok {
+		return v, "", true
+	}
+	return value{}, "", false
+}
+
+func set(fs *flag.FlagSet, name string, values []string, origin string) error {
+	for _, v := range values {
+		if err := fs.Set(name, v); err != nil {
+			return fmt.Errorf("invalid value %q for %s from %s: %w", v, name, origin, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// listFlag is a repeatable flag like the -hook flag of updatego
+type listFlag []string
+
+func (l *listFlag) String() string     { return strings.Join(*l, ",") }
+func (l *listFlag) Set(v string) error { *l = append(*l, v); return nil }
+
+const testConfig = `# shared by all commands
+log-format = "json"
+verbose = true
+
+[updatego]
+wait = true # wait for other runs
+hook = ["after-install=go clean -cache", 'after-install=-notify-send "done"']
+
+[flatten]
+include = "*.go,*.md"
+`
+
+func writeTestConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newTestFlagSet() (*flag.FlagSet, *listFlag) {
+	fs := flag.NewFlagSet("updatego", flag.ContinueOnError)
+	hooks := &listFlag{}
+	fs.Var(hooks, "hook", "")
+	fs.Bool("wait", false, "")
+	fs.Bool("verbose", false, "")
+	fs.Bool("quiet", false, "")
+	fs.String("log-format", "text", "")
+	fs.Bool("help", false, "")
+	return fs, hooks
+}
+
+func TestLoadFilePrecedence(t *testing.T) {
+	path := writeTestConfig(t, testConfig)
+	t.Setenv(EnvName("updatego", "verbose"), "false")
+	t.Setenv(EnvName("", "quiet"), "true")
+
+	fs, hooks := newTestFlagSet()
+	if err := fs.Parse([]string{"-log-format", "text"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := LoadFile(path, "updatego", fs)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+
+	want := []Setting{
+		{Name: "hook", Value: `after-install=go clean -cache,after-install=-notify-send "done"`, Source: SourceFile, Origin: "[updatego] line 7"},
+		{Name: "log-format", Value: "text", Source: SourceFlag},
+		{Name: "quiet", Value: "true", Source: SourceEnv, Origin: "GO_SCRIPTS_QUIET"},
+		{Name: "verbose", Value: "false", Source: SourceEnv, Origin: "GO_SCRIPTS_UPDATEGO_VERBOSE"},
+		{Name: "wait", Value: "true", Source: SourceFile, Origin: "[updatego] line 6"},
+	}
+	if have := c.Settings(); !slices.Equal(have, want) {
+		t.Errorf("Settings() =\n%+v\nwant\n%+v", have, want)
+	}
+	if len(*hooks) != 2 {
+		t.Errorf("hooks = %q, want both configured hooks", *hooks)
+	}
+
+	var out bytes.Buffer
+	if err := c.Show(&out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if have := strings.Fields(lines[len(lines)-1]); !slices.Equal(have, []string{"wait", `"true"`, "config", "([updatego]", "line", "6)"}) {
+		t.Errorf("Show() =\n%s", out.String())
+	}
+}
+
+func TestLoadFileMissing(t *testing.T) {
+	fs, _ := newTestFlagSet()
+	c, err := LoadFile(filepath.Join(t.TempDir(), "config.toml"), "updatego", fs)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	for _, s := range c.Settings() {
+		if s.Source != SourceDefault {
+			t.Errorf("setting %s from %s, want default", s.Name, s.Source)
+		}
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	tests := map[string]string{
+		"unknown setting": "[updatego]\nwiat = true\n",
+		"invalid value":   "[updatego]\nwait = 3\n",
+		"syntax error":    "[updatego\n",
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			fs, _ := newTestFlagSet()
+			if _, err := LoadFile(writeTestConfig(t, content), "updatego", fs); err == nil {
+				t.Error("LoadFile() expected error")
+			}
+		})
+	}
+}
+
+func TestParseTOML(t *testing.T) {
+	tables, err := parseTOML(strings.NewReader(`
+top = 'literal # not a comment'
+[a]
+s = "tab\tquote\" hash#"  # comment
+n = 1_000
+f = 1.5
+b = false
+list = [ "x", 2, true ]
+empty = []
+"quoted key" = "v"
+multi = [
+  "golang.org/x/tools/gopls@latest", # language server
+  # linters
+  'go1.22:honnef.co/go/tools/cmd/staticcheck@2023.1.7'
+  , "last",
+]
+after = "multi"
+[b.c]
+`))
+	if err != nil {
+		t.Fatalf("parseTOML() error = %v", err)
+	}
+
+	want := map[string][]string{
+		"s":          {"tab\tquote\" hash#"},
+		"n":          {"1000"},
+		"f":          {"1.5"},
+		"b":          {"false"},
+		"list":       {"x", "2", "true"},
+		"empty":      {},
+		"quoted key": {"v"},
+		"multi":      {"golang.org/x/tools/gopls@latest", "go1.22:honnef.co/go/tools/cmd/staticcheck@2023.1.7", "last"},
+		"after":      {"multi"},
+	}
+	for key, values := range want {
+		if have := tables["a"][key].values; !slices.Equal(have, values) {
+			t.Errorf("%s = %q, want %q", key, have, values)
+		}
+	}
+	if have := tables[""]["top"].values; !slices.Equal(have, []string{"literal # not a comment"}) {
+		t.Errorf("top = %q", have)
+	}
+	if _, ok := tables["b.c"]; !ok {
+		t.Error("table [b.c] missing")
+	}
+	if line := tables["a"]["after"].line; line != 17 {
+		t.Errorf("after is on line %d, want 17", line)
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []string{
+		"key",
+		"key = bare",
+		`key = "unterminated`,
+		"key = [1, 2",
+		"key = [\n1,\n2\n",
+		"key = [\n1\n2\n]",
+		"key = 1\nkey = 2",
+		"[a]\n[a]",
+		"[]",
+		"bad key = 1",
+		`key = "a" "b"`,
+	}
+
+	for _, input := range tests {
+		if _, err := parseTOML(strings.NewReader(input)); err == nil {
+			t.Errorf("parseTOML(%q) expected error", input)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// value is a value of the config file with the line it was defined on.
+// Scalars have a single element, arrays one per entry.
+type value struct {
+	values []string
+	line   int
+}
+
+// table maps keys to values of one section of the config file
+type table map[string]value
+
+// errUnterminatedArray is returned for arrays continued on the next line
+var errUnterminatedArray = errors.New("unterminated array")
+
+// parseTOML parses the subset of TOML the config file needs: [tables],
+// key = value pairs with strings, integers, floats, booleans and arrays of
+// those, which may span lines, and comments. Top-level keys are stored under "".
+func parseTOML(r io.Reader) (map[string]table, error) {
+	tables := map[string]table{"": {}}
+	current := ""
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			name, ok := strings.CutSuffix(strings.TrimPrefix(line, "["), "]")
+			name = strings.TrimSpace(name)
+			if !ok || name == "" || strings.HasPrefix(name, "[") {
+				return nil, fmt.Errorf("line %d: invalid table header %q", lineNo, line)
+			}
+			if _, exists := tables[name]; exists && name != "" {
+				return nil, fmt.Errorf("line %d: table [%s] defined twice", lineNo, name)
+			}
+			tables[name] = table{}
+			current = name
+			continue
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNo)
+		}
+		key, err := parseKey(strings.TrimSpace(key))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if _, exists := tables[current][key]; exists {
+			return nil, fmt.Errorf("line %d: key %q defined twice", lineNo, key)
+		}
+
+		keyLine := lineNo
+		raw = strings.TrimSpace(raw)
+		values, err := parseValue(raw)
+		// Arrays continue until the closing bracket.
+		for errors.Is(err, errUnterminatedArray) && scanner.Scan() {
+			lineNo++
+			raw += " " + strings.TrimSpace(stripComment(scanner.Text()))
+			values, err = parseValue(raw)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", keyLine, err)
+		}
+		tables[current][key] = value{values: values, line: keyLine}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return tables, nil
+}
+
+// stripComment removes a trailing # comment that isn't part of a string
+func stripComment(line string) string {
+	var quote rune
+	escaped := false
+	for i, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func parseKey(key string) (string, error) {
+	if strings.HasPrefix(key, `"`) {
+		return strconv.Unquote(key)
+	}
+	if key == "" || strings.Trim(key, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_") != "" {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return key, nil
+}
+
+func parseValue(raw string) ([]string, error) {
+	inner, isArray := strings.CutPrefix(raw, "[")
+	if !isArray {
+		v, rest, err := parseScalar(raw)
+		if err != nil {
+			return nil, err
+		}
+		if rest != "" {
+			return nil, fmt.Errorf("unexpected %q after value", rest)
+		}
+		return []string{v}, nil
+	}
+
+	values := []string{}
+	for {
+		inner = strings.TrimSpace(inner)
+		if rest, ok := strings.CutPrefix(inner, "]"); ok {
+			if strings.TrimSpace(rest) != "" {
+				return nil, fmt.Errorf("unexpected %q after array", rest)
+			}
+			return values, nil
+		}
+		if inner == "" {
+			return nil, errUnterminatedArray
+		}
+
+		v, rest, err := parseScalar(inner)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+
+		rest = strings.TrimSpace(rest)
+		if after, ok := strings.CutPrefix(rest, ","); ok {
+			rest = after
+		} else if rest == "" {
+			return nil, errUnterminatedArray
+		} else if !strings.HasPrefix(rest, "]") {
+			return nil, fmt.Errorf("expected , or ] in array, got %q", rest)
+		}
+		inner = rest
+	}
+}
+
+// parseScalar parses the value at the start of s and returns it as flag
+// value along with the unparsed rest
+func parseScalar(s string) (string, string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		end := 1
+		for ; end < len(s); end++ {
+			if s[end] == '\\' {
+				end++
+			} else if s[end] == '"' {
+				break
+			}
+		}
+		if end >= len(s) {
+			return "", "", fmt.Errorf("unterminated string %s", s)
+		}
+		v, err := strconv.Unquote(s[:end+1])
+		if err != nil {
+			return "", "", fmt.Errorf("invalid string %s: %w", s[:end+1], err)
+		}
+		return v, strings.TrimSpace(s[end+1:]), nil
+	case strings.HasPrefix(s, "'"):
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated string %s", s)
+		}
+		return s[1 : end+1], strings.TrimSpace(s[end+2:]), nil
+	}
+
+	end := strings.IndexAny(s, ",]")
+	if end < 0 {
+		end = len(s)
+	}
+	token := strings.TrimSpace(s[:end])
+
+	switch {
+	case token == "true" || token == "false":
+	case isNumber(token):
+		token = strings.ReplaceAll(token, "_", "")
+	default:
+		return "", "", fmt.Errorf("invalid value %q, strings must be quoted", token)
+	}
+	return token, s[end:], nil
+}
+
+func isNumber(s string) bool {
+	s = strings.ReplaceAll(s, "_", "")
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return true
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
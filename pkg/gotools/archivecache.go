@@ -0,0 +1,86 @@
+package gotools
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultArchiveCacheDir returns the directory release archives are kept in
+// for verify-installation, next to the release metadata cache
+func DefaultArchiveCacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, "go-scripts", "archives"), nil
+}
+
+// CachedArchive returns the archive of version in CacheDir. The error wraps
+// fs.ErrNotExist if there is none. The archive was verified when it was
+// cached, callers verify it again before trusting it.
+func (d *Downloader) CachedArchive(version string) (string, error) {
+	if d.CacheDir == "" {
+		return "", fmt.Errorf("no archive cache: %w", fs.ErrNotExist)
+	}
+
+	path := filepath.Join(d.CacheDir, archiveFilename(version))
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("failed to find cached archive: %w", err)
+	}
+	return path, nil
+}
+
+// CacheArchive copies the verified archive of version at path into CacheDir.
+// Only the latest archive is kept, the ones of other versions are removed.
+func (d *Downloader) CacheArchive(path, version string) error {
+	if d.CacheDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(d.CacheDir, 0755); err != nil {
+		return fmt.Errorf("failed to create archive cache: %w", err)
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer src.Close()
+
+	filename := archiveFilename(version)
+	tmp, err := os.CreateTemp(d.CacheDir, filename+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file in archive cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to copy archive to cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(d.CacheDir, filename)); err != nil {
+		return fmt.Errorf("failed to cache archive: %w", err)
+	}
+
+	entries, err := os.ReadDir(d.CacheDir)
+	if err != nil {
+		return fmt.Errorf("failed to list archive cache: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.Name() == filename || !strings.HasSuffix(entry.Name(), ".tar.gz") {
+			continue
+		}
+		loggerOrDefault(d.Logger).Debug("Removing cached archive", "path", entry.Name())
+		if err := os.Remove(filepath.Join(d.CacheDir, entry.Name())); err != nil {
+			return fmt.Errorf("failed to remove cached archive: %w", err)
+		}
+	}
+
+	return nil
+}
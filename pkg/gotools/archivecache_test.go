@@ -0,0 +1,49 @@
+package gotools
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchiveCache(t *testing.T) {
+	downloader := &Downloader{CacheDir: filepath.Join(t.TempDir(), "archives")}
+	if _, err := downloader.CachedArchive("1.24.0"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("CachedArchive() of an empty cache error = %v, want fs.ErrNotExist", err)
+	}
+
+	download := filepath.Join(t.TempDir(), archiveFilename("1.24.0"))
+	if err := os.WriteFile(download, []byte("go1.24.0"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := downloader.CacheArchive(download, "1.24.0"); err != nil {
+		t.Fatalf("CacheArchive() error = %v", err)
+	}
+	path, err := downloader.CachedArchive("1.24.0")
+	if err != nil {
+		t.Fatalf("CachedArchive() error = %v", err)
+	}
+	if content, err := os.ReadFile(path); err != nil || string(content) != "go1.24.0" {
+		t.Errorf("cached archive = %q, %v, want the download", content, err)
+	}
+
+	// Caching a newer archive replaces the older one.
+	if err := os.WriteFile(download, []byte("go1.24.1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := downloader.CacheArchive(download, "1.24.1"); err != nil {
+		t.Fatalf("CacheArchive() error = %v", err)
+	}
+	if _, err := downloader.CachedArchive("1.24.0"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("CachedArchive() of the replaced version error = %v, want fs.ErrNotExist", err)
+	}
+	if entries, err := os.ReadDir(downloader.CacheDir); err != nil || len(entries) != 1 {
+		t.Errorf("archive cache = %v, %v, want only the latest archive", entries, err)
+	}
+
+	if _, err := (&Downloader{}).CachedArchive("1.24.1"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("CachedArchive() without a cache error = %v, want fs.ErrNotExist", err)
+	}
+}
@@ -0,0 +1,193 @@
+package gotools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// errNotChunked is returned by downloadChunked if the file can't be downloaded
+// in ranges and has to be fetched with a single request
+var errNotChunked = errors.New("chunked download not possible")
+
+// defaultMinChunkSize is the smallest range of a chunked download, smaller
+// files are fetched with fewer connections
+const defaultMinChunkSize = 1 << 20
+
+// chunk is a byte range of a chunked download
+type chunk struct {
+	start int64
+	// end is the last byte of the range
+	end int64
+	// written is the number of bytes of the range already downloaded
+	written int64
+}
+
+// splitChunks splits size bytes into at most n chunks of at least minSize bytes
+func splitChunks(size int64, n int, minSize int64) []chunk {
+	if minSize > 0 && size/minSize < int64(n) {
+		n = int(size / minSize)
+	}
+	n = max(n, 1)
+
+	chunks := make([]chunk, 0, n)
+	chunkSize := size / int64(n)
+	for i := range int64(n) {
+		end := (i+1)*chunkSize - 1
+		if i == int64(n)-1 {
+			end = size - 1
+		}
+		chunks = append(chunks, chunk{start: i * chunkSize, end: end})
+	}
+	return chunks
+}
+
+// downloadChunked downloads url into output with up to Connections concurrent
+// range requests. It returns errNotChunked if the server doesn't support
+// ranges. Like single downloads, the file is verified by VerifyChecksum.
+func (d *Downloader) downloadChunked(ctx context.Context, url string, output *os.File) error {
+	size, err := d.probeRanges(ctx, url)
+	if err != nil {
+		return err
+	}
+
+	minSize := d.minChunkSize
+	if minSize <= 0 {
+		minSize = defaultMinChunkSize
+	}
+	chunks := splitChunks(size, d.Connections, minSize)
+	if len(chunks) < 2 {
+		return fmt.Errorf("%w: file is too small to split", errNotChunked)
+	}
+
+	// Preallocate the file, the chunks are written at their offsets.
+	if err := output.Truncate(size); err != nil {
+		return fmt.Errorf("failed to preallocate file: %w", err)
+	}
+
+	loggerOrDefault(d.Logger).Debug("Downloading in chunks", "url", url, "size", size, "chunks", len(chunks))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for i := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := d.downloadRange(ctx, url, output, &chunks[i]); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				// Stop the other chunks, the download failed.
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return firstErr
+}
+
+// probeRanges returns the size of the file at url if the server accepts
+// range requests for it
+func (d *Downloader) probeRanges(ctx context.Context, url string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		return 0, fmt.Errorf("%w: %w", errNotChunked, err)
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode != http.StatusOK:
+		return 0, fmt.Errorf("%w: unexpected status code: %d", errNotChunked, resp.StatusCode)
+	case resp.Header.Get("Accept-Ranges") != "bytes":
+		return 0, fmt.Errorf("%w: server doesn't accept ranges", errNotChunked)
+	case resp.ContentLength <= 0:
+		return 0, fmt.Errorf("%w: unknown size", errNotChunked)
+	}
+
+	return resp.ContentLength, nil
+}
+
+// downloadRange downloads c into output with retries, a retry resumes after
+// the bytes already written
+func (d *Downloader) downloadRange(ctx context.Context, url string, output io.WriterAt, c *chunk) error {
+	logger := loggerOrDefault(d.Logger)
+
+	var lastSeenErr error
+	err := d.Retry.poll(ctx, func(ctx context.Context) (bool, error) {
+		from := c.start + c.written
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return false, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", from, c.end))
+
+		resp, err := d.client.Do(req)
+		if err != nil {
+			lastSeenErr = fmt.Errorf("failed to perform HTTP request: %w", err)
+			logger.Debug("Chunk download failed, retrying", "start", from, "error", lastSeenErr)
+			return false, nil
+		}
+		defer resp.Body.Close()
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return false, fmt.Errorf("%w: server ignored the range", errNotChunked)
+		case resp.StatusCode != http.StatusPartialContent:
+			lastSeenErr = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+			logger.Debug("Chunk download failed, retrying", "start", from, "error", lastSeenErr)
+			return false, nil
+		case !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", from)):
+			return false, fmt.Errorf("%w: unexpected content range %q", errNotChunked, resp.Header.Get("Content-Range"))
+		}
+
+		remaining := c.end + 1 - from
+		n, err := io.Copy(io.NewOffsetWriter(output, from), io.LimitReader(resp.Body, remaining))
+		c.written += n
+		d.received.Add(n)
+		if err == nil && n < remaining {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			lastSeenErr = fmt.Errorf("failed to copy response body: %w", err)
+			logger.Debug("Chunk download failed, retrying", "start", from, "error", lastSeenErr)
+			return false, nil
+		}
+
+		return true, nil
+	})
+
+	if err != nil {
+		if errors.Is(err, errNotChunked) {
+			return err
+		}
+		if lastSeenErr != nil {
+			return fmt.Errorf("download of bytes %d-%d failed with: %w", c.start, c.end, lastSeenErr)
+		}
+		return fmt.Errorf("download of bytes %d-%d failed after retries: %w", c.start, c.end, err)
+	}
+
+	return nil
+}
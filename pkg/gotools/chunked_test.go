@@ -0,0 +1,243 @@
+package gotools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ibihim/go-scripts/pkg/gotools/internal/releasetest"
+)
+
+func TestSplitChunks(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int64
+		n       int
+		minSize int64
+		want    []chunk
+	}{
+		{
+			name:    "even",
+			size:    100,
+			n:       4,
+			minSize: 10,
+			want:    []chunk{{start: 0, end: 24}, {start: 25, end: 49}, {start: 50, end: 74}, {start: 75, end: 99}},
+		},
+		{
+			name:    "remainder in last chunk",
+			size:    10,
+			n:       3,
+			minSize: 1,
+			want:    []chunk{{start: 0, end: 2}, {start: 3, end: 5}, {start: 6, end: 9}},
+		},
+		{
+			name:    "limited by minimum size",
+			size:    100,
+			n:       8,
+			minSize: 40,
+			want:    []chunk{{start: 0, end: 49}, {start: 50, end: 99}},
+		},
+		{
+			name:    "smaller than minimum size",
+			size:    10,
+			n:       4,
+			minSize: 40,
+			want:    []chunk{{start: 0, end: 9}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitChunks(tt.size, tt.n, tt.minSize); !slices.Equal(got, tt.want) {
+				t.Errorf("splitChunks(%d, %d, %d) = %v, want %v", tt.size, tt.n, tt.minSize, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadChunked(t *testing.T) {
+	const version = "1.24.1"
+
+	tests := []struct {
+		name         string
+		fault        releasetest.Fault
+		failures     int
+		connections  int
+		minChunkSize int64
+		// wantCorrupt is set if the download must fail VerifyChecksum
+		wantCorrupt bool
+		// wantRequests and wantProbes are the GET and HEAD requests of the archive
+		wantRequests int
+		wantProbes   int
+	}{
+		{
+			name:         "chunked",
+			connections:  4,
+			wantRequests: 4,
+			wantProbes:   1,
+		},
+		{
+			name:         "single connection",
+			connections:  1,
+			wantRequests: 1,
+		},
+		{
+			name:         "ranges unsupported",
+			fault:        releasetest.NoRanges,
+			connections:  4,
+			wantRequests: 1,
+			wantProbes:   1,
+		},
+		{
+			name:         "too small to split",
+			connections:  4,
+			minChunkSize: 1 << 20,
+			wantRequests: 1,
+			wantProbes:   1,
+		},
+		{
+			name:         "transient failures",
+			fault:        releasetest.Failing,
+			failures:     2,
+			connections:  4,
+			wantRequests: 6,
+			wantProbes:   1,
+		},
+		{
+			name:         "corrupt archive",
+			fault:        releasetest.Corrupt,
+			connections:  4,
+			wantCorrupt:  true,
+			wantRequests: 4,
+			wantProbes:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := releasetest.NewRelease(t, version)
+			server := releasetest.NewServer(t, release)
+			server.SetFault(tt.fault, tt.failures, 0)
+
+			downloader := NewDownloader()
+			downloader.BaseURL = server.DownloadURL()
+			downloader.TempDir = t.TempDir()
+			downloader.Retry = RetryPolicy{Interval: 10 * time.Millisecond, Timeout: time.Second}
+			downloader.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+			downloader.Connections = tt.connections
+			downloader.minChunkSize = tt.minChunkSize
+			if downloader.minChunkSize == 0 {
+				downloader.minChunkSize = 64
+			}
+			defer downloader.Cleanup()
+
+			path, err := downloader.Download(context.Background(), version)
+			if err != nil {
+				t.Fatalf("Download() error = %v", err)
+			}
+
+			// The checksum is only fetched by VerifyChecksum.
+			archive := "/go/" + release.Filename()
+			if have := server.Requests(archive + ".sha256"); have != 0 {
+				t.Errorf("checksum requests of Download() = %d, want none", have)
+			}
+			verified, err := downloader.VerifyChecksum(context.Background(), path, version)
+			if err != nil {
+				t.Fatalf("VerifyChecksum() error = %v", err)
+			}
+			if verified == tt.wantCorrupt {
+				t.Errorf("VerifyChecksum() = %t, want %t", verified, !tt.wantCorrupt)
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.wantCorrupt && !bytes.Equal(content, release.Archive) {
+				t.Errorf("downloaded %d bytes differing from the %d byte archive", len(content), len(release.Archive))
+			}
+
+			if have := server.Requests(archive); have != tt.wantRequests {
+				t.Errorf("archive requests = %d, want %d", have, tt.wantRequests)
+			}
+			if have := server.Requests("HEAD " + archive); have != tt.wantProbes {
+				t.Errorf("archive probes = %d, want %d", have, tt.wantProbes)
+			}
+		})
+	}
+}
+
+func TestDownloadChunkedResume(t *testing.T) {
+	archive := bytes.Repeat([]byte("0123456789"), 40)
+
+	var (
+		mu     sync.Mutex
+		ranges []string
+		cut    = make(map[int]bool)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/go1.24.1.linux-amd64.tar.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == http.MethodHead {
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(archive))
+			return
+		}
+
+		var start, end int
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		// Break off the first response of each range halfway.
+		first := !cut[end]
+		cut[end] = true
+		mu.Unlock()
+
+		body := archive[start : end+1]
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(archive)))
+		w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+		w.WriteHeader(http.StatusPartialContent)
+		if first {
+			body = body[:len(body)/2]
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	downloader := NewDownloader()
+	downloader.BaseURL = server.URL
+	downloader.TempDir = t.TempDir()
+	downloader.Retry = RetryPolicy{Interval: 10 * time.Millisecond, Timeout: time.Second}
+	downloader.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	downloader.Connections = 2
+	downloader.minChunkSize = 64
+	defer downloader.Cleanup()
+
+	path, err := downloader.Download(context.Background(), "1.24.1")
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if content, err := os.ReadFile(path); err != nil || !bytes.Equal(content, archive) {
+		t.Fatalf("downloaded %q, %v", content, err)
+	}
+
+	slices.Sort(ranges)
+	want := []string{"bytes=0-199", "bytes=100-199", "bytes=200-399", "bytes=300-399"}
+	if !slices.Equal(ranges, want) {
+		t.Errorf("ranges = %q, want %q", ranges, want)
+	}
+}
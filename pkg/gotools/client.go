@@ -2,10 +2,15 @@
 package gotools
 
 import (
+	"context"
+	"fmt"
 	"io"
+	"log/slog"
 	"net"
 	"net/http"
 	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 const (
@@ -14,6 +19,28 @@ const (
 	immediate = true
 )
 
+// RetryPolicy controls how failed requests are retried
+type RetryPolicy struct {
+	// Interval is the pause between two attempts
+	Interval time.Duration
+	// Timeout bounds the time of all attempts together
+	Timeout time.Duration
+}
+
+// DefaultRetryPolicy is used for every zero field of a RetryPolicy
+var DefaultRetryPolicy = RetryPolicy{Interval: interval, Timeout: timeout}
+
+// poll runs condition until it is done, fails or the policy times out
+func (p RetryPolicy) poll(ctx context.Context, condition wait.ConditionWithContextFunc) error {
+	if p.Interval <= 0 {
+		p.Interval = DefaultRetryPolicy.Interval
+	}
+	if p.Timeout <= 0 {
+		p.Timeout = DefaultRetryPolicy.Timeout
+	}
+	return wait.PollUntilContextTimeout(ctx, p.Interval, p.Timeout, immediate, condition)
+}
+
 // DefaultTimeouts defines sensible defaults for HTTP operations
 var DefaultTimeouts = struct {
 	// Connect timeout limits the time spent establishing a TCP connection
@@ -58,6 +85,59 @@ func NewHTTPClient() *http.Client {
 	}
 }
 
+// fetch GETs url with retries based on interval and timeout and returns the response body
+func fetch(ctx context.Context, client *http.Client, url string, logger *slog.Logger) ([]byte, error) {
+	var body []byte
+	var lastSeenErr error
+
+	err := wait.PollUntilContextTimeout(ctx, interval, timeout, immediate, func(ctx context.Context) (bool, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return false, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			lastSeenErr = fmt.Errorf("failed to perform HTTP request: %w", err)
+			loggerOrDefault(logger).Debug("Request failed, retrying", "url", url, "error", lastSeenErr)
+			return false, nil // Temporary error, retry
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			lastSeenErr = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+			loggerOrDefault(logger).Debug("Request failed, retrying", "url", url, "error", lastSeenErr)
+			return false, nil // Non-200 status code, retry
+		}
+
+		body, err = io.ReadAll(resp.Body)
+		if err != nil {
+			lastSeenErr = fmt.Errorf("failed to read response: %w", err)
+			return false, nil // Read error, retry
+		}
+
+		return true, nil
+	})
+
+	if err != nil {
+		if lastSeenErr != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", url, lastSeenErr)
+		}
+
+		return nil, fmt.Errorf("failed to fetch %s after retries: %w", url, err)
+	}
+
+	return body, nil
+}
+
+// loggerOrDefault returns logger, or the default logger if it is nil
+func loggerOrDefault(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.Default()
+	}
+	return logger
+}
+
 func safeClose(body io.Closer) {
 	if body != nil {
 		body.Close()
@@ -0,0 +1,156 @@
+package gotools
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// extractedSizeFactor estimates the extracted size of a release from its
+// archive size. A Go release extracts to about three and a half times its
+// size, the factor leaves some headroom.
+const extractedSizeFactor = 4
+
+// releaseCacheSize is the room kept for rewriting the release metadata cache,
+// the list of all releases is a few MiB
+const releaseCacheSize = 8 << 20
+
+// ErrInsufficientSpace is returned if a filesystem lacks the space for a stage
+var ErrInsufficientSpace = errors.New("insufficient disk space")
+
+// SpaceRequirement is the space a stage of the pipeline needs below a path
+type SpaceRequirement struct {
+	// Purpose describes the stage for error messages, e.g. "download"
+	Purpose string
+	// Path is where the stage writes, it doesn't need to exist yet
+	Path string
+	// Bytes is the space needed
+	Bytes uint64
+}
+
+// CheckFreeSpace checks that every filesystem has room for the requirements
+// placed on it. Requirements on the same filesystem add up, as they need to
+// fit at the same time.
+func CheckFreeSpace(requirements ...SpaceRequirement) error {
+	type filesystem struct {
+		path      string
+		available uint64
+		needed    uint64
+		purposes  []string
+	}
+
+	var order []uint64
+	filesystems := make(map[uint64]*filesystem)
+
+	for _, req := range requirements {
+		if req.Bytes == 0 {
+			continue
+		}
+
+		existing, err := existingAncestor(req.Path)
+		if err != nil {
+			return fmt.Errorf("failed to check free space for %s: %w", req.Purpose, err)
+		}
+
+		var stat syscall.Stat_t
+		if err := syscall.Stat(existing, &stat); err != nil {
+			return fmt.Errorf("failed to stat %s: %w", existing, err)
+		}
+
+		fs, ok := filesystems[stat.Dev]
+		if !ok {
+			var statfs syscall.Statfs_t
+			if err := syscall.Statfs(existing, &statfs); err != nil {
+				return fmt.Errorf("failed to statfs %s: %w", existing, err)
+			}
+
+			// Bavail excludes the blocks reserved for root, unlike Bfree.
+			fs = &filesystem{path: existing, available: statfs.Bavail * uint64(statfs.Bsize)}
+			filesystems[stat.Dev] = fs
+			order = append(order, stat.Dev)
+		}
+
+		fs.needed += req.Bytes
+		fs.purposes = append(fs.purposes, fmt.Sprintf("%s in %s", req.Purpose, req.Path))
+	}
+
+	var errs []error
+	for _, dev := range order {
+		fs := filesystems[dev]
+		if fs.needed > fs.available {
+			errs = append(errs, fmt.Errorf("%w on the filesystem of %s: need %s for %s, only %s available",
+				ErrInsufficientSpace, fs.path, formatBytes(fs.needed), strings.Join(fs.purposes, " and "), formatBytes(fs.available)))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// existingAncestor returns path or its closest existing parent directory
+func existingAncestor(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", fmt.Errorf("no existing parent directory for %s", path)
+		}
+		path = parent
+	}
+}
+
+// formatBytes formats a byte count for humans, e.g. "75.2 MiB"
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// Preflight checks that there is room for downloading the archive of release
+// into the temporary directory, for extracting it into InstallDir and for
+// updating the release metadata cache of the checker, if it has one.
+// Releases without a known archive size are not checked.
+func (u *Updater) Preflight(release *GoRelease) error {
+	return CheckFreeSpace(u.spaceRequirements(release)...)
+}
+
+// spaceRequirements returns the space the update to release needs, nothing if
+// the archive size is unknown
+func (u *Updater) spaceRequirements(release *GoRelease) []SpaceRequirement {
+	archive := release.Archive()
+	if archive == nil || archive.Size <= 0 {
+		return nil
+	}
+
+	size := uint64(archive.Size)
+	requirements := []SpaceRequirement{
+		{Purpose: "download", Path: cmp.Or(u.Downloader.TempDir, os.TempDir()), Bytes: size},
+		{Purpose: "extraction", Path: u.Installer.InstallDir, Bytes: size * extractedSizeFactor},
+	}
+	if u.Checker != nil && u.Checker.CachePath != "" {
+		requirements = append(requirements, SpaceRequirement{Purpose: "release cache", Path: u.Checker.CachePath, Bytes: releaseCacheSize})
+	}
+	return requirements
+}
@@ -0,0 +1,104 @@
+package gotools
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+)
+
+func TestCheckFreeSpace(t *testing.T) {
+	dir := t.TempDir()
+	var statfs syscall.Statfs_t
+	if err := syscall.Statfs(dir, &statfs); err != nil {
+		t.Fatal(err)
+	}
+	available := statfs.Bavail * uint64(statfs.Bsize)
+	missing := filepath.Join(dir, "not", "yet", "created")
+
+	tests := []struct {
+		name         string
+		requirements []SpaceRequirement
+		wantErr      bool
+	}{
+		{
+			name:         "fits",
+			requirements: []SpaceRequirement{{Purpose: "download", Path: dir, Bytes: 1024}},
+		},
+		{
+			name:         "path doesn't exist yet",
+			requirements: []SpaceRequirement{{Purpose: "extraction", Path: missing, Bytes: 1024}},
+		},
+		{
+			name:         "too large",
+			requirements: []SpaceRequirement{{Purpose: "download", Path: dir, Bytes: available + 1<<30}},
+			wantErr:      true,
+		},
+		{
+			name: "same filesystem adds up",
+			requirements: []SpaceRequirement{
+				{Purpose: "download", Path: dir, Bytes: available/2 + 1<<20},
+				{Purpose: "extraction", Path: missing, Bytes: available/2 + 1<<20},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckFreeSpace(tt.requirements...)
+			if tt.wantErr != errors.Is(err, ErrInsufficientSpace) {
+				t.Errorf("CheckFreeSpace() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		bytes uint64
+		want  string
+	}{
+		{bytes: 512, want: "512 B"},
+		{bytes: 1536, want: "1.5 KiB"},
+		{bytes: 75 << 20, want: "75.0 MiB"},
+		{bytes: 3 << 30, want: "3.0 GiB"},
+	}
+
+	for _, tt := range tests {
+		if have := formatBytes(tt.bytes); have != tt.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", tt.bytes, have, tt.want)
+		}
+	}
+}
+
+func TestSpaceRequirements(t *testing.T) {
+	release := &GoRelease{
+		Version: "go1.24.1",
+		Files:   []GoFile{{Filename: archiveFilename("1.24.1"), Size: 75 << 20}},
+	}
+	updater := &Updater{
+		Checker:    &Checker{CachePath: "/home/gopher/.cache/go-scripts/releases.json"},
+		Downloader: &Downloader{TempDir: "/var/tmp"},
+		Installer:  &Installer{InstallDir: "/home/gopher/.local/lib"},
+	}
+
+	want := []SpaceRequirement{
+		{Purpose: "download", Path: "/var/tmp", Bytes: 75 << 20},
+		{Purpose: "extraction", Path: "/home/gopher/.local/lib", Bytes: 300 << 20},
+		{Purpose: "release cache", Path: "/home/gopher/.cache/go-scripts/releases.json", Bytes: releaseCacheSize},
+	}
+	if got := updater.spaceRequirements(release); !slices.Equal(got, want) {
+		t.Errorf("spaceRequirements() = %+v, want %+v", got, want)
+	}
+
+	updater.Checker.CachePath = ""
+	if got := updater.spaceRequirements(release); !slices.Equal(got, want[:2]) {
+		t.Errorf("spaceRequirements() without a cache = %+v, want %+v", got, want[:2])
+	}
+
+	if got := updater.spaceRequirements(&GoRelease{Version: "go1.24.1"}); got != nil {
+		t.Errorf("spaceRequirements() without an archive size = %+v, want none", got)
+	}
+}
@@ -4,38 +4,97 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
-
-	"k8s.io/apimachinery/pkg/util/wait"
+	"sync"
+	"sync/atomic"
 )
 
 // Downloader handles downloading Go releases
 type Downloader struct {
+	// BaseURL is the location of the release archives and their checksums
+	BaseURL string
+	// Retry controls how failed requests are retried
+	Retry  RetryPolicy
 	client *http.Client
+	// Logger receives progress and retry messages
+	Logger *slog.Logger
+	// TempDir is where the temporary download directories are created,
+	// empty uses os.TempDir()
+	TempDir string
+	// Connections is the number of concurrent range requests a download is
+	// split into, downloads use a single request if it is below 2 or the
+	// server doesn't support ranges
+	Connections int
+	// CacheDir keeps a verified archive for CachedArchive, empty disables
+	// the cache
+	CacheDir string
+
+	// minChunkSize overrides defaultMinChunkSize
+	minChunkSize int64
+
+	mu       sync.Mutex
+	tempDirs []string
+	// received counts the bytes of all archive downloads, including retries
+	received atomic.Int64
 }
 
+// DefaultDownloadURL is the official location of the Go release archives
+const DefaultDownloadURL = "https://dl.google.com/go/"
+
 // NewDownloader creates a new downloader with the given options
 func NewDownloader() *Downloader {
 	return &Downloader{
-		client: NewHTTPClient(), // Using the shared HTTP client
+		BaseURL: DefaultDownloadURL,
+		client:  NewHTTPClient(), // Using the shared HTTP client
+		Logger:  slog.Default(),
 	}
 }
 
-// Download downloads the Go release for the given version
-func (d *Downloader) Download(ctx context.Context, version string) (string, error) {
+// SetHTTPClient makes the downloader use client, e.g. one from NewHTTPClientWithOptions
+func (d *Downloader) SetHTTPClient(client *http.Client) {
+	d.client = client
+}
+
+// fileURL returns the URL of filename below BaseURL
+func (d *Downloader) fileURL(filename string) string {
+	base := d.BaseURL
+	if base == "" {
+		base = DefaultDownloadURL
+	}
+	return strings.TrimSuffix(base, "/") + "/" + filename
+}
+
+// archiveFilename returns the name of the release archive for version, e.g. "go1.24.1.linux-amd64.tar.gz"
+func archiveFilename(version string) string {
+	return fmt.Sprintf("go%s.linux-amd64.tar.gz", version)
+}
+
+// Download downloads the Go release for the given version. The archive is
+// stored in a temporary directory that lives until Cleanup, a failed download
+// removes it right away.
+func (d *Downloader) Download(ctx context.Context, version string) (_ string, err error) {
 	// Create temporary directory and create file handle.
-	tmpDir, err := os.MkdirTemp("", "goupdate-*")
+	tmpDir, err := createTempDir(d.TempDir)
 	if err != nil {
-		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+		return "", err
 	}
+	d.trackTempDir(tmpDir)
+	defer func() {
+		if err != nil {
+			d.removeTempDir(tmpDir)
+		}
+	}()
 
-	filename := fmt.Sprintf("go%s.linux-amd64.tar.gz", version)
-	url := fmt.Sprintf("https://dl.google.com/go/%s", filename)
+	filename := archiveFilename(version)
+	url := d.fileURL(filename)
 	outputPath := filepath.Join(tmpDir, filename)
 
 	output, err := os.Create(outputPath)
@@ -44,9 +103,37 @@ func (d *Downloader) Download(ctx context.Context, version string) (string, erro
 	}
 	defer output.Close()
 
+	logger := loggerOrDefault(d.Logger)
+	logger.Info("Downloading Go", "version", version, "url", url)
+
+	if d.Connections > 1 {
+		err := d.downloadChunked(ctx, url, output)
+		if err == nil {
+			logger.Debug("Downloaded Go", "version", version, "path", outputPath)
+			return outputPath, nil
+		}
+		if !errors.Is(err, errNotChunked) {
+			return "", fmt.Errorf("download failed with: %w", err)
+		}
+		logger.Debug("Downloading with a single request", "reason", err)
+	}
+
+	if err := d.downloadSingle(ctx, url, output); err != nil {
+		return "", err
+	}
+
+	logger.Debug("Downloaded Go", "version", version, "path", outputPath)
+	return outputPath, nil
+}
+
+// downloadSingle downloads url into output with a single request, retrying
+// from the start on failures
+func (d *Downloader) downloadSingle(ctx context.Context, url string, output *os.File) error {
+	logger := loggerOrDefault(d.Logger)
+
 	// Try to download the file.
 	var lastSeenErr error
-	err = wait.PollUntilContextTimeout(ctx, interval, timeout, immediate, func(ctx context.Context) (bool, error) {
+	err := d.Retry.poll(ctx, func(ctx context.Context) (bool, error) {
 		// Reset file position and truncate file to the beginning.
 		if _, err := output.Seek(0, 0); err != nil {
 			return false, fmt.Errorf("failed to reset file position: %w", err)
@@ -63,18 +150,22 @@ func (d *Downloader) Download(ctx context.Context, version string) (string, erro
 		resp, err := d.client.Do(req)
 		if err != nil {
 			lastSeenErr = fmt.Errorf("failed to perform HTTP request: %w", err)
+			logger.Debug("Download failed, retrying", "error", lastSeenErr)
 			return false, nil // Temporary error, retry
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
 			lastSeenErr = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+			logger.Debug("Download failed, retrying", "error", lastSeenErr)
 			return false, nil // Non-200 status code, retry
 		}
 
-		_, err = io.Copy(output, resp.Body)
+		n, err := io.Copy(output, resp.Body)
+		d.received.Add(n)
 		if err != nil {
 			lastSeenErr = fmt.Errorf("failed to copy response body: %w", err)
+			logger.Debug("Download failed, retrying", "error", lastSeenErr)
 			return false, nil
 		}
 
@@ -83,13 +174,56 @@ func (d *Downloader) Download(ctx context.Context, version string) (string, erro
 
 	if err != nil {
 		if lastSeenErr != nil {
-			return "", fmt.Errorf("download failed with: %w", lastSeenErr)
+			return fmt.Errorf("download failed with: %w", lastSeenErr)
 		}
 
-		return "", fmt.Errorf("download failed after retries: %w", err)
+		return fmt.Errorf("download failed after retries: %w", err)
 	}
 
-	return outputPath, nil
+	return nil
+}
+
+// Received returns the number of bytes received by downloads so far,
+// including failed attempts
+func (d *Downloader) Received() int64 {
+	return d.received.Load()
+}
+
+// Cleanup removes the temporary directories of all downloads, including the
+// archives returned by Download
+func (d *Downloader) Cleanup() error {
+	d.mu.Lock()
+	dirs := d.tempDirs
+	d.tempDirs = nil
+	d.mu.Unlock()
+
+	var errs []error
+	for _, dir := range dirs {
+		loggerOrDefault(d.Logger).Debug("Removing temporary directory", "path", dir)
+		if err := os.RemoveAll(dir); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove temporary directory %s: %w", dir, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// trackTempDir records dir for removal by Cleanup
+func (d *Downloader) trackTempDir(dir string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.tempDirs = append(d.tempDirs, dir)
+}
+
+// removeTempDir removes dir now instead of in Cleanup
+func (d *Downloader) removeTempDir(dir string) {
+	d.mu.Lock()
+	d.tempDirs = slices.DeleteFunc(d.tempDirs, func(tracked string) bool { return tracked == dir })
+	d.mu.Unlock()
+
+	if err := os.RemoveAll(dir); err != nil {
+		loggerOrDefault(d.Logger).Warn("Failed to remove temporary directory", "path", dir, "error", err)
+	}
 }
 
 // VerifyChecksum verifies the downloaded file checksum
@@ -106,17 +240,19 @@ func (d *Downloader) VerifyChecksum(ctx context.Context, filePath, version strin
 		return false, fmt.Errorf("failed to calculate checksum: %w", err)
 	}
 
+	loggerOrDefault(d.Logger).Debug("Verifying checksum", "path", filePath, "expected", expectedSum, "actual", actualSum)
+
 	return expectedSum == actualSum, nil
 }
 
 // fetchChecksum fetches the expected checksum for a version
 func (d *Downloader) fetchChecksum(ctx context.Context, version string) (string, error) {
-	checksumURL := fmt.Sprintf("https://dl.google.com/go/go%s.linux-amd64.tar.gz.sha256", version)
+	checksumURL := d.fileURL(archiveFilename(version) + ".sha256")
 
 	var checksumBytes []byte
 	var lastSeenErr error
 
-	err := wait.PollUntilContextTimeout(ctx, interval, timeout, immediate, func(ctx context.Context) (bool, error) {
+	err := d.Retry.poll(ctx, func(ctx context.Context) (bool, error) {
 		req, err := http.NewRequestWithContext(ctx, "GET", checksumURL, nil)
 		if err != nil {
 			return false, fmt.Errorf("failed to create checksum request: %w", err)
@@ -166,14 +302,18 @@ func (d *Downloader) fetchChecksum(ctx context.Context, version string) (string,
 
 // calculateChecksum calculates the SHA256 checksum of a file
 func (d *Downloader) calculateChecksum(filePath string) (string, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to open file for checksum calculation: %w", err)
-	}
-	defer file.Close()
+	return calculateFileChecksum(filePath)
+}
+
+// calculateFileChecksum calculates the hex encoded SHA256 checksum of a file
+func calculateFileChecksum(filePath string) (string, error) {
+	return fileChecksum(OSFileSystem{}, filePath)
+}
 
+// readerChecksum calculates the hex encoded SHA256 checksum of everything read from r
+func readerChecksum(r io.Reader) (string, error) {
 	hash := sha256.New()
-	if _, err := io.Copy(hash, file); err != nil {
+	if _, err := io.Copy(hash, r); err != nil {
 		return "", fmt.Errorf("failed to read file for checksum calculation: %w", err)
 	}
 
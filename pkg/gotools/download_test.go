@@ -1,17 +1,28 @@
 package gotools
 
 import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestTemporaryDirCreation(t *testing.T) {
-	tmpDir, err := os.MkdirTemp("", "goupdate-*")
+	tmpDir, err := createTempDir(t.TempDir())
 	if err != nil {
 		t.Fatal(err)
 	}
 
+	owner, err := readLockOwner(filepath.Join(tmpDir, tempOwnerFile))
+	if err != nil || owner.PID != os.Getpid() {
+		t.Fatalf("owner of %s = %v, %v", tmpDir, owner, err)
+	}
+
 	t.Log("tmpDir path:", tmpDir)
 
 	filename := "go1.24.1.linux-amd64.tar.gz"
@@ -24,3 +35,52 @@ func TestTemporaryDirCreation(t *testing.T) {
 	}
 	defer output.Close()
 }
+
+func TestDownloadCleanup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/go1.24.1.linux-amd64.tar.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("archive"))
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	downloader := NewDownloader()
+	downloader.BaseURL = server.URL
+	downloader.TempDir = tmpDir
+	downloader.Retry = RetryPolicy{Interval: 10 * time.Millisecond, Timeout: 50 * time.Millisecond}
+	downloader.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	leftovers := func() []string {
+		t.Helper()
+		paths, err := filepath.Glob(filepath.Join(tmpDir, TempPattern))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return paths
+	}
+
+	if _, err := downloader.Download(context.Background(), "1.0.0"); err == nil {
+		t.Fatal("Download() of a missing version expected an error")
+	}
+	if paths := leftovers(); len(paths) != 0 {
+		t.Errorf("failed download left %q behind", paths)
+	}
+
+	path, err := downloader.Download(context.Background(), "1.24.1")
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if content, err := os.ReadFile(path); err != nil || string(content) != "archive" {
+		t.Fatalf("downloaded %q, %v", content, err)
+	}
+
+	if err := downloader.Cleanup(); err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	if paths := leftovers(); len(paths) != 0 {
+		t.Errorf("Cleanup() left %q behind", paths)
+	}
+}
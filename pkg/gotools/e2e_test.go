@@ -0,0 +1,324 @@
+package gotools_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibihim/go-scripts/pkg/gotools"
+	"github.com/ibihim/go-scripts/pkg/gotools/internal/releasetest"
+)
+
+// e2eVersion is the latest stable release of the test server
+const e2eVersion = "1.99.0"
+
+// newE2EUpdater returns an updater for the release server, installing into
+// a temporary HOME that holds an old installation. On-failure and
+// after-install hooks record their environment in $HOME/hook.
+func newE2EUpdater(t *testing.T, server *releasetest.Server) (*gotools.Updater, string) {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	oldVersion := filepath.Join(home, ".local", "lib", "go", "VERSION")
+	if err := os.MkdirAll(filepath.Dir(oldVersion), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(oldVersion, []byte("go1.0.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	checker := gotools.NewChecker()
+	checker.ReleasesURL = server.ReleasesURL()
+
+	downloader := gotools.NewDownloader()
+	downloader.BaseURL = server.DownloadURL()
+	downloader.Retry = gotools.RetryPolicy{Interval: 10 * time.Millisecond, Timeout: 500 * time.Millisecond}
+
+	hooks := gotools.NewHooks()
+	hooks.Stdout, hooks.Stderr = io.Discard, io.Discard
+	record := gotools.Hook{Command: `echo "$UPDATEGO_HOOK $UPDATEGO_NEW_VERSION" > "$HOME/hook"`}
+	hooks.Add(gotools.HookAfterInstall, record)
+	hooks.Add(gotools.HookOnFailure, record)
+
+	updater := &gotools.Updater{
+		Checker:    checker,
+		Downloader: downloader,
+		Installer: &gotools.Installer{
+			InstallDir: filepath.Join(home, ".local", "lib"),
+			BinDir:     filepath.Join(home, ".local", "bin"),
+		},
+		Hooks: hooks,
+	}
+	updater.SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	return updater, home
+}
+
+func TestE2EUpdate(t *testing.T) {
+	tests := []struct {
+		name     string
+		fault    releasetest.Fault
+		failures int
+		delay    time.Duration
+		timeout  time.Duration
+		// wantErr is a substring of the update error, empty if it succeeds
+		wantErr string
+		// wantStage is the pipeline stage that fails
+		wantStage gotools.Stage
+		// wantRequests is the number of archive downloads, zero skips the check
+		wantRequests int
+	}{
+		{
+			name:         "update",
+			wantRequests: 1,
+		},
+		{
+			name:         "transient failures",
+			fault:        releasetest.Failing,
+			failures:     2,
+			wantRequests: 3,
+		},
+		{
+			name:  "slow download",
+			fault: releasetest.Slow,
+			delay: time.Millisecond,
+		},
+		{
+			name:      "corrupt archive",
+			fault:     releasetest.Corrupt,
+			wantErr:   "could not be verified",
+			wantStage: gotools.StageVerify,
+		},
+		{
+			name:      "failing server",
+			fault:     releasetest.Failing,
+			wantErr:   "failed to download latest version",
+			wantStage: gotools.StageDownload,
+		},
+		{
+			name:      "download too slow",
+			fault:     releasetest.Slow,
+			delay:     100 * time.Millisecond,
+			timeout:   300 * time.Millisecond,
+			wantErr:   "context deadline exceeded",
+			wantStage: gotools.StageDownload,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unstable := releasetest.NewRelease(t, "1.100.0")
+			unstable.Stable = false
+			server := releasetest.NewServer(t, unstable, releasetest.NewRelease(t, e2eVersion), releasetest.NewRelease(t, "1.98.0"))
+			server.SetFault(tt.fault, tt.failures, tt.delay)
+			updater, home := newE2EUpdater(t, server)
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			status, err := updater.Check(ctx)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if !status.NeedsUpdate || status.Latest != e2eVersion {
+				t.Fatalf("Check() = %+v, want an update to %s", status, e2eVersion)
+			}
+
+			err = updater.Update(ctx, status)
+
+			if leftovers, _ := filepath.Glob(filepath.Join(os.Getenv("TMPDIR"), gotools.TempPattern)); len(leftovers) > 0 {
+				t.Errorf("temporary files left behind: %q", leftovers)
+			}
+
+			hook, _ := os.ReadFile(filepath.Join(home, "hook"))
+			installed, _ := updater.Installer.InstalledVersion()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Update() error = %v, want %q", err, tt.wantErr)
+				}
+				if stage := gotools.FailedStage(err); stage != tt.wantStage {
+					t.Errorf("FailedStage() = %q, want %q", stage, tt.wantStage)
+				}
+				if want := "on-failure " + e2eVersion + "\n"; string(hook) != want {
+					t.Errorf("hook output = %q, want %q", hook, want)
+				}
+				if installed != "1.0.0" {
+					t.Errorf("installed version after failure = %q, want the old 1.0.0", installed)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if want := "after-install " + e2eVersion + "\n"; string(hook) != want {
+				t.Errorf("hook output = %q, want %q", hook, want)
+			}
+			if installed != e2eVersion {
+				t.Errorf("installed version = %q, want %s", installed, e2eVersion)
+			}
+			output, err := exec.Command(filepath.Join(home, ".local", "bin", "go"), "version").Output()
+			if want := "go version go" + e2eVersion + " linux/amd64\n"; err != nil || string(output) != want {
+				t.Errorf("go version = %q, %v, want %q", output, err, want)
+			}
+
+			archive := "/go/go" + e2eVersion + ".linux-amd64.tar.gz"
+			if have := server.Requests(archive); tt.wantRequests > 0 && have != tt.wantRequests {
+				t.Errorf("archive requests = %d, want %d", have, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestE2ECheck(t *testing.T) {
+	tests := []struct {
+		name string
+		// installed is the version in the VERSION file, empty removes it
+		installed       string
+		wantNeedsUpdate bool
+	}{
+		{name: "up to date", installed: e2eVersion},
+		{name: "outdated", installed: "1.98.0", wantNeedsUpdate: true},
+		{name: "not installed", wantNeedsUpdate: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := releasetest.NewServer(t, releasetest.NewRelease(t, e2eVersion), releasetest.NewRelease(t, "1.98.0"))
+			updater, home := newE2EUpdater(t, server)
+
+			version := filepath.Join(home, ".local", "lib", "go", "VERSION")
+			if tt.installed == "" {
+				if err := os.Remove(version); err != nil {
+					t.Fatal(err)
+				}
+			} else if err := os.WriteFile(version, []byte("go"+tt.installed+"\ntime 2025-03-04T10:00:00Z\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			status, err := updater.Check(context.Background())
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if status.Installed != tt.installed || status.NeedsUpdate != tt.wantNeedsUpdate {
+				t.Errorf("Check() = installed %q, needs update %t, want %q, %t",
+					status.Installed, status.NeedsUpdate, tt.installed, tt.wantNeedsUpdate)
+			}
+			if server.Requests("/go/go"+e2eVersion+".linux-amd64.tar.gz") != 0 {
+				t.Error("Check() downloaded an archive")
+			}
+		})
+	}
+}
+
+func TestE2ECancelledDownload(t *testing.T) {
+	server := releasetest.NewServer(t, releasetest.NewRelease(t, e2eVersion))
+	server.SetFault(releasetest.Slow, 0, 50*time.Millisecond)
+	updater, _ := newE2EUpdater(t, server)
+	updater.Downloader.Retry.Timeout = time.Minute
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	status, err := updater.Check(ctx)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	// Cancel like Ctrl-C once the archive is being downloaded.
+	archive := "/go/go" + e2eVersion + ".linux-amd64.tar.gz"
+	go func() {
+		for server.Requests(archive) == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	err = updater.Update(ctx, status)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Update() error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Update() returned %s after the start of the download", elapsed)
+	}
+	if installed, _ := updater.Installer.InstalledVersion(); installed != "1.0.0" {
+		t.Errorf("installed version after cancellation = %q, want the old 1.0.0", installed)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(os.Getenv("TMPDIR"), gotools.TempPattern)); len(leftovers) > 0 {
+		t.Errorf("temporary files left behind: %q", leftovers)
+	}
+}
+
+// slowToolRunner runs go install until ctx is done or for ten seconds, other
+// commands like the version check of the installation run on the host
+type slowToolRunner struct {
+	started chan struct{}
+	err     chan error
+}
+
+func (r slowToolRunner) Run(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+	if len(args) == 0 || args[0] != "install" {
+		return gotools.ExecRunner{}.Run(ctx, dir, env, name, args...)
+	}
+	close(r.started)
+	select {
+	case <-ctx.Done():
+	case <-time.After(10 * time.Second):
+	}
+	r.err <- ctx.Err()
+	return nil, ctx.Err()
+}
+
+// TestE2EInterruptedTools interrupts the tool builds after the deadline of the
+// installation, Ctrl-C must still reach them.
+func TestE2EInterruptedTools(t *testing.T) {
+	server := releasetest.NewServer(t, releasetest.NewRelease(t, e2eVersion))
+	updater, _ := newE2EUpdater(t, server)
+	updater.Timeout = 500 * time.Millisecond
+	updater.Tools = []gotools.Tool{{Package: "golang.org/x/tools/gopls", Version: "latest"}}
+	runner := slowToolRunner{started: make(chan struct{}), err: make(chan error, 1)}
+	updater.Installer.Runner = runner
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	status, err := updater.Check(ctx)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	go func() {
+		<-runner.started
+		time.Sleep(2 * updater.Timeout)
+		cancel()
+	}()
+
+	// The interrupted after-install hook fails the update, Go is installed.
+	start := time.Now()
+	updater.Update(ctx, status)
+	if err := <-runner.err; !errors.Is(err, context.Canceled) {
+		t.Errorf("tool build stopped with %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Update() returned %s after the start, the interruption didn't reach the tools", elapsed)
+	}
+	if installed, _ := updater.Installer.InstalledVersion(); installed != e2eVersion {
+		t.Errorf("installed version = %q, want %s", installed, e2eVersion)
+	}
+}
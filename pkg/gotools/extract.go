@@ -0,0 +1,429 @@
+package gotools
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ExtractLimits bounds the resources an archive may consume during extraction.
+// They protect against decompression bombs, a Go release is well below them.
+type ExtractLimits struct {
+	// MaxTotalSize limits the sum of all extracted file sizes in bytes
+	MaxTotalSize int64
+	// MaxFileSize limits the size of a single extracted file in bytes
+	MaxFileSize int64
+	// MaxEntries limits the number of entries in the archive
+	MaxEntries int
+}
+
+// DefaultExtractLimits are used for every zero field of Installer.Limits
+var DefaultExtractLimits = ExtractLimits{
+	MaxTotalSize: 2 << 30,   // 2 GiB, a Go release extracts to about 250 MiB
+	MaxFileSize:  512 << 20, // 512 MiB
+	MaxEntries:   100_000,   // a Go release has about 15k entries
+}
+
+// DefaultExtractWorkers is the number of goroutines writing extracted files.
+// Writing is mostly waiting on the filesystem, so more workers than CPUs pay
+// off, especially on network filesystems.
+const DefaultExtractWorkers = 8
+
+// parallelMaxFileSize is the largest file handed to the write workers. Larger
+// files are written by the reading goroutine to bound the buffered memory.
+const parallelMaxFileSize = 4 << 20
+
+// ErrUnsafeArchive is returned for archives that try to escape the extraction
+// root or exceed the extraction limits.
+var ErrUnsafeArchive = errors.New("unsafe archive")
+
+// withDefaults fills zero fields with DefaultExtractLimits
+func (l ExtractLimits) withDefaults() ExtractLimits {
+	if l.MaxTotalSize <= 0 {
+		l.MaxTotalSize = DefaultExtractLimits.MaxTotalSize
+	}
+	if l.MaxFileSize <= 0 {
+		l.MaxFileSize = DefaultExtractLimits.MaxFileSize
+	}
+	if l.MaxEntries <= 0 {
+		l.MaxEntries = DefaultExtractLimits.MaxEntries
+	}
+	return l
+}
+
+// extractor safely writes the entries of a tar stream below root
+type extractor struct {
+	fs     FileSystem
+	root   string
+	limits ExtractLimits
+	// include selects the entries to extract, nil extracts all entries
+	include func(name string) bool
+	// workers is the number of goroutines writing files, 1 extracts serially
+	workers int
+	pool    *writePool
+	logger  *slog.Logger
+
+	entries int
+	written int64
+	// dirs are the directory entries, their metadata is restored once all
+	// children are written, as writing a child changes the directory's mtime
+	// and a read-only directory mode would prevent writing children at all.
+	dirs []dirMetadata
+}
+
+// dirMetadata is the mode and modification time of an extracted directory
+type dirMetadata struct {
+	path    string
+	perm    fs.FileMode
+	modTime time.Time
+}
+
+func newExtractor(root string, limits ExtractLimits, include func(name string) bool) *extractor {
+	return &extractor{
+		fs:      OSFileSystem{},
+		root:    filepath.Clean(root),
+		limits:  limits.withDefaults(),
+		include: include,
+		workers: DefaultExtractWorkers,
+		logger:  slog.Default(),
+	}
+}
+
+// extractFile extracts the gzipped tarball at tarballPath
+func (e *extractor) extractFile(ctx context.Context, tarballPath string) error {
+	archive, err := e.fs.Open(tarballPath)
+	if err != nil {
+		return fmt.Errorf("failed to open tarball: %w", err)
+	}
+	defer archive.Close()
+
+	gzipReader, err := gzip.NewReader(archive)
+	if err != nil {
+		return fmt.Errorf("failed to create gzip reader: %w", err)
+	}
+	defer gzipReader.Close()
+
+	return e.extract(ctx, gzipReader)
+}
+
+// extract extracts the uncompressed tar stream r. The stream is read on the
+// calling goroutine, regular files are written by a pool of workers.
+// Directories, links and files replacing pending writes are handled in
+// archive order, see barrier.
+func (e *extractor) extract(ctx context.Context, r io.Reader) error {
+	if e.workers > 1 {
+		e.pool = newWritePool(ctx, e.workers, func(job writeJob) error {
+			return e.writeFile(job.target, job.perm, job.modTime, bytes.NewReader(job.data))
+		})
+		// Never leave workers behind when returning early.
+		defer e.pool.close()
+	}
+
+	tarReader := tar.NewReader(r)
+	for {
+		if err := e.pool.err(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("extraction cancelled: %w", ctx.Err())
+		default:
+		}
+
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar header: %w", err)
+		}
+
+		e.entries++
+		if e.entries > e.limits.MaxEntries {
+			return fmt.Errorf("%w: more than %d entries", ErrUnsafeArchive, e.limits.MaxEntries)
+		}
+
+		if e.include != nil && !e.include(header.Name) {
+			continue
+		}
+
+		if err := e.extractEntry(header, tarReader); err != nil {
+			return err
+		}
+	}
+
+	if err := e.pool.close(); err != nil {
+		return err
+	}
+
+	return e.restoreDirs()
+}
+
+// barrier waits until all pending file writes are done. It is called before
+// an entry touches a path that a pending write may still use.
+func (e *extractor) barrier(paths ...string) error {
+	if !e.pool.pending(paths...) {
+		return nil
+	}
+	return e.pool.wait()
+}
+
+// extractEntry writes a single entry, content is positioned at the entry's data
+func (e *extractor) extractEntry(header *tar.Header, content io.Reader) error {
+	target, err := e.target(header.Name)
+	if err != nil {
+		return err
+	}
+
+	// Setuid, setgid and sticky bits are never applied.
+	perm := fs.FileMode(header.Mode).Perm()
+
+	switch header.Typeflag {
+	case tar.TypeDir:
+		// The owner needs write access until all children are written.
+		if err := e.mkdirAll(target, perm|0700); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", target, err)
+		}
+		e.dirs = append(e.dirs, dirMetadata{path: target, perm: perm, modTime: header.ModTime})
+
+	case tar.TypeReg:
+		if header.Size > e.limits.MaxFileSize {
+			return fmt.Errorf("%w: %s is larger than %d bytes", ErrUnsafeArchive, header.Name, e.limits.MaxFileSize)
+		}
+		if e.written+header.Size > e.limits.MaxTotalSize {
+			return fmt.Errorf("%w: extracted size exceeds %d bytes", ErrUnsafeArchive, e.limits.MaxTotalSize)
+		}
+
+		if err := e.barrier(target); err != nil {
+			return err
+		}
+		if err := e.prepare(target); err != nil {
+			return err
+		}
+		e.written += header.Size
+
+		if e.pool == nil || header.Size > parallelMaxFileSize {
+			return e.writeFile(target, perm, header.ModTime, io.LimitReader(content, header.Size))
+		}
+
+		data, err := io.ReadAll(io.LimitReader(content, header.Size))
+		if err != nil {
+			return fmt.Errorf("failed to read %s from archive: %w", header.Name, err)
+		}
+		e.pool.submit(writeJob{target: target, perm: perm, modTime: header.ModTime, data: data})
+
+	case tar.TypeSymlink:
+		if err := e.checkSymlink(header.Name, header.Linkname); err != nil {
+			return err
+		}
+		// A pending write must not follow the symlink created in its place.
+		if err := e.barrier(target); err != nil {
+			return err
+		}
+		if err := e.prepare(target); err != nil {
+			return err
+		}
+
+		if err := e.fs.Symlink(header.Linkname, target); err != nil {
+			return fmt.Errorf("failed to create symlink %s -> %s: %w", target, header.Linkname, err)
+		}
+
+	case tar.TypeLink:
+		// Hardlink names are relative to the archive root, not to the entry.
+		source, err := e.target(header.Linkname)
+		if err != nil {
+			return err
+		}
+		// The source has to be written completely before it can be linked.
+		if err := e.barrier(source, target); err != nil {
+			return err
+		}
+		if err := e.prepare(target); err != nil {
+			return err
+		}
+
+		info, err := e.fs.Lstat(source)
+		if err != nil {
+			return fmt.Errorf("failed to stat hardlink source %s: %w", source, err)
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("%w: hardlink %s to non-regular file %s", ErrUnsafeArchive, header.Name, header.Linkname)
+		}
+
+		if err := e.fs.Link(source, target); err != nil {
+			return fmt.Errorf("failed to create hardlink %s -> %s: %w", target, source, err)
+		}
+
+	default:
+		e.logger.Warn("Skipping unsupported file type", "type", string(header.Typeflag), "name", header.Name)
+	}
+
+	return nil
+}
+
+// writeFile writes content to a new file at target and restores its metadata
+func (e *extractor) writeFile(target string, perm fs.FileMode, modTime time.Time, content io.Reader) error {
+	file, err := e.fs.Create(target, perm)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", target, err)
+	}
+
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write file %s: %w", target, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close file %s: %w", target, err)
+	}
+
+	return e.restoreMetadata(target, perm, modTime)
+}
+
+// restoreDirs applies the archived mode and modification time to all
+// extracted directories, deepest first.
+func (e *extractor) restoreDirs() error {
+	for _, dir := range slices.Backward(e.dirs) {
+		if err := e.restoreMetadata(dir.path, dir.perm, dir.modTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreMetadata sets the exact mode, unaffected by the umask, and the
+// modification time of path. Symlinks are never passed, as both calls follow them.
+func (e *extractor) restoreMetadata(path string, perm fs.FileMode, modTime time.Time) error {
+	if err := e.fs.Chmod(path, perm); err != nil {
+		return fmt.Errorf("failed to set mode of %s: %w", path, err)
+	}
+	if err := e.fs.Chtimes(path, modTime, modTime); err != nil {
+		return fmt.Errorf("failed to set modification time of %s: %w", path, err)
+	}
+	return nil
+}
+
+// target resolves an archive name to a path below root. It rejects absolute
+// names, names escaping root and names leading through an existing symlink.
+func (e *extractor) target(name string) (string, error) {
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("%w: path traversal attempt: %s", ErrUnsafeArchive, name)
+	}
+
+	target := filepath.Join(e.root, name)
+	if err := e.checkNoSymlinks(filepath.Dir(target)); err != nil {
+		return "", err
+	}
+
+	return target, nil
+}
+
+// checkNoSymlinks ensures that no existing component of dir below root is a
+// symlink. Otherwise an archive could first plant a symlink and then write
+// through it, possibly outside of root.
+func (e *extractor) checkNoSymlinks(dir string) error {
+	rel, err := filepath.Rel(e.root, dir)
+	if err != nil {
+		return fmt.Errorf("%w: %s is outside of %s", ErrUnsafeArchive, dir, e.root)
+	}
+	if rel == "." {
+		return nil
+	}
+
+	current := e.root
+	for _, component := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, component)
+
+		info, err := e.fs.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", current, err)
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("%w: path leads through symlink %s", ErrUnsafeArchive, current)
+		}
+	}
+
+	return nil
+}
+
+// checkSymlink ensures that a symlink named name pointing to linkname stays within root
+func (e *extractor) checkSymlink(name, linkname string) error {
+	if linkname == "" || filepath.IsAbs(linkname) {
+		return fmt.Errorf("%w: symlink %s points to %q", ErrUnsafeArchive, name, linkname)
+	}
+
+	if ascendsAfterDescending(linkname) {
+		return fmt.Errorf("%w: symlink %s points to %q", ErrUnsafeArchive, name, linkname)
+	}
+
+	resolved := filepath.Join(filepath.Dir(name), linkname)
+	if !filepath.IsLocal(resolved) {
+		return fmt.Errorf("%w: symlink %s escapes the root via %q", ErrUnsafeArchive, name, linkname)
+	}
+
+	return nil
+}
+
+// ascendsAfterDescending reports whether linkname has a ".." after another
+// component. The link's parent has no symlinks (see target), so leading ".."
+// can be resolved lexically, but a later one could step back out of a
+// symlinked directory, e.g. "sub/.." where sub links to "..".
+func ascendsAfterDescending(linkname string) bool {
+	descending := false
+	for _, component := range strings.Split(linkname, "/") {
+		if component == ".." && descending {
+			return true
+		}
+		if component != ".." && component != "." && component != "" {
+			descending = true
+		}
+	}
+	return false
+}
+
+// prepare creates the parent of target and removes any existing non-directory
+// at target, so that it is replaced instead of written through.
+func (e *extractor) prepare(target string) error {
+	if err := e.mkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create parent directory for %s: %w", target, err)
+	}
+
+	info, err := e.fs.Lstat(target)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", target, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%w: %s replaces a directory", ErrUnsafeArchive, target)
+	}
+
+	if err := e.fs.Remove(target); err != nil {
+		return fmt.Errorf("failed to remove existing %s: %w", target, err)
+	}
+
+	return nil
+}
+
+// mkdirAll is MkdirAll of the filesystem that refuses to create directories through symlinks
+func (e *extractor) mkdirAll(dir string, perm fs.FileMode) error {
+	if err := e.checkNoSymlinks(dir); err != nil {
+		return err
+	}
+	return e.fs.MkdirAll(dir, perm)
+}
@@ -0,0 +1,130 @@
+package gotools
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// writeJob is a regular file read from the archive, waiting to be written
+type writeJob struct {
+	target  string
+	perm    fs.FileMode
+	modTime time.Time
+	data    []byte
+}
+
+// writePool writes files on a bounded number of goroutines. Only the
+// goroutine reading the archive submits jobs and waits for them; a nil pool
+// is valid and has nothing pending.
+type writePool struct {
+	ctx   context.Context
+	jobs  chan writeJob
+	write func(writeJob) error
+
+	// workers tracks the worker goroutines, inflight the submitted jobs
+	workers  sync.WaitGroup
+	inflight sync.WaitGroup
+	// targets are the paths of the jobs submitted since the last wait
+	targets map[string]bool
+
+	mu       sync.Mutex
+	firstErr error
+
+	closeOnce sync.Once
+}
+
+func newWritePool(ctx context.Context, workers int, write func(writeJob) error) *writePool {
+	p := &writePool{
+		ctx:   ctx,
+		write: write,
+		// A small buffer keeps the workers busy while bounding the memory held by queued files.
+		jobs:    make(chan writeJob, workers),
+		targets: make(map[string]bool),
+	}
+
+	p.workers.Add(workers)
+	for range workers {
+		go p.work()
+	}
+
+	return p
+}
+
+func (p *writePool) work() {
+	defer p.workers.Done()
+
+	for job := range p.jobs {
+		// Once cancelled or failed, drain the queue without writing. A skipped
+		// job fails the pool, so the extraction can't pass for complete.
+		if err := p.ctx.Err(); err != nil {
+			p.setErr(fmt.Errorf("extraction cancelled: %w", err))
+		} else if p.err() == nil {
+			if err := p.write(job); err != nil {
+				p.setErr(err)
+			}
+		}
+		p.inflight.Done()
+	}
+}
+
+// submit queues a job, it blocks while all workers are busy and the queue is full
+func (p *writePool) submit(job writeJob) {
+	p.targets[job.target] = true
+	p.inflight.Add(1)
+	p.jobs <- job
+}
+
+// pending reports whether any of paths may still be written by a submitted job
+func (p *writePool) pending(paths ...string) bool {
+	if p == nil {
+		return false
+	}
+	for _, path := range paths {
+		if p.targets[path] {
+			return true
+		}
+	}
+	return false
+}
+
+// wait blocks until all submitted jobs are done and returns the first error
+func (p *writePool) wait() error {
+	if p == nil {
+		return nil
+	}
+	p.inflight.Wait()
+	clear(p.targets)
+	return p.err()
+}
+
+// close stops the workers after all submitted jobs are done and returns the first error
+func (p *writePool) close() error {
+	if p == nil {
+		return nil
+	}
+	p.closeOnce.Do(func() {
+		close(p.jobs)
+		p.workers.Wait()
+	})
+	return p.err()
+}
+
+func (p *writePool) err() error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.firstErr
+}
+
+func (p *writePool) setErr(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.firstErr == nil {
+		p.firstErr = err
+	}
+}
@@ -0,0 +1,375 @@
+package gotools
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// hostileTarballs are archives that must not write outside the extraction root
+var hostileTarballs = map[string][]tarEntry{
+	"parent traversal": {
+		{Name: "../evil", Content: "pwned"},
+	},
+	"nested traversal": {
+		{Name: "go/../../evil", Content: "pwned"},
+	},
+	"absolute path": {
+		{Name: "/tmp/evil", Content: "pwned"},
+	},
+	"sibling with shared prefix": {
+		{Name: "../lib-evil/evil", Content: "pwned"},
+	},
+	"absolute symlink": {
+		{Name: "go/link", Type: tar.TypeSymlink, Linkname: "/etc"},
+	},
+	"escaping symlink": {
+		{Name: "go/link", Type: tar.TypeSymlink, Linkname: "../../.."},
+	},
+	"write through symlink": {
+		{Name: "go/link", Type: tar.TypeSymlink, Linkname: "."},
+		{Name: "go/link/evil", Content: "pwned"},
+	},
+	"symlink chain": {
+		{Name: "go/a", Type: tar.TypeSymlink, Linkname: "."},
+		{Name: "go/a/b", Type: tar.TypeSymlink, Linkname: "../.."},
+	},
+	"symlink stepping out of a symlinked directory": {
+		{Name: "go/b", Type: tar.TypeSymlink, Linkname: ".."},
+		{Name: "go/a", Type: tar.TypeSymlink, Linkname: "b/../.."},
+	},
+	"escaping hardlink": {
+		{Name: "go/passwd", Type: tar.TypeLink, Linkname: "../../etc/passwd"},
+	},
+}
+
+func TestExtractRejectsHostileArchives(t *testing.T) {
+	for name, entries := range hostileTarballs {
+		t.Run(name, func(t *testing.T) {
+			parent := t.TempDir()
+			root := filepath.Join(parent, "lib")
+			if err := os.Mkdir(root, 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			err := newExtractor(root, ExtractLimits{}, nil).extract(context.Background(), bytes.NewReader(buildTestTar(t, entries)))
+			if !errors.Is(err, ErrUnsafeArchive) {
+				t.Errorf("extract() error = %v, want %v", err, ErrUnsafeArchive)
+			}
+
+			assertNothingOutside(t, parent, root)
+		})
+	}
+}
+
+// TestCheckSymlink covers the lexical checks of symlink targets. A ".." after
+// a normal component is rejected even if it resolves within the root, as that
+// component may be a symlink to a parent itself.
+func TestCheckSymlink(t *testing.T) {
+	tests := []struct {
+		name, linkname string
+		wantErr        bool
+	}{
+		{name: "go/misc/link", linkname: "../src/fmt/print.go"},
+		{name: "go/bin/gofmt", linkname: "../pkg/tool/gofmt"},
+		{name: "go/a/b/link", linkname: "../../c"},
+		{name: "go/link", linkname: "./src"},
+		{name: "go/link", linkname: "src/fmt"},
+		{name: "go/link", linkname: "", wantErr: true},
+		{name: "go/link", linkname: "/etc", wantErr: true},
+		{name: "go/link", linkname: "../..", wantErr: true},
+		{name: "go/a/link", linkname: "src/../fmt", wantErr: true},
+		{name: "go/a", linkname: "b/../..", wantErr: true},
+		{name: "go/a/b/link", linkname: "./c/../..", wantErr: true},
+	}
+
+	e := newExtractor(t.TempDir(), ExtractLimits{}, nil)
+	for _, tt := range tests {
+		t.Run(tt.name+"->"+tt.linkname, func(t *testing.T) {
+			err := e.checkSymlink(tt.name, tt.linkname)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnsafeArchive) {
+					t.Errorf("checkSymlink() error = %v, want %v", err, ErrUnsafeArchive)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("checkSymlink() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestExtractLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  ExtractLimits
+		entries []tarEntry
+		wantErr bool
+	}{
+		{
+			name:   "within limits",
+			limits: ExtractLimits{MaxTotalSize: 10, MaxFileSize: 5, MaxEntries: 2},
+			entries: []tarEntry{
+				{Name: "a", Content: "12345"},
+				{Name: "b", Content: "12345"},
+			},
+		},
+		{
+			name:    "file too large",
+			limits:  ExtractLimits{MaxFileSize: 4},
+			entries: []tarEntry{{Name: "a", Content: "12345"}},
+			wantErr: true,
+		},
+		{
+			name:   "total too large",
+			limits: ExtractLimits{MaxTotalSize: 8},
+			entries: []tarEntry{
+				{Name: "a", Content: "12345"},
+				{Name: "b", Content: "12345"},
+			},
+			wantErr: true,
+		},
+		{
+			name:   "too many entries",
+			limits: ExtractLimits{MaxEntries: 2},
+			entries: []tarEntry{
+				{Name: "a/", Type: tar.TypeDir},
+				{Name: "a/b/", Type: tar.TypeDir},
+				{Name: "a/b/c/", Type: tar.TypeDir},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newExtractor(t.TempDir(), tt.limits, nil).extract(context.Background(), bytes.NewReader(buildTestTar(t, tt.entries)))
+			if tt.wantErr != errors.Is(err, ErrUnsafeArchive) {
+				t.Errorf("extract() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExtractIgnoresSetuid(t *testing.T) {
+	root := t.TempDir()
+	entries := []tarEntry{{Name: "go/bin/go", Mode: 04755 | 02000, Content: "#!/bin/sh\n"}}
+
+	if err := newExtractor(root, ExtractLimits{}, nil).extract(context.Background(), bytes.NewReader(buildTestTar(t, entries))); err != nil {
+		t.Fatalf("extract() error = %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(root, "go", "bin", "go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&(fs.ModeSetuid|fs.ModeSetgid) != 0 {
+		t.Errorf("extracted mode = %v, want no setuid/setgid bits", info.Mode())
+	}
+}
+
+func TestExtractHardlink(t *testing.T) {
+	root := t.TempDir()
+	entries := []tarEntry{
+		{Name: "go/bin/go", Content: "binary"},
+		{Name: "go/pkg/tool/go", Type: tar.TypeLink, Linkname: "go/bin/go"},
+	}
+
+	if err := newExtractor(root, ExtractLimits{}, nil).extract(context.Background(), bytes.NewReader(buildTestTar(t, entries))); err != nil {
+		t.Fatalf("extract() error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(root, "go", "pkg", "tool", "go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "binary" {
+		t.Errorf("hardlink content = %q, want %q", content, "binary")
+	}
+}
+
+func TestExtractPreservesMetadata(t *testing.T) {
+	modTime := time.Date(2025, 3, 4, 17, 19, 55, 0, time.UTC)
+	entries := []tarEntry{
+		{Name: "go/", Type: tar.TypeDir, Mode: 0755, ModTime: modTime},
+		{Name: "go/bin/", Type: tar.TypeDir, Mode: 0750, ModTime: modTime.Add(time.Hour)},
+		{Name: "go/bin/go", Mode: 0755, Content: "binary", ModTime: modTime.Add(2 * time.Hour)},
+		{Name: "go/lib/", Type: tar.TypeDir, Mode: 0555, ModTime: modTime.Add(3 * time.Hour)},
+		{Name: "go/lib/secret", Mode: 0600, Content: "secret", ModTime: modTime.Add(4 * time.Hour)},
+		{Name: "go/lib/world", Mode: 0666, Content: "world", ModTime: modTime.Add(5 * time.Hour)},
+	}
+
+	root := t.TempDir()
+	// Make the read-only directory removable again for t.TempDir's cleanup.
+	t.Cleanup(func() { os.Chmod(filepath.Join(root, "go", "lib"), 0755) })
+
+	data := buildTestTar(t, entries)
+	if err := newExtractor(root, ExtractLimits{}, nil).extract(context.Background(), bytes.NewReader(data)); err != nil {
+		t.Fatalf("extract() error = %v", err)
+	}
+
+	tarReader := tar.NewReader(bytes.NewReader(data))
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		info, err := os.Lstat(filepath.Join(root, header.Name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have, want := info.Mode().Perm(), fs.FileMode(header.Mode).Perm(); have != want {
+			t.Errorf("%s: mode = %v, want %v", header.Name, have, want)
+		}
+		if have, want := info.ModTime(), header.ModTime; !have.Equal(want) {
+			t.Errorf("%s: mtime = %v, want %v", header.Name, have, want)
+		}
+	}
+}
+
+func FuzzExtract(f *testing.F) {
+	f.Add(buildTestTar(f, testGoTarball))
+	for _, entries := range hostileTarballs {
+		f.Add(buildTestTar(f, entries))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		parent := t.TempDir()
+		root := filepath.Join(parent, "lib")
+		if err := os.Mkdir(root, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		limits := ExtractLimits{MaxTotalSize: 1 << 20, MaxFileSize: 1 << 20, MaxEntries: 1000}
+		// Errors are expected for most inputs, only escapes are failures.
+		_ = newExtractor(root, limits, nil).extract(context.Background(), bytes.NewReader(data))
+
+		assertNothingOutside(t, parent, root)
+	})
+}
+
+// assertNothingOutside fails if parent contains anything besides root
+func assertNothingOutside(t *testing.T, parent, root string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if filepath.Join(parent, entry.Name()) != root {
+			t.Errorf("extraction escaped the root: found %s", filepath.Join(parent, entry.Name()))
+		}
+	}
+}
+
+func TestExtractSerialAndParallelMatch(t *testing.T) {
+	entries := benchmarkTarball(200)
+	data := buildTestTar(t, entries)
+
+	for _, workers := range []int{1, DefaultExtractWorkers} {
+		root := t.TempDir()
+		e := newExtractor(root, ExtractLimits{}, nil)
+		e.workers = workers
+		if err := e.extract(context.Background(), bytes.NewReader(data)); err != nil {
+			t.Fatalf("extract() with %d workers error = %v", workers, err)
+		}
+
+		for _, entry := range entries {
+			if entry.Type != 0 {
+				continue
+			}
+			content, err := os.ReadFile(filepath.Join(root, entry.Name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != entry.Content {
+				t.Errorf("%d workers: %s content = %q, want %q", workers, entry.Name, content, entry.Content)
+			}
+		}
+	}
+}
+
+func TestWritePoolCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	var written []string
+	p := newWritePool(ctx, 1, func(job writeJob) error {
+		if len(written) == 0 {
+			close(started)
+			<-release
+		}
+		written = append(written, job.target)
+		return nil
+	})
+
+	p.submit(writeJob{target: "go/VERSION"})
+	<-started
+	cancel()
+	close(release)
+	p.submit(writeJob{target: "go/bin/go"})
+
+	if err := p.wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("wait() error = %v, want context.Canceled", err)
+	}
+	if err := p.close(); !errors.Is(err, context.Canceled) {
+		t.Errorf("close() error = %v, want context.Canceled", err)
+	}
+	if !slices.Equal(written, []string{"go/VERSION"}) {
+		t.Errorf("written = %q, want only the job started before the cancellation", written)
+	}
+}
+
+// benchmarkTarball returns a release-like layout of files spread over directories
+func benchmarkTarball(files int) []tarEntry {
+	content := strings.Repeat("package fmt\n", 350) // about the median file size of a Go release
+	entries := []tarEntry{{Name: "go/", Type: tar.TypeDir}}
+	for i := range files {
+		if i%50 == 0 {
+			entries = append(entries, tarEntry{Name: fmt.Sprintf("go/src/pkg%d/", i/50), Type: tar.TypeDir})
+		}
+		entries = append(entries, tarEntry{Name: fmt.Sprintf("go/src/pkg%d/file%d.go", i/50, i), Content: content})
+	}
+	return entries
+}
+
+func BenchmarkExtract(b *testing.B) {
+	data := buildTestTar(b, benchmarkTarball(2000))
+
+	for _, bm := range []struct {
+		name    string
+		workers int
+	}{
+		{name: "serial", workers: 1},
+		{name: "parallel", workers: DefaultExtractWorkers},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for range b.N {
+				e := newExtractor(b.TempDir(), ExtractLimits{}, nil)
+				e.workers = bm.workers
+				if err := e.extract(context.Background(), bytes.NewReader(data)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
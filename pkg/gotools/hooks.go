@@ -0,0 +1,164 @@
+package gotools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// HookPoint identifies a stage of the update pipeline at which hooks run
+type HookPoint string
+
+const (
+	// HookBeforeDownload runs before the release archive is downloaded
+	HookBeforeDownload HookPoint = "before-download"
+	// HookAfterVerify runs after the downloaded archive passed checksum verification
+	HookAfterVerify HookPoint = "after-verify"
+	// HookBeforeSwap runs right before the new installation replaces the existing one
+	HookBeforeSwap HookPoint = "before-swap"
+	// HookAfterInstall runs after the new installation has been verified
+	HookAfterInstall HookPoint = "after-install"
+	// HookOnFailure runs when any stage of the pipeline fails
+	HookOnFailure HookPoint = "on-failure"
+)
+
+// HookPoints lists all hook points in pipeline order
+var HookPoints = []HookPoint{
+	HookBeforeDownload,
+	HookAfterVerify,
+	HookBeforeSwap,
+	HookAfterInstall,
+	HookOnFailure,
+}
+
+// Environment variables passed to every hook
+const (
+	HookEnvPoint      = "UPDATEGO_HOOK"
+	HookEnvOldVersion = "UPDATEGO_OLD_VERSION"
+	HookEnvNewVersion = "UPDATEGO_NEW_VERSION"
+	HookEnvError      = "UPDATEGO_ERROR"
+)
+
+// Hook is a shell command run at a hook point
+type Hook struct {
+	// Command is run with /bin/sh -c
+	Command string
+	// IgnoreFailure keeps the pipeline going if the command fails
+	IgnoreFailure bool
+}
+
+// ParseHook parses a hook definition of the form "point=command".
+// Like in make, a command prefixed with "-" may fail without aborting the pipeline.
+func ParseHook(s string) (HookPoint, Hook, error) {
+	name, command, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", Hook{}, fmt.Errorf("invalid hook %q: expected point=command", s)
+	}
+
+	point := HookPoint(strings.TrimSpace(name))
+	if !point.valid() {
+		return "", Hook{}, fmt.Errorf("unknown hook point %q", name)
+	}
+
+	hook := Hook{Command: strings.TrimSpace(command)}
+	if rest, ok := strings.CutPrefix(hook.Command, "-"); ok {
+		hook.Command = strings.TrimSpace(rest)
+		hook.IgnoreFailure = true
+	}
+	if hook.Command == "" {
+		return "", Hook{}, fmt.Errorf("empty command for hook %q", point)
+	}
+
+	return point, hook, nil
+}
+
+func (p HookPoint) valid() bool {
+	for _, known := range HookPoints {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
+// HookEnv describes the update the hooks are run for
+type HookEnv struct {
+	OldVersion string
+	NewVersion string
+	// Err is set for the on-failure hook
+	Err error
+}
+
+// Hooks holds the commands run at each point of the update pipeline
+type Hooks struct {
+	commands map[HookPoint][]Hook
+	// Stdout and Stderr receive the output of the hook commands
+	Stdout io.Writer
+	Stderr io.Writer
+	// Logger receives a message for every hook run
+	Logger *slog.Logger
+}
+
+// NewHooks creates an empty set of hooks writing to the process output
+func NewHooks() *Hooks {
+	return &Hooks{
+		commands: make(map[HookPoint][]Hook),
+		Stdout:   os.Stdout,
+		Stderr:   os.Stderr,
+		Logger:   slog.Default(),
+	}
+}
+
+// Add registers a hook at the given point
+func (h *Hooks) Add(point HookPoint, hook Hook) {
+	h.commands[point] = append(h.commands[point], hook)
+}
+
+// Run runs all hooks registered at the given point in order.
+// It stops at the first failing hook unless that hook ignores failures.
+func (h *Hooks) Run(ctx context.Context, point HookPoint, env HookEnv) error {
+	if h == nil {
+		return nil
+	}
+
+	for _, hook := range h.commands[point] {
+		cmd := exec.CommandContext(ctx, "/bin/sh", "-c", hook.Command)
+		cmd.Stdout = h.Stdout
+		cmd.Stderr = h.Stderr
+		cmd.Env = append(os.Environ(),
+			HookEnvPoint+"="+string(point),
+			HookEnvOldVersion+"="+env.OldVersion,
+			HookEnvNewVersion+"="+env.NewVersion,
+		)
+		if env.Err != nil {
+			cmd.Env = append(cmd.Env, HookEnvError+"="+env.Err.Error())
+		}
+
+		loggerOrDefault(h.Logger).Debug("Running hook", "point", point, "command", hook.Command)
+		if err := cmd.Run(); err != nil {
+			if hook.IgnoreFailure {
+				loggerOrDefault(h.Logger).Warn("Hook failed, continuing", "point", point, "command", hook.Command, "error", err)
+				continue
+			}
+			return fmt.Errorf("%s hook %q failed: %w", point, hook.Command, err)
+		}
+	}
+
+	return nil
+}
+
+// RunOnFailure runs the on-failure hooks for err and returns err joined with
+// any hook error. It is meant to wrap the error returned from a failed stage.
+func (h *Hooks) RunOnFailure(ctx context.Context, env HookEnv, err error) error {
+	env.Err = err
+	// The pipeline context may already be cancelled, hooks still deserve to run.
+	if hookErr := h.Run(context.WithoutCancel(ctx), HookOnFailure, env); hookErr != nil {
+		return errors.Join(err, hookErr)
+	}
+	return err
+}
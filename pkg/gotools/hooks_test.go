@@ -0,0 +1,133 @@
+package gotools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseHook(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		point   HookPoint
+		hook    Hook
+		wantErr bool
+	}{
+		{
+			name:  "simple command",
+			input: "after-install=go clean -cache",
+			point: HookAfterInstall,
+			hook:  Hook{Command: "go clean -cache"},
+		},
+		{
+			name:  "command may fail",
+			input: "after-install=-notify-send done",
+			point: HookAfterInstall,
+			hook:  Hook{Command: "notify-send done", IgnoreFailure: true},
+		},
+		{
+			name:    "unknown point",
+			input:   "after-lunch=true",
+			wantErr: true,
+		},
+		{
+			name:    "missing command",
+			input:   "before-swap",
+			wantErr: true,
+		},
+		{
+			name:    "empty command",
+			input:   "before-swap= ",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			point, hook, err := ParseHook(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHook() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if point != tt.point || hook != tt.hook {
+				t.Errorf("ParseHook() = %v, %+v, want %v, %+v", point, hook, tt.point, tt.hook)
+			}
+		})
+	}
+}
+
+func TestHooksRun(t *testing.T) {
+	env := HookEnv{OldVersion: "1.23.1", NewVersion: "1.24.0"}
+
+	t.Run("passes versions via environment", func(t *testing.T) {
+		hooks := NewHooks()
+		out := &strings.Builder{}
+		hooks.Stdout = out
+		hooks.Add(HookBeforeSwap, Hook{Command: `echo "$UPDATEGO_HOOK $UPDATEGO_OLD_VERSION $UPDATEGO_NEW_VERSION"`})
+
+		if err := hooks.Run(context.Background(), HookBeforeSwap, env); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if have, want := strings.TrimSpace(out.String()), "before-swap 1.23.1 1.24.0"; have != want {
+			t.Errorf("hook output = %q, want %q", have, want)
+		}
+	})
+
+	t.Run("failure aborts", func(t *testing.T) {
+		hooks := NewHooks()
+		out := &strings.Builder{}
+		hooks.Stdout = out
+		hooks.Add(HookBeforeDownload, Hook{Command: "exit 3"})
+		hooks.Add(HookBeforeDownload, Hook{Command: "echo unreachable"})
+
+		if err := hooks.Run(context.Background(), HookBeforeDownload, env); err == nil {
+			t.Fatal("Run() expected error")
+		}
+		if out.Len() != 0 {
+			t.Errorf("hooks after a failing hook should not run, got output %q", out.String())
+		}
+	})
+
+	t.Run("ignored failure continues", func(t *testing.T) {
+		hooks := NewHooks()
+		out := &strings.Builder{}
+		hooks.Stdout = out
+		hooks.Add(HookAfterInstall, Hook{Command: "exit 1", IgnoreFailure: true})
+		hooks.Add(HookAfterInstall, Hook{Command: "echo reached"})
+
+		if err := hooks.Run(context.Background(), HookAfterInstall, env); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if strings.TrimSpace(out.String()) != "reached" {
+			t.Errorf("hook output = %q, want %q", out.String(), "reached")
+		}
+	})
+
+	t.Run("nil hooks are a no-op", func(t *testing.T) {
+		var hooks *Hooks
+		if err := hooks.Run(context.Background(), HookAfterInstall, env); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	})
+}
+
+func TestHooksRunOnFailure(t *testing.T) {
+	hooks := NewHooks()
+	out := &strings.Builder{}
+	hooks.Stdout = out
+	hooks.Add(HookOnFailure, Hook{Command: `echo "$UPDATEGO_ERROR"`})
+
+	stageErr := errors.New("download failed")
+	err := hooks.RunOnFailure(context.Background(), HookEnv{}, stageErr)
+	if !errors.Is(err, stageErr) {
+		t.Errorf("RunOnFailure() = %v, want %v", err, stageErr)
+	}
+	if strings.TrimSpace(out.String()) != "download failed" {
+		t.Errorf("hook output = %q, want %q", out.String(), "download failed")
+	}
+}
@@ -2,17 +2,14 @@
 package gotools
 
 import (
-	"archive/tar"
-	"compress/gzip"
 	"context"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"os"
-	"os/exec"
 	"os/user"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // Installer handles the installation process
@@ -21,89 +18,175 @@ type Installer struct {
 	InstallDir string
 	// BinDir specifies where to symlink the go binary
 	BinDir string
+	// BeforeSwap is called once the new installation is extracted, right
+	// before it replaces the existing one. Returning an error aborts the
+	// installation, leaving the old one in place.
+	BeforeSwap func(ctx context.Context) error
+	// Limits bounds the extraction, zero fields fall back to DefaultExtractLimits
+	Limits ExtractLimits
+	// ExtractWorkers is the number of goroutines writing files, zero uses DefaultExtractWorkers
+	ExtractWorkers int
+	// Logger receives progress messages
+	Logger *slog.Logger
+	// FS is the filesystem the tarball is read from and Go is installed to,
+	// nil uses the host's. The installation lock always lives on the host.
+	FS FileSystem
+	// Runner runs the installed go binary, nil runs it on the host
+	Runner CommandRunner
+	// Clock times the installation, nil uses the system clock
+	Clock Clock
 }
 
 // NewInstaller creates a new installer with non-sudo defaults
 func NewInstaller() (*Installer, error) {
-	installDir := ""
-	binDir := ""
+	homeDir, err := homeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine installation directory: %w", err)
+	}
 
+	return newInstallerForHome(homeDir), nil
+}
+
+// homeDir returns the home directory of the current user, falling back to $HOME
+func homeDir() (string, error) {
 	usr, err := user.Current()
-	if err != nil {
-		// Fallback to $HOME
-		homeDir := os.Getenv("HOME")
-		if homeDir != "" {
-			installDir = filepath.Join(homeDir, ".local", "lib")
-			binDir = filepath.Join(homeDir, ".local", "bin")
-		} else {
-			return nil, fmt.Errorf("failed to determine installation directory: %w", err)
-		}
-	} else {
-		installDir = filepath.Join(usr.HomeDir, ".local", "lib")
-		binDir = filepath.Join(usr.HomeDir, ".local", "bin")
+	if err == nil {
+		return usr.HomeDir, nil
+	}
+	if homeDir := os.Getenv("HOME"); homeDir != "" {
+		return homeDir, nil
 	}
+	return "", err
+}
 
+// newInstallerForHome creates an installer for ~/.local below homeDir
+func newInstallerForHome(homeDir string) *Installer {
 	return &Installer{
-		InstallDir: installDir,
-		BinDir:     binDir,
-	}, nil
+		InstallDir: filepath.Join(homeDir, ".local", "lib"),
+		BinDir:     filepath.Join(homeDir, ".local", "bin"),
+		Limits:     DefaultExtractLimits,
+		Logger:     slog.Default(),
+		FS:         OSFileSystem{},
+		Runner:     ExecRunner{},
+		Clock:      SystemClock{},
+	}
+}
+
+// filesystem returns FS, defaulting to the host's filesystem
+func (i *Installer) filesystem() FileSystem {
+	if i.FS == nil {
+		return OSFileSystem{}
+	}
+	return i.FS
+}
+
+// commandRunner returns Runner, defaulting to running commands on the host
+func (i *Installer) commandRunner() CommandRunner {
+	if i.Runner == nil {
+		return ExecRunner{}
+	}
+	return i.Runner
 }
 
-// Install installs Go from the given tarball
+// now returns the time of Clock, defaulting to the system clock
+func (i *Installer) now() time.Time {
+	if i.Clock == nil {
+		return time.Now()
+	}
+	return i.Clock.Now()
+}
+
+// Install installs Go from the given tarball. The tarball is extracted next
+// to the existing installation, which is only replaced once the extraction
+// succeeded, so failing or cancelling before that leaves it untouched.
 func (i *Installer) Install(ctx context.Context, tarballPath string) error {
+	logger := loggerOrDefault(i.Logger)
+	logger.Info("Installing Go", "installDir", i.InstallDir, "binDir", i.BinDir)
+	start := i.now()
+	fsys := i.filesystem()
+
 	if err := i.ensureDirectories(); err != nil {
 		return fmt.Errorf("failed to create installation directories: %w", err)
 	}
 
-	if err := i.removeExisting(); err != nil {
-		return fmt.Errorf("failed to remove existing installation: %w", err)
+	// A staging directory left behind by a killed run is replaced.
+	staging := filepath.Join(i.InstallDir, ".go.new")
+	if err := fsys.RemoveAll(staging); err != nil {
+		return fmt.Errorf("failed to remove previous staging directory: %w", err)
 	}
+	defer fsys.RemoveAll(staging)
 
-	if err := i.extractTarball(ctx, tarballPath); err != nil {
+	logger.Debug("Extracting archive", "path", tarballPath, "staging", staging)
+	if err := i.newExtractor(staging, nil).extractFile(ctx, tarballPath); err != nil {
 		return fmt.Errorf("extraction failed: %w", err)
 	}
 
+	if i.BeforeSwap != nil {
+		if err := i.BeforeSwap(ctx); err != nil {
+			return fmt.Errorf("aborted before replacing existing installation: %w", err)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("installation cancelled: %w", err)
+	}
+
+	if err := i.swap(filepath.Join(staging, "go")); err != nil {
+		return err
+	}
+
 	if err := i.createSymlinks(); err != nil {
 		return fmt.Errorf("failed to create symlinks: %w", err)
 	}
 
+	logger.Debug("Installed Go", "duration", i.now().Sub(start))
 	return nil
 }
 
 // ensureDirectories creates the necessary directories for installation
 func (i *Installer) ensureDirectories() error {
-	if err := os.MkdirAll(i.InstallDir, 0755); err != nil {
+	fsys := i.filesystem()
+	if err := fsys.MkdirAll(i.InstallDir, 0755); err != nil {
 		return fmt.Errorf("failed to create install directory %s: %w", i.InstallDir, err)
 	}
 
-	if err := os.MkdirAll(i.BinDir, 0755); err != nil {
+	if err := fsys.MkdirAll(i.BinDir, 0755); err != nil {
 		return fmt.Errorf("failed to create bin directory %s: %w", i.BinDir, err)
 	}
 
 	return nil
 }
 
-// removeExisting removes any existing Go installation
-func (i *Installer) removeExisting() error {
+// swap replaces the installation with the tree at goroot. The old tree is
+// moved aside first and restored if the new one can't be moved into place.
+func (i *Installer) swap(goroot string) error {
+	fsys := i.filesystem()
 	goDir := filepath.Join(i.InstallDir, "go")
-	if _, err := os.Stat(goDir); os.IsNotExist(err) {
-		return nil
+	oldDir := filepath.Join(i.InstallDir, ".go.old")
+
+	if err := fsys.RemoveAll(oldDir); err != nil {
+		return fmt.Errorf("failed to remove previous installation: %w", err)
 	}
-	if err := os.RemoveAll(goDir); err != nil {
-		return fmt.Errorf("failed to remove existing Go installation: %w", err)
+
+	_, err := fsys.Lstat(goDir)
+	hasOld := err == nil
+	if hasOld {
+		if err := fsys.Rename(goDir, oldDir); err != nil {
+			return fmt.Errorf("failed to move existing installation aside: %w", err)
+		}
 	}
 
-	goLink := filepath.Join(i.BinDir, "go")
-	if _, err := os.Lstat(goLink); err == nil {
-		if err := os.Remove(goLink); err != nil {
-			return fmt.Errorf("failed to remove existing Go symlink: %w", err)
+	if err := fsys.Rename(goroot, goDir); err != nil {
+		if hasOld {
+			if restoreErr := fsys.Rename(oldDir, goDir); restoreErr != nil {
+				return fmt.Errorf("failed to move new installation into place: %w, and to restore the old one: %v", err, restoreErr)
+			}
 		}
+		return fmt.Errorf("failed to move new installation into place: %w", err)
 	}
 
-	goFmtLink := filepath.Join(i.BinDir, "gofmt")
-	if _, err := os.Lstat(goFmtLink); err == nil {
-		if err := os.Remove(goFmtLink); err != nil {
-			return fmt.Errorf("failed to remove existing gofmt symlink: %w", err)
+	if hasOld {
+		if err := fsys.RemoveAll(oldDir); err != nil {
+			loggerOrDefault(i.Logger).Warn("Failed to remove old installation", "path", oldDir, "error", err)
 		}
 	}
 
@@ -112,96 +195,47 @@ func (i *Installer) removeExisting() error {
 
 // extractTarball extracts the Go tarball to the installation directory
 func (i *Installer) extractTarball(ctx context.Context, tarballPath string) error {
-	archive, err := os.Open(tarballPath)
-	if err != nil {
-		return fmt.Errorf("failed to open tarball: %w", err)
-	}
-	defer archive.Close()
-
-	gzipReader, err := gzip.NewReader(archive)
-	if err != nil {
-		return fmt.Errorf("failed to create gzip reader: %w", err)
-	}
-	defer gzipReader.Close()
-
-	tarReader := tar.NewReader(gzipReader)
-	for {
-		select {
-		case <-ctx.Done():
-			return fmt.Errorf("extraction cancelled: %w", ctx.Err())
-		default:
-		}
-
-		header, err := tarReader.Next()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return fmt.Errorf("failed to read tar header: %w", err)
-		}
-
-		// Construct target path - header.Name is name of the file entry.
-		target := filepath.Join(i.InstallDir, header.Name)
-
-		// SECURITY: Verify the target path is still within the installation directory
-		// This is critical to prevent path traversal attacks from malicious tarballs
-		// that might contain entries with "../" to try to write files outside the intended directory
-		if !strings.HasPrefix(target, i.InstallDir) {
-			return fmt.Errorf("invalid tar entry (path traversal attempt): %s", header.Name)
-		}
-
-		// Handle different file types
-		switch header.Typeflag {
-		case tar.TypeDir:
-			if err := os.MkdirAll(target, os.FileMode(header.Mode)); err != nil {
-				return fmt.Errorf("failed to create directory %s: %w", target, err)
-			}
-
-		case tar.TypeReg: // indicates a regular file
-			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
-				return fmt.Errorf("failed to create parent directory for %s: %w", target, err)
-			}
-
-			// Give me a fresh, empty file that I can write to, creating it if needed or clearing it if it already exists.
-			file, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(header.Mode))
-			if err != nil {
-				return fmt.Errorf("failed to create file %s: %w", target, err)
-			}
+	return i.extractEntries(ctx, tarballPath, nil)
+}
 
-			if _, err := io.Copy(file, tarReader); err != nil {
-				file.Close()
-				return fmt.Errorf("failed to write file %s: %w", target, err)
-			}
-			file.Close()
+// extractEntries extracts the entries of the Go tarball for which include
+// returns true. A nil include extracts all entries.
+func (i *Installer) extractEntries(ctx context.Context, tarballPath string, include func(name string) bool) error {
+	return i.newExtractor(i.InstallDir, include).extractFile(ctx, tarballPath)
+}
 
-		case tar.TypeSymlink:
-			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
-				return fmt.Errorf("failed to create parent directory for symlink %s: %w", target, err)
-			}
+// newExtractor returns an extractor below root with the installer's settings
+func (i *Installer) newExtractor(root string, include func(name string) bool) *extractor {
+	e := newExtractor(root, i.Limits, include)
+	e.fs = i.filesystem()
+	e.logger = loggerOrDefault(i.Logger)
+	if i.ExtractWorkers > 0 {
+		e.workers = i.ExtractWorkers
+	}
+	return e
+}
 
-			if err := os.Symlink(header.Linkname, target); err != nil {
-				return fmt.Errorf("failed to create symlink %s -> %s: %w", target, header.Linkname, err)
+// createSymlinks creates symlinks to Go binaries, replacing existing ones
+func (i *Installer) createSymlinks() error {
+	fsys := i.filesystem()
+	for _, name := range []string{"go", "gofmt"} {
+		link := filepath.Join(i.BinDir, name)
+		if _, err := fsys.Lstat(link); err == nil {
+			if err := fsys.Remove(link); err != nil {
+				return fmt.Errorf("failed to remove existing %s symlink: %w", name, err)
 			}
-
-		default:
-			log.Printf("Skipping unsupported file type %c: %s", header.Typeflag, header.Name)
 		}
 	}
 
-	return nil
-}
-
-// createSymlinks creates symlinks to Go binaries
-func (i *Installer) createSymlinks() error {
 	goSrc := filepath.Join(i.InstallDir, "go", "bin", "go")
 	goDst := filepath.Join(i.BinDir, "go")
-	if err := os.Symlink(goSrc, goDst); err != nil {
+	if err := fsys.Symlink(goSrc, goDst); err != nil {
 		return fmt.Errorf("failed to create symlink for go: %w", err)
 	}
 
 	goFmtSrc := filepath.Join(i.InstallDir, "go", "bin", "gofmt")
 	goFmtDst := filepath.Join(i.BinDir, "gofmt")
-	if err := os.Symlink(goFmtSrc, goFmtDst); err != nil {
+	if err := fsys.Symlink(goFmtSrc, goFmtDst); err != nil {
 		return fmt.Errorf("failed to create symlink for gofmt: %w", err)
 	}
 
@@ -212,67 +246,16 @@ func (i *Installer) createSymlinks() error {
 func (i *Installer) Verify(ctx context.Context) error {
 	goPath := filepath.Join(i.BinDir, "go")
 
-	if _, err := os.Stat(goPath); os.IsNotExist(err) {
+	if _, err := i.filesystem().Stat(goPath); os.IsNotExist(err) {
 		return fmt.Errorf("Go binary not found at %s", goPath)
 	}
 
 	// Test the Go installation by running 'go version'
-	output := &strings.Builder{}
-	err := i.runGoCommand(ctx, goPath, []string{"version"}, output)
-	if err != nil {
-		return fmt.Errorf("Go installation verification failed: %s: %w", output.String(), err)
-	}
-
-	return nil
-}
-
-// runGoCommand runs a Go command and captures its output
-func (i *Installer) runGoCommand(ctx context.Context, goPath string, args []string, output *strings.Builder) error {
-	r, w, err := os.Pipe()
+	output, err := i.commandRunner().Run(ctx, "", nil, goPath, "version")
 	if err != nil {
-		return fmt.Errorf("failed to create pipe: %w", err)
-	}
-	defer r.Close()
-
-	cmd := &exec.Cmd{
-		Path:   goPath,
-		Args:   append([]string{goPath}, args...),
-		Stdout: w,
-		Stderr: w,
-	}
-
-	if err := cmd.Start(); err != nil {
-		w.Close()
-		return fmt.Errorf("failed to start command: %w", err)
-	}
-
-	// Close write end of pipe immediately after starting the command
-	// This is crucial - it signals to the read end (r) that no more data will be written
-	// Using defer w.Close() would cause io.ReadAll(r) to block indefinitely
-	// since it would wait for EOF which only happens when all writers are closed
-	w.Close()
-
-	outputBytes, err := io.ReadAll(r)
-	if err != nil {
-		return fmt.Errorf("failed to read command output: %w", err)
-	}
-
-	waitCh := make(chan error, 1)
-	go func() {
-		waitCh <- cmd.Wait()
-	}()
-
-	select {
-	case err := <-waitCh:
-		if err != nil {
-			return fmt.Errorf("command failed: %w", err)
-		}
-	case <-ctx.Done():
-		cmd.Process.Kill()
-		return ctx.Err()
+		return fmt.Errorf("Go installation verification failed: %s: %w", strings.TrimSpace(string(output)), err)
 	}
 
-	output.Write(outputBytes)
 	return nil
 }
 
@@ -0,0 +1,427 @@
+package gotools
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tarEntry describes a single entry of a test tarball
+type tarEntry struct {
+	Name     string
+	Type     byte
+	Mode     int64
+	Content  string
+	Linkname string
+	ModTime  time.Time
+}
+
+// writeTestTarball writes a gzipped tarball with the given entries to a
+// temporary directory and returns its path.
+func writeTestTarball(t testing.TB, entries []tarEntry) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "go.tar.gz")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	gzipWriter := gzip.NewWriter(file)
+	if _, err := gzipWriter.Write(buildTestTar(t, entries)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// buildTestTar returns an uncompressed tar stream with the given entries
+func buildTestTar(t testing.TB, entries []tarEntry) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	tarWriter := tar.NewWriter(buf)
+
+	for _, entry := range entries {
+		header := &tar.Header{
+			Name:     entry.Name,
+			Typeflag: entry.Type,
+			Mode:     entry.Mode,
+			Linkname: entry.Linkname,
+			ModTime:  entry.ModTime,
+		}
+		if header.Typeflag == 0 {
+			header.Typeflag = tar.TypeReg
+		}
+		if header.Mode == 0 {
+			header.Mode = 0644
+			if header.Typeflag == tar.TypeDir {
+				header.Mode = 0755
+			}
+		}
+		if header.Typeflag == tar.TypeReg {
+			header.Size = int64(len(entry.Content))
+		}
+
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := tarWriter.Write([]byte(entry.Content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// testGoTarball is a minimal release archive layout
+var testGoTarball = []tarEntry{
+	{Name: "go/", Type: tar.TypeDir},
+	{Name: "go/VERSION", Content: "go1.24.1\ntime 2025-03-04T17:19:55Z\n"},
+	{Name: "go/bin/", Type: tar.TypeDir},
+	{Name: "go/bin/go", Mode: 0755, Content: "#!/bin/sh\necho go version go1.24.1 linux/amd64\n"},
+	{Name: "go/src/fmt/print.go", Content: "package fmt\n"},
+	{Name: "go/misc/link", Type: tar.TypeSymlink, Linkname: "../src/fmt/print.go"},
+}
+
+func TestExtractTarball(t *testing.T) {
+	installer := &Installer{InstallDir: t.TempDir()}
+	tarball := writeTestTarball(t, testGoTarball)
+
+	if err := installer.extractTarball(context.Background(), tarball); err != nil {
+		t.Fatalf("extractTarball() error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(installer.InstallDir, "go", "src", "fmt", "print.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "package fmt\n" {
+		t.Errorf("extracted content = %q, want %q", content, "package fmt\n")
+	}
+
+	target, err := os.Readlink(filepath.Join(installer.InstallDir, "go", "misc", "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != "../src/fmt/print.go" {
+		t.Errorf("symlink target = %q, want %q", target, "../src/fmt/print.go")
+	}
+}
+
+// gzipTestTar returns a gzipped tarball with the given entries
+func gzipTestTar(t testing.TB, entries []tarEntry) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(buf)
+	if _, err := gzipWriter.Write(buildTestTar(t, entries)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// newMemInstaller returns an installer for /home/gopher on an in-memory
+// filesystem holding the test tarball at /tmp/go.tar.gz and an old installation
+func newMemInstaller(t *testing.T) (*Installer, *memFS, *fakeGo) {
+	t.Helper()
+
+	mem := newMemFS()
+	mem.writeFile(t, "/tmp/go.tar.gz", gzipTestTar(t, testGoTarball), 0644)
+	mem.writeFile(t, "/home/gopher/.local/lib/go/VERSION", []byte("go1.23.0\n"), 0644)
+	mem.writeFile(t, "/home/gopher/.local/lib/go/bin/go", []byte("old"), 0755)
+	mem.writeFile(t, "/home/gopher/.local/lib/go/obsolete.go", []byte("old"), 0644)
+	if err := mem.MkdirAll("/home/gopher/.local/bin", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := mem.Symlink("/home/gopher/.local/lib/go/bin/go", "/home/gopher/.local/bin/go"); err != nil {
+		t.Fatal(err)
+	}
+
+	goBinary := &fakeGo{fs: mem}
+	installer := newInstallerForHome("/home/gopher")
+	installer.FS = mem
+	installer.Runner = goBinary
+	installer.Clock = &fakeClock{step: time.Second}
+	installer.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return installer, mem, goBinary
+}
+
+func TestNewInstallerForHome(t *testing.T) {
+	installer := newInstallerForHome("/home/gopher")
+
+	if installer.InstallDir != "/home/gopher/.local/lib" || installer.BinDir != "/home/gopher/.local/bin" {
+		t.Errorf("newInstallerForHome() dirs = %q, %q", installer.InstallDir, installer.BinDir)
+	}
+	if _, ok := installer.FS.(OSFileSystem); !ok {
+		t.Errorf("newInstallerForHome() FS = %T, want OSFileSystem", installer.FS)
+	}
+}
+
+func TestInstall(t *testing.T) {
+	for _, workers := range []int{1, DefaultExtractWorkers} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			installer, mem, goBinary := newMemInstaller(t)
+			installer.ExtractWorkers = workers
+			logs := &strings.Builder{}
+			installer.Logger = slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			ctx := context.Background()
+
+			if err := installer.Install(ctx, "/tmp/go.tar.gz"); err != nil {
+				t.Fatalf("Install() error = %v", err)
+			}
+			if err := installer.Verify(ctx); err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+
+			if !strings.Contains(logs.String(), "duration=1s") {
+				t.Errorf("installation duration not logged with the fake clock:\n%s", logs)
+			}
+			if want := []string{"/home/gopher/.local/bin/go version"}; !slices.Equal(goBinary.calls, want) {
+				t.Errorf("commands = %q, want %q", goBinary.calls, want)
+			}
+			if _, err := mem.Lstat("/home/gopher/.local/lib/go/obsolete.go"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("old installation was not removed: %v", err)
+			}
+			for _, name := range []string{"go", "gofmt"} {
+				target, err := mem.Readlink("/home/gopher/.local/bin/" + name)
+				if want := "/home/gopher/.local/lib/go/bin/" + name; err != nil || target != want {
+					t.Errorf("%s symlink = %q, %v, want %q", name, target, err, want)
+				}
+			}
+			if content := mem.readFile(t, "/home/gopher/.local/lib/go/src/fmt/print.go"); content != "package fmt\n" {
+				t.Errorf("extracted content = %q", content)
+			}
+
+			version, err := installer.InstalledVersion()
+			if err != nil || version != "1.24.1" {
+				t.Errorf("InstalledVersion() = %q, %v", version, err)
+			}
+			report, err := installer.VerifyIntegrity(ctx, "/tmp/go.tar.gz")
+			if err != nil || !report.Clean() {
+				t.Errorf("VerifyIntegrity() = %+v, %v", report, err)
+			}
+		})
+	}
+}
+
+func TestInstallFailures(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(installer *Installer, mem *memFS, goBinary *fakeGo)
+		// wantErr is a substring of the error of Install, or of Verify if Install succeeds
+		wantErr string
+		// keepsOld is set if the old installation has to survive
+		keepsOld bool
+	}{
+		{
+			name: "install directory not creatable",
+			setup: func(_ *Installer, mem *memFS, _ *fakeGo) {
+				mem.failOn("mkdir", "/home/gopher/.local/lib")
+			},
+			wantErr:  "failed to create installation directories",
+			keepsOld: true,
+		},
+		{
+			name: "aborted before swap",
+			setup: func(installer *Installer, _ *memFS, _ *fakeGo) {
+				installer.BeforeSwap = func(context.Context) error { return errInjected }
+			},
+			wantErr:  "aborted before replacing existing installation",
+			keepsOld: true,
+		},
+		{
+			name: "old installation not movable",
+			setup: func(_ *Installer, mem *memFS, _ *fakeGo) {
+				mem.failOn("rename", "/home/gopher/.local/lib/.go.old")
+			},
+			wantErr:  "failed to move existing installation aside",
+			keepsOld: true,
+		},
+		{
+			name: "tarball missing",
+			setup: func(_ *Installer, mem *memFS, _ *fakeGo) {
+				mem.Remove("/tmp/go.tar.gz")
+			},
+			wantErr:  "failed to open tarball",
+			keepsOld: true,
+		},
+		{
+			name: "corrupt tarball",
+			setup: func(_ *Installer, mem *memFS, _ *fakeGo) {
+				mem.writeFile(t, "/tmp/go.tar.gz", []byte("not gzip"), 0644)
+			},
+			wantErr:  "failed to create gzip reader",
+			keepsOld: true,
+		},
+		{
+			name: "file not writable",
+			setup: func(_ *Installer, mem *memFS, _ *fakeGo) {
+				mem.failOn("create", "/home/gopher/.local/lib/.go.new/go/src/fmt/print.go")
+			},
+			wantErr:  "failed to create file",
+			keepsOld: true,
+		},
+		{
+			name: "metadata not restorable",
+			setup: func(_ *Installer, mem *memFS, _ *fakeGo) {
+				mem.failOn("chmod", "/home/gopher/.local/lib/.go.new/go/bin")
+			},
+			wantErr:  "failed to set mode",
+			keepsOld: true,
+		},
+		{
+			name: "symlink not creatable",
+			setup: func(_ *Installer, mem *memFS, _ *fakeGo) {
+				mem.failOn("symlink", "/home/gopher/.local/bin/gofmt")
+			},
+			wantErr: "failed to create symlink for gofmt",
+		},
+		{
+			name: "go binary broken",
+			setup: func(_ *Installer, _ *memFS, goBinary *fakeGo) {
+				goBinary.err = errors.New("exit status 2")
+			},
+			wantErr: "verification failed: go: broken: exit status 2",
+		},
+		{
+			name: "go binary not executable",
+			setup: func(_ *Installer, mem *memFS, _ *fakeGo) {
+				mem.writeFile(t, "/tmp/go.tar.gz", gzipTestTar(t, []tarEntry{
+					{Name: "go/VERSION", Content: "go1.24.1\n"},
+					{Name: "go/bin/go", Mode: 0644},
+				}), 0644)
+			},
+			wantErr: "is not executable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			installer, mem, goBinary := newMemInstaller(t)
+			tt.setup(installer, mem, goBinary)
+			ctx := context.Background()
+
+			err := installer.Install(ctx, "/tmp/go.tar.gz")
+			if err == nil {
+				err = installer.Verify(ctx)
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Install() error = %v, want %q", err, tt.wantErr)
+			}
+
+			_, err = mem.Lstat("/home/gopher/.local/lib/go/obsolete.go")
+			if kept := err == nil; kept != tt.keepsOld {
+				t.Errorf("old installation kept = %v, want %v", kept, tt.keepsOld)
+			}
+			for _, dir := range []string{".go.new", ".go.old"} {
+				if _, err := mem.Lstat("/home/gopher/.local/lib/" + dir); !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("%s was left behind: %v", dir, err)
+				}
+			}
+		})
+	}
+}
+
+// cancelFS cancels a context when path is created, to cancel at a precise
+// point of an extraction
+type cancelFS struct {
+	FileSystem
+	path   string
+	cancel context.CancelFunc
+}
+
+func (c *cancelFS) Create(name string, perm fs.FileMode) (io.WriteCloser, error) {
+	if name == c.path {
+		c.cancel()
+	}
+	return c.FileSystem.Create(name, perm)
+}
+
+func TestInstallCancelled(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(installer *Installer, mem *memFS, cancel context.CancelFunc)
+	}{
+		{
+			name: "during extraction",
+			setup: func(installer *Installer, mem *memFS, cancel context.CancelFunc) {
+				installer.FS = &cancelFS{FileSystem: mem, path: "/home/gopher/.local/lib/.go.new/go/src/fmt/print.go", cancel: cancel}
+			},
+		},
+		{
+			name: "before swap",
+			setup: func(installer *Installer, _ *memFS, cancel context.CancelFunc) {
+				installer.BeforeSwap = func(context.Context) error {
+					cancel()
+					return nil
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			installer, mem, _ := newMemInstaller(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			tt.setup(installer, mem, cancel)
+
+			err := installer.Install(ctx, "/tmp/go.tar.gz")
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("Install() error = %v, want context.Canceled", err)
+			}
+			if content := mem.readFile(t, "/home/gopher/.local/lib/go/obsolete.go"); content != "old" {
+				t.Errorf("old installation changed, obsolete.go = %q", content)
+			}
+			if _, err := mem.Lstat("/home/gopher/.local/bin/gofmt"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("symlinks were created after cancellation: %v", err)
+			}
+			if _, err := mem.Lstat("/home/gopher/.local/lib/.go.new"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("staging directory was left behind: %v", err)
+			}
+		})
+	}
+
+	t.Run("during verification", func(t *testing.T) {
+		installer, _, goBinary := newMemInstaller(t)
+		if err := installer.Install(context.Background(), "/tmp/go.tar.gz"); err != nil {
+			t.Fatalf("Install() error = %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := installer.Verify(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("Verify() error = %v, want context.Canceled", err)
+		}
+		if len(goBinary.calls) != 1 {
+			t.Errorf("commands = %q", goBinary.calls)
+		}
+	})
+}
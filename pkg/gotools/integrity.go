@@ -0,0 +1,205 @@
+package gotools
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// IntegrityReport lists the differences between an installation and its release archive.
+// All paths are relative to the installation directory, e.g. "go/src/fmt/print.go".
+type IntegrityReport struct {
+	// Added are files present on disk but not in the archive
+	Added []string
+	// Missing are files present in the archive but not on disk
+	Missing []string
+	// Modified are files whose content, type or link target differ from the archive
+	Modified []string
+}
+
+// Clean reports whether the installation matches the archive
+func (r *IntegrityReport) Clean() bool {
+	return len(r.Added) == 0 && len(r.Missing) == 0 && len(r.Modified) == 0
+}
+
+// Drifted returns the missing and modified files, which can be restored from the archive
+func (r *IntegrityReport) Drifted() []string {
+	drifted := append(slices.Clone(r.Missing), r.Modified...)
+	slices.Sort(drifted)
+	return drifted
+}
+
+// InstalledVersion reads the version of the Go installation in InstallDir
+// from its VERSION file, e.g. "1.24.1".
+func (i *Installer) InstalledVersion() (string, error) {
+	file, err := i.filesystem().Open(filepath.Join(i.InstallDir, "go", "VERSION"))
+	if err != nil {
+		return "", fmt.Errorf("failed to open VERSION file: %w", err)
+	}
+	defer file.Close()
+
+	// The first line holds the version, newer releases add more lines like the build time.
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		return "", fmt.Errorf("empty VERSION file")
+	}
+
+	version := strings.TrimSpace(scanner.Text())
+	if !strings.HasPrefix(version, "go") {
+		return "", fmt.Errorf("unexpected version %q in VERSION file", version)
+	}
+
+	return strings.TrimPrefix(version, "go"), nil
+}
+
+// VerifyIntegrity compares every file of the installation in InstallDir
+// with the release archive at tarballPath.
+func (i *Installer) VerifyIntegrity(ctx context.Context, tarballPath string) (*IntegrityReport, error) {
+	fsys := i.filesystem()
+	archive, err := fsys.Open(tarballPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tarball: %w", err)
+	}
+	defer archive.Close()
+
+	gzipReader, err := gzip.NewReader(archive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+	}
+	defer gzipReader.Close()
+
+	report := &IntegrityReport{}
+	inArchive := make(map[string]bool)
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("verification cancelled: %w", ctx.Err())
+		default:
+		}
+
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar header: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeSymlink {
+			continue
+		}
+
+		if !filepath.IsLocal(header.Name) {
+			return nil, fmt.Errorf("%w: path traversal attempt: %s", ErrUnsafeArchive, header.Name)
+		}
+
+		name := filepath.Clean(header.Name)
+		inArchive[name] = true
+
+		info, err := fsys.Lstat(filepath.Join(i.InstallDir, name))
+		if os.IsNotExist(err) {
+			report.Missing = append(report.Missing, name)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", name, err)
+		}
+
+		matches, err := i.matchesEntry(name, info, header, tarReader)
+		if err != nil {
+			return nil, err
+		}
+		if !matches {
+			report.Modified = append(report.Modified, name)
+		}
+	}
+
+	root := filepath.Join(i.InstallDir, "go")
+	err = fsys.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		name, err := filepath.Rel(i.InstallDir, path)
+		if err != nil {
+			return err
+		}
+		if !inArchive[name] {
+			report.Added = append(report.Added, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk installation: %w", err)
+	}
+
+	slices.Sort(report.Missing)
+	slices.Sort(report.Modified)
+	slices.Sort(report.Added)
+
+	return report, nil
+}
+
+// matchesEntry compares a file on disk with its archive entry
+func (i *Installer) matchesEntry(name string, info fs.FileInfo, header *tar.Header, content io.Reader) (bool, error) {
+	path := filepath.Join(i.InstallDir, name)
+
+	if header.Typeflag == tar.TypeSymlink {
+		if info.Mode()&fs.ModeSymlink == 0 {
+			return false, nil
+		}
+		target, err := i.filesystem().Readlink(path)
+		if err != nil {
+			return false, fmt.Errorf("failed to read symlink %s: %w", name, err)
+		}
+		return target == header.Linkname, nil
+	}
+
+	if !info.Mode().IsRegular() || info.Size() != header.Size {
+		return false, nil
+	}
+
+	expected := sha256.New()
+	if _, err := io.Copy(expected, content); err != nil {
+		return false, fmt.Errorf("failed to read %s from archive: %w", name, err)
+	}
+
+	actual, err := fileChecksum(i.filesystem(), path)
+	if err != nil {
+		return false, err
+	}
+
+	return hex.EncodeToString(expected.Sum(nil)) == actual, nil
+}
+
+// Repair restores the missing and modified files of report from the archive.
+// Added files are left untouched.
+func (i *Installer) Repair(ctx context.Context, tarballPath string, report *IntegrityReport) error {
+	drifted := make(map[string]bool)
+	for _, name := range report.Drifted() {
+		drifted[name] = true
+	}
+
+	if len(drifted) == 0 {
+		return nil
+	}
+
+	return i.extractEntries(ctx, tarballPath, func(name string) bool {
+		return drifted[filepath.Clean(name)]
+	})
+}
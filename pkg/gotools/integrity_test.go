@@ -0,0 +1,88 @@
+package gotools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestInstalledVersion(t *testing.T) {
+	installer := &Installer{InstallDir: t.TempDir()}
+	if err := installer.extractTarball(context.Background(), writeTestTarball(t, testGoTarball)); err != nil {
+		t.Fatal(err)
+	}
+
+	version, err := installer.InstalledVersion()
+	if err != nil {
+		t.Fatalf("InstalledVersion() error = %v", err)
+	}
+	if version != "1.24.1" {
+		t.Errorf("InstalledVersion() = %q, want %q", version, "1.24.1")
+	}
+}
+
+func TestVerifyIntegrity(t *testing.T) {
+	ctx := context.Background()
+	tarball := writeTestTarball(t, testGoTarball)
+	installer := &Installer{InstallDir: t.TempDir()}
+	if err := installer.extractTarball(ctx, tarball); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := installer.VerifyIntegrity(ctx, tarball)
+	if err != nil {
+		t.Fatalf("VerifyIntegrity() error = %v", err)
+	}
+	if !report.Clean() {
+		t.Fatalf("fresh installation should be clean, got %+v", report)
+	}
+
+	root := filepath.Join(installer.InstallDir, "go")
+	if err := os.WriteFile(filepath.Join(root, "src", "fmt", "print.go"), []byte("package fmt // debug\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(root, "bin", "go")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "src", "fmt", "debug.go"), []byte("package fmt\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(root, "misc", "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("elsewhere", filepath.Join(root, "misc", "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = installer.VerifyIntegrity(ctx, tarball)
+	if err != nil {
+		t.Fatalf("VerifyIntegrity() error = %v", err)
+	}
+
+	if want := []string{"go/src/fmt/debug.go"}; !slices.Equal(report.Added, want) {
+		t.Errorf("Added = %v, want %v", report.Added, want)
+	}
+	if want := []string{"go/bin/go"}; !slices.Equal(report.Missing, want) {
+		t.Errorf("Missing = %v, want %v", report.Missing, want)
+	}
+	if want := []string{"go/misc/link", "go/src/fmt/print.go"}; !slices.Equal(report.Modified, want) {
+		t.Errorf("Modified = %v, want %v", report.Modified, want)
+	}
+
+	if err := installer.Repair(ctx, tarball, report); err != nil {
+		t.Fatalf("Repair() error = %v", err)
+	}
+
+	report, err = installer.VerifyIntegrity(ctx, tarball)
+	if err != nil {
+		t.Fatalf("VerifyIntegrity() error = %v", err)
+	}
+	if len(report.Drifted()) != 0 {
+		t.Errorf("repaired installation still drifted: %v", report.Drifted())
+	}
+	if want := []string{"go/src/fmt/debug.go"}; !slices.Equal(report.Added, want) {
+		t.Errorf("Repair() should leave added files alone, Added = %v, want %v", report.Added, want)
+	}
+}
@@ -0,0 +1,303 @@
+// Package releasetest serves fake Go releases for tests. A Server answers like
+// the release metadata endpoint (?mode=json, with &include=all for unstable
+// releases) and the download host, with tiny archives whose go binary is a
+// shell script printing its version.
+package releasetest
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Fault makes a Server misbehave when serving archives
+type Fault int
+
+const (
+	// OK serves archives as they are
+	OK Fault = iota
+	// Corrupt serves archives that don't match their checksum
+	Corrupt
+	// Slow sends archives in small chunks with a delay between them
+	Slow
+	// Failing answers archive requests with 500 Internal Server Error, for the
+	// given number of first requests or, if it is zero, for all of them
+	Failing
+	// NoRanges serves archives without support for range requests
+	NoRanges
+)
+
+// chunkSize is the size of the chunks sent by a Slow server
+const chunkSize = 64
+
+// Release is a fake Go release
+type Release struct {
+	// Version is the version without the "go" prefix, e.g. "1.24.1"
+	Version string
+	// Stable marks the release as stable, only stable releases are installed
+	Stable bool
+	// Archive is the gzipped tarball of the release
+	Archive []byte
+}
+
+// Filename returns the name of the release's archive, e.g. "go1.24.1.linux-amd64.tar.gz"
+func (r *Release) Filename() string {
+	return fmt.Sprintf("go%s.linux-amd64.tar.gz", r.Version)
+}
+
+// Checksum returns the hex encoded SHA256 checksum of the archive
+func (r *Release) Checksum() string {
+	sum := sha256.Sum256(r.Archive)
+	return hex.EncodeToString(sum[:])
+}
+
+// NewRelease returns a stable release with a minimal archive: a VERSION file,
+// go and gofmt scripts printing the version and a source file
+func NewRelease(t testing.TB, version string) *Release {
+	t.Helper()
+
+	script := fmt.Sprintf("#!/bin/sh\necho go version go%s linux/amd64\n", version)
+	files := []struct {
+		name    string
+		mode    int64
+		content string
+	}{
+		{"go/VERSION", 0644, fmt.Sprintf("go%s\ntime 2025-01-01T00:00:00Z\n", version)},
+		{"go/bin/go", 0755, script},
+		{"go/bin/gofmt", 0755, "#!/bin/sh\n"},
+		{"go/src/fmt/print.go", 0644, "package fmt\n"},
+	}
+
+	buf := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+	modTime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, dir := range []string{"go/", "go/bin/", "go/src/", "go/src/fmt/"} {
+		header := &tar.Header{Name: dir, Typeflag: tar.TypeDir, Mode: 0755, ModTime: modTime}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range files {
+		header := &tar.Header{
+			Name:     file.name,
+			Typeflag: tar.TypeReg,
+			Mode:     file.mode,
+			Size:     int64(len(file.content)),
+			ModTime:  modTime,
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tarWriter.Write([]byte(file.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return &Release{Version: version, Stable: true, Archive: buf.Bytes()}
+}
+
+// Server serves fake releases. Metadata is served at ReleasesURL, archives
+// and checksums below DownloadURL.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	releases []*Release
+	fault    Fault
+	failures int
+	delay    time.Duration
+	requests map[string]int
+}
+
+// NewServer starts a server for releases, newest first like the official
+// endpoint. It is closed when the test ends.
+func NewServer(t testing.TB, releases ...*Release) *Server {
+	s := &Server{
+		releases: releases,
+		delay:    10 * time.Millisecond,
+		requests: make(map[string]int),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /dl/", s.serveMetadata)
+	mux.HandleFunc("GET /go/{file}", s.serveFile)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// ReleasesURL is the URL of the release metadata
+func (s *Server) ReleasesURL() string {
+	return s.URL + "/dl/?mode=json"
+}
+
+// DownloadURL is the URL the archives and checksums are served below
+func (s *Server) DownloadURL() string {
+	return s.URL + "/go/"
+}
+
+// SetFault makes the server misbehave when serving archives. failures limits
+// a Failing fault to the first requests, delay is the pause of a Slow fault.
+func (s *Server) SetFault(fault Fault, failures int, delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fault = fault
+	s.failures = failures
+	s.delay = delay
+}
+
+// Requests returns the number of GET requests for path, e.g.
+// "/go/go1.24.1.linux-amd64.tar.gz". HEAD requests are counted as "HEAD " and
+// the path.
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+func (s *Server) serveMetadata(w http.ResponseWriter, r *http.Request) {
+	s.count(r)
+	if r.URL.Query().Get("mode") != "json" {
+		http.Error(w, "only ?mode=json is supported", http.StatusNotFound)
+		return
+	}
+	// Like go.dev, unstable releases are only listed with include=all.
+	includeAll := r.URL.Query().Get("include") == "all"
+
+	type file struct {
+		Filename string `json:"filename"`
+		OS       string `json:"os"`
+		Arch     string `json:"arch"`
+		Version  string `json:"version"`
+		SHA256   string `json:"sha256"`
+		Size     int    `json:"size"`
+		Kind     string `json:"kind"`
+	}
+	type release struct {
+		Version string `json:"version"`
+		Stable  bool   `json:"stable"`
+		Files   []file `json:"files"`
+	}
+
+	s.mu.Lock()
+	metadata := make([]release, 0, len(s.releases))
+	for _, r := range s.releases {
+		if !r.Stable && !includeAll {
+			continue
+		}
+		metadata = append(metadata, release{
+			Version: "go" + r.Version,
+			Stable:  r.Stable,
+			Files: []file{{
+				Filename: r.Filename(),
+				OS:       "linux",
+				Arch:     "amd64",
+				Version:  "go" + r.Version,
+				SHA256:   r.Checksum(),
+				Size:     len(r.Archive),
+				Kind:     "archive",
+			}},
+		})
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(metadata)
+}
+
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request) {
+	requests := s.count(r)
+	name := r.PathValue("file")
+
+	s.mu.Lock()
+	fault, failures, delay := s.fault, s.failures, s.delay
+	var release *Release
+	for _, candidate := range s.releases {
+		if name == candidate.Filename() || name == candidate.Filename()+".sha256" {
+			release = candidate
+		}
+	}
+	s.mu.Unlock()
+
+	if release == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if strings.HasSuffix(name, ".sha256") {
+		fmt.Fprintln(w, release.Checksum())
+		return
+	}
+
+	archive := release.Archive
+	switch fault {
+	case Corrupt:
+		archive = bytes.Clone(archive)
+		archive[len(archive)/2] ^= 0xff
+	case Failing:
+		if r.Method == http.MethodGet && (failures == 0 || requests <= failures) {
+			http.Error(w, "fake failure", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	switch fault {
+	case Slow, NoRanges:
+		w.Header().Set("Content-Length", fmt.Sprint(len(archive)))
+	default:
+		// ServeContent answers HEAD and range requests.
+		http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(archive))
+		return
+	}
+	if fault != Slow {
+		w.Write(archive)
+		return
+	}
+
+	for chunk := range slices.Chunk(archive, chunkSize) {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(delay):
+		}
+		if _, err := w.Write(chunk); err != nil {
+			return
+		}
+		w.(http.Flusher).Flush()
+	}
+}
+
+// count records a request and returns the number of requests for its path
+// with the same method so far
+func (s *Server) count(r *http.Request) int {
+	key := r.URL.Path
+	if r.Method == http.MethodHead {
+		key = "HEAD " + key
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[key]++
+	return s.requests[key]
+}
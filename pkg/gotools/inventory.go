@@ -0,0 +1,438 @@
+package gotools
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// ToolchainKind tells how a toolchain got onto the system
+type ToolchainKind string
+
+const (
+	// KindManaged is the installation maintained by updatego
+	KindManaged ToolchainKind = "managed"
+	// KindSource is a toolchain built by InstallSource
+	KindSource ToolchainKind = "source"
+	// KindSDK is a toolchain downloaded by a golang.org/dl command
+	KindSDK ToolchainKind = "sdk"
+	// KindSystem is a toolchain installed by hand or by the distribution
+	KindSystem ToolchainKind = "system"
+	// KindModuleCache is a toolchain downloaded by the go command for GOTOOLCHAIN
+	KindModuleCache ToolchainKind = "modcache"
+)
+
+// kindOrder sorts the inventory
+var kindOrder = []ToolchainKind{KindManaged, KindSource, KindSDK, KindSystem, KindModuleCache}
+
+// moduleToolchainPrefix starts the module cache directories of toolchains,
+// e.g. "toolchain@v0.0.1-go1.24.1.linux-amd64"
+const moduleToolchainPrefix = "toolchain@v0.0.1-"
+
+// Toolchain is a Go installation found by an Inventory
+type Toolchain struct {
+	// Root is the GOROOT of the toolchain
+	Root string
+	Kind ToolchainKind
+	// Version is the version from the VERSION file, e.g. "1.24.1", or the
+	// name of a source build
+	Version string
+	// Size is the disk usage in bytes, including cached downloads
+	Size int64
+	// References describe what uses the toolchain, e.g. "go on PATH"
+	References []string
+
+	// downloads are the module download cache files of the toolchain
+	downloads []string
+}
+
+// Referenced reports whether anything uses the toolchain
+func (t *Toolchain) Referenced() bool {
+	return len(t.References) > 0
+}
+
+// Inventory discovers the Go toolchains of the system
+type Inventory struct {
+	// Installer locates the managed installation and the source builds
+	Installer *Installer
+	// SystemRoots are glob patterns of toolchains installed outside of updatego
+	SystemRoots []string
+	// SDKDir holds the toolchains of the golang.org/dl commands
+	SDKDir string
+	// ModCache is the module cache, GOMODCACHE
+	ModCache string
+	// Path is the search path for go binaries, PATH
+	Path string
+	// GOROOT and GOTOOLCHAIN are the values of the environment variables
+	GOROOT      string
+	GOTOOLCHAIN string
+	// Projects are searched for go.mod and go.work files, whose go and
+	// toolchain lines reference module cache toolchains
+	Projects []string
+	// Logger receives progress messages
+	Logger *slog.Logger
+}
+
+// DefaultSystemRoots are the usual places of Go installations not managed by updatego
+var DefaultSystemRoots = []string{
+	"/usr/local/go",
+	"/usr/lib/go",
+	"/usr/lib/go-*",
+	"/usr/lib/golang",
+	"/usr/lib64/go",
+	"/snap/go/current",
+}
+
+// NewInventory creates an inventory of the default locations and the environment
+func NewInventory(installer *Installer) (*Inventory, error) {
+	home, err := homeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine home directory: %w", err)
+	}
+
+	modCache := os.Getenv("GOMODCACHE")
+	if modCache == "" {
+		gopath := filepath.Join(home, "go")
+		if list := filepath.SplitList(os.Getenv("GOPATH")); len(list) > 0 && list[0] != "" {
+			gopath = list[0]
+		}
+		modCache = filepath.Join(gopath, "pkg", "mod")
+	}
+
+	return &Inventory{
+		Installer:   installer,
+		SystemRoots: DefaultSystemRoots,
+		SDKDir:      filepath.Join(home, "sdk"),
+		ModCache:    modCache,
+		Path:        os.Getenv("PATH"),
+		GOROOT:      os.Getenv("GOROOT"),
+		GOTOOLCHAIN: os.Getenv("GOTOOLCHAIN"),
+		Logger:      slog.Default(),
+	}, nil
+}
+
+// Scan finds all toolchains, measures them and resolves their references
+func (inv *Inventory) Scan(ctx context.Context) ([]Toolchain, error) {
+	logger := loggerOrDefault(inv.Logger)
+	found := make(map[string]*Toolchain)
+
+	add := func(root string, kind ToolchainKind) {
+		resolved, err := filepath.EvalSymlinks(root)
+		if err != nil || !isGoRoot(resolved) {
+			return
+		}
+		if _, ok := found[resolved]; !ok {
+			found[resolved] = &Toolchain{Root: resolved, Kind: kind}
+		}
+	}
+
+	if inv.Installer != nil {
+		add(filepath.Join(inv.Installer.InstallDir, "go"), KindManaged)
+		builds, _ := filepath.Glob(filepath.Join(inv.Installer.InstallDir, "go?*"))
+		for _, root := range builds {
+			add(root, KindSource)
+		}
+	}
+	if inv.SDKDir != "" {
+		sdks, _ := filepath.Glob(filepath.Join(inv.SDKDir, "go*"))
+		for _, root := range sdks {
+			add(root, KindSDK)
+		}
+	}
+	for _, pattern := range inv.SystemRoots {
+		roots, _ := filepath.Glob(pattern)
+		for _, root := range roots {
+			add(root, KindSystem)
+		}
+	}
+	if inv.GOROOT != "" {
+		add(inv.GOROOT, KindSystem)
+	}
+	for _, dir := range filepath.SplitList(inv.Path) {
+		if binary, err := filepath.EvalSymlinks(filepath.Join(dir, "go")); err == nil {
+			add(filepath.Dir(filepath.Dir(binary)), KindSystem)
+		}
+	}
+	if inv.ModCache != "" {
+		modules, _ := filepath.Glob(filepath.Join(inv.ModCache, "golang.org", moduleToolchainPrefix+"*"))
+		for _, root := range modules {
+			add(root, KindModuleCache)
+		}
+	}
+
+	toolchains := make([]Toolchain, 0, len(found))
+	for _, toolchain := range found {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("inventory cancelled: %w", err)
+		}
+
+		logger.Debug("Measuring toolchain", "root", toolchain.Root)
+		toolchain.Version = readVersionFile(toolchain.Root)
+		toolchain.Size = diskUsage(toolchain.Root)
+		if toolchain.Kind == KindModuleCache {
+			toolchain.downloads = inv.moduleDownloads(toolchain.Root)
+			for _, download := range toolchain.downloads {
+				toolchain.Size += diskUsage(download)
+			}
+		}
+		toolchains = append(toolchains, *toolchain)
+	}
+
+	if err := inv.resolveReferences(ctx, toolchains); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(toolchains, func(a, b Toolchain) int {
+		return cmp.Or(
+			cmp.Compare(slices.Index(kindOrder, a.Kind), slices.Index(kindOrder, b.Kind)),
+			cmp.Compare(a.Root, b.Root),
+		)
+	})
+
+	return toolchains, nil
+}
+
+// resolveReferences fills the references of toolchains
+func (inv *Inventory) resolveReferences(ctx context.Context, toolchains []Toolchain) error {
+	refer := func(path, reference string) {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return
+		}
+		for i := range toolchains {
+			if resolved == toolchains[i].Root || strings.HasPrefix(resolved, toolchains[i].Root+string(filepath.Separator)) {
+				toolchains[i].References = append(toolchains[i].References, reference)
+			}
+		}
+	}
+	referVersion := func(version, reference string) {
+		for i := range toolchains {
+			if toolchains[i].Kind == KindModuleCache && matchesVersion(toolchains[i].Version, version) {
+				toolchains[i].References = append(toolchains[i].References, reference)
+			}
+		}
+	}
+
+	// Only the first go on PATH is run as "go".
+	for _, dir := range filepath.SplitList(inv.Path) {
+		binary := filepath.Join(dir, "go")
+		if _, err := os.Stat(binary); err == nil {
+			refer(binary, "go on PATH ("+binary+")")
+			break
+		}
+	}
+
+	if inv.GOROOT != "" {
+		refer(inv.GOROOT, "$GOROOT")
+	}
+
+	if inv.Installer != nil {
+		links, _ := os.ReadDir(inv.Installer.BinDir)
+		for _, link := range links {
+			if link.Type()&fs.ModeSymlink != 0 {
+				path := filepath.Join(inv.Installer.BinDir, link.Name())
+				refer(path, path)
+			}
+		}
+	}
+
+	if version, ok := toolchainVersion(inv.GOTOOLCHAIN); ok {
+		referVersion(version, "$GOTOOLCHAIN")
+	}
+
+	for _, project := range inv.Projects {
+		err := walkModuleFiles(ctx, project, func(path, directive, version string) {
+			referVersion(version, fmt.Sprintf("%s (%s %s)", path, directive, version))
+		})
+		if err != nil {
+			return fmt.Errorf("failed to search %s for go.mod files: %w", project, err)
+		}
+	}
+
+	return nil
+}
+
+// moduleDownloads returns the download cache files of the module cache toolchain at root
+func (inv *Inventory) moduleDownloads(root string) []string {
+	version := strings.TrimPrefix(filepath.Base(root), "toolchain@")
+	downloads, _ := filepath.Glob(filepath.Join(inv.ModCache, "cache", "download", "golang.org", "toolchain", "@v", version+".*"))
+	return downloads
+}
+
+// Collect removes the unreferenced module cache toolchains, or with dryRun
+// only reports them. It returns the removed toolchains.
+func (inv *Inventory) Collect(toolchains []Toolchain, dryRun bool) ([]Toolchain, error) {
+	logger := loggerOrDefault(inv.Logger)
+
+	var removed []Toolchain
+	for _, toolchain := range toolchains {
+		if toolchain.Kind != KindModuleCache || toolchain.Referenced() {
+			continue
+		}
+
+		if !dryRun {
+			logger.Info("Removing unused toolchain", "root", toolchain.Root)
+			for _, path := range append([]string{toolchain.Root}, toolchain.downloads...) {
+				if err := removeReadOnly(path); err != nil {
+					return removed, fmt.Errorf("failed to remove %s: %w", path, err)
+				}
+			}
+		}
+		removed = append(removed, toolchain)
+	}
+
+	return removed, nil
+}
+
+// PrintToolchains writes the toolchains as a table to w
+func PrintToolchains(w io.Writer, toolchains []Toolchain) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ROOT\tKIND\tVERSION\tSIZE\tREFERENCED BY")
+	for _, t := range toolchains {
+		references := strings.Join(t.References, ", ")
+		if references == "" {
+			references = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", t.Root, t.Kind, t.Version, formatBytes(uint64(t.Size)), references)
+	}
+	return tw.Flush()
+}
+
+// TotalSize returns the disk usage of toolchains in a human readable form
+func TotalSize(toolchains []Toolchain) string {
+	var total int64
+	for _, t := range toolchains {
+		total += t.Size
+	}
+	return formatBytes(uint64(total))
+}
+
+// isGoRoot reports whether dir looks like a GOROOT
+func isGoRoot(dir string) bool {
+	if _, err := os.Stat(filepath.Join(dir, "bin", "go")); err != nil {
+		return false
+	}
+	for _, marker := range []string{"VERSION", filepath.Join("src", "runtime")} {
+		if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// readVersionFile returns the version of the GOROOT at root without the "go"
+// prefix, or "unknown"
+func readVersionFile(root string) string {
+	data, err := os.ReadFile(filepath.Join(root, "VERSION"))
+	if err != nil {
+		return "unknown"
+	}
+	version, _, _ := strings.Cut(string(data), "\n")
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return "unknown"
+	}
+	return strings.TrimPrefix(version, "go")
+}
+
+// diskUsage returns the size of the regular files below path
+func diskUsage(path string) int64 {
+	var size int64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// removeReadOnly removes path like os.RemoveAll, including the read-only
+// directories of the module cache
+func removeReadOnly(path string) error {
+	filepath.WalkDir(path, func(dir string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			os.Chmod(dir, 0700)
+		}
+		return nil
+	})
+	return os.RemoveAll(path)
+}
+
+// toolchainVersion returns the version named by a GOTOOLCHAIN value like
+// "go1.24.1+auto", ok is false for "auto", "local" and "path"
+func toolchainVersion(value string) (string, bool) {
+	name, _, _ := strings.Cut(value, "+")
+	if !strings.HasPrefix(name, "go1") {
+		return "", false
+	}
+	return strings.TrimPrefix(name, "go"), true
+}
+
+// matchesVersion reports whether the toolchain version satisfies the version
+// of a go line. A language version like "1.24" matches all its releases, as
+// the go command picks a release for it.
+func matchesVersion(toolchain, version string) bool {
+	if toolchain == version {
+		return true
+	}
+	return strings.Count(version, ".") == 1 && strings.HasPrefix(toolchain, version+".")
+}
+
+// walkModuleFiles calls fn for the go and toolchain lines of all go.mod and
+// go.work files below dir. Vendored and hidden directories are skipped.
+func walkModuleFiles(ctx context.Context, dir string, fn func(path, directive, version string)) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != dir && (name == "vendor" || name == "testdata" || name == "node_modules" || strings.HasPrefix(name, ".")) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != "go.mod" && d.Name() != "go.work" {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) != 2 {
+				continue
+			}
+			switch fields[0] {
+			case "go":
+				fn(path, "go", fields[1])
+			case "toolchain":
+				if version, ok := toolchainVersion(fields[1]); ok {
+					fn(path, "toolchain", version)
+				}
+			}
+		}
+		return scanner.Err()
+	})
+}
@@ -0,0 +1,205 @@
+package gotools
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeTestGoRoot creates a GOROOT with a go binary and the given VERSION,
+// without a VERSION file if version is empty
+func writeTestGoRoot(t *testing.T, root, version string) {
+	t.Helper()
+
+	files := map[string]string{"bin/go": "#!/bin/sh\n", "src/runtime/proc.go": "package runtime\n"}
+	if version != "" {
+		files["VERSION"] = version + "\ntime 2025-01-01T00:00:00Z\n"
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func symlink(t *testing.T, target, link string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestInventory lays out toolchains of every kind below a temporary
+// directory and returns an inventory of them
+func newTestInventory(t *testing.T) (*Inventory, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	installer := &Installer{InstallDir: filepath.Join(dir, "lib"), BinDir: filepath.Join(dir, "bin")}
+
+	writeTestGoRoot(t, filepath.Join(dir, "lib", "go"), "go1.24.1")
+	symlink(t, filepath.Join(dir, "lib", "go", "bin", "go"), filepath.Join(dir, "bin", "go"))
+	writeTestGoRoot(t, filepath.Join(dir, "lib", "go1.24-custom"), "go1.24-custom")
+	symlink(t, filepath.Join(dir, "lib", "go1.24-custom", "bin", "go"), filepath.Join(dir, "bin", "go1.24-custom"))
+	writeTestGoRoot(t, filepath.Join(dir, "sdk", "go1.23.0"), "go1.23.0")
+	writeTestGoRoot(t, filepath.Join(dir, "usr", "local", "go"), "go1.22.0")
+	writeTestGoRoot(t, filepath.Join(dir, "usr", "lib", "go-1.21"), "")
+	symlink(t, filepath.Join(dir, "usr", "lib", "go-1.21", "bin", "go"), filepath.Join(dir, "usr", "bin", "go"))
+
+	modCache := filepath.Join(dir, "mod")
+	for _, version := range []string{"1.21.5", "1.23.4", "1.25.0"} {
+		name := "v0.0.1-go" + version + ".linux-amd64"
+		writeTestGoRoot(t, filepath.Join(modCache, "golang.org", "toolchain@"+name), "go"+version)
+		writeTestFile(t, filepath.Join(modCache, "cache", "download", "golang.org", "toolchain", "@v", name+".zip"), "zip")
+	}
+	// The module cache is read-only.
+	filepath.WalkDir(modCache, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			os.Chmod(path, 0555)
+		}
+		return nil
+	})
+	t.Cleanup(func() { removeReadOnly(modCache) })
+
+	project := filepath.Join(dir, "src", "project")
+	writeTestFile(t, filepath.Join(project, "go.mod"), "module example.com/project\n\ngo 1.21\n")
+	writeTestFile(t, filepath.Join(project, "vendor", "example.com", "dep", "go.mod"), "module example.com/dep\n\ntoolchain go1.23.4\n")
+
+	return &Inventory{
+		Installer:   installer,
+		SystemRoots: []string{filepath.Join(dir, "usr", "local", "go"), filepath.Join(dir, "usr", "lib", "go-*")},
+		SDKDir:      filepath.Join(dir, "sdk"),
+		ModCache:    modCache,
+		Path:        strings.Join([]string{filepath.Join(dir, "usr", "bin"), filepath.Join(dir, "bin")}, string(filepath.ListSeparator)),
+		GOTOOLCHAIN: "go1.25.0+auto",
+		Projects:    []string{filepath.Join(dir, "src")},
+	}, dir
+}
+
+func TestInventoryScan(t *testing.T) {
+	inv, dir := newTestInventory(t)
+
+	toolchains, err := inv.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	mod := filepath.Join(dir, "mod", "golang.org")
+	want := []struct {
+		root       string
+		kind       ToolchainKind
+		version    string
+		references []string
+	}{
+		{filepath.Join(dir, "lib", "go"), KindManaged, "1.24.1", []string{filepath.Join(dir, "bin", "go")}},
+		{filepath.Join(dir, "lib", "go1.24-custom"), KindSource, "1.24-custom", []string{filepath.Join(dir, "bin", "go1.24-custom")}},
+		{filepath.Join(dir, "sdk", "go1.23.0"), KindSDK, "1.23.0", nil},
+		{filepath.Join(dir, "usr", "lib", "go-1.21"), KindSystem, "unknown", []string{"go on PATH (" + filepath.Join(dir, "usr", "bin", "go") + ")"}},
+		{filepath.Join(dir, "usr", "local", "go"), KindSystem, "1.22.0", nil},
+		{filepath.Join(mod, "toolchain@v0.0.1-go1.21.5.linux-amd64"), KindModuleCache, "1.21.5", []string{filepath.Join(dir, "src", "project", "go.mod") + " (go 1.21)"}},
+		{filepath.Join(mod, "toolchain@v0.0.1-go1.23.4.linux-amd64"), KindModuleCache, "1.23.4", nil},
+		{filepath.Join(mod, "toolchain@v0.0.1-go1.25.0.linux-amd64"), KindModuleCache, "1.25.0", []string{"$GOTOOLCHAIN"}},
+	}
+
+	if len(toolchains) != len(want) {
+		t.Fatalf("Scan() found %d toolchains, want %d: %+v", len(toolchains), len(want), toolchains)
+	}
+	for i, w := range want {
+		have := toolchains[i]
+		if have.Root != w.root || have.Kind != w.kind || have.Version != w.version || !slices.Equal(have.References, w.references) {
+			t.Errorf("toolchain %d = %s %s %s %q\nwant %s %s %s %q", i,
+				have.Root, have.Kind, have.Version, have.References, w.root, w.kind, w.version, w.references)
+		}
+		if have.Size <= 0 {
+			t.Errorf("toolchain %s has size %d", have.Root, have.Size)
+		}
+	}
+
+	out := &strings.Builder{}
+	if err := PrintToolchains(out, toolchains); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != len(want)+1 || !strings.HasPrefix(lines[0], "ROOT") {
+		t.Errorf("PrintToolchains() = %q", out)
+	}
+}
+
+func TestInventoryCollect(t *testing.T) {
+	inv, dir := newTestInventory(t)
+	unused := filepath.Join(dir, "mod", "golang.org", "toolchain@v0.0.1-go1.23.4.linux-amd64")
+	download := filepath.Join(dir, "mod", "cache", "download", "golang.org", "toolchain", "@v", "v0.0.1-go1.23.4.linux-amd64.zip")
+
+	toolchains, err := inv.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	removed, err := inv.Collect(toolchains, true)
+	if err != nil || len(removed) != 1 || removed[0].Root != unused {
+		t.Fatalf("Collect() dry run = %+v, %v", removed, err)
+	}
+	if _, err := os.Stat(unused); err != nil {
+		t.Fatalf("dry run removed %s: %v", unused, err)
+	}
+
+	removed, err = inv.Collect(toolchains, false)
+	if err != nil || len(removed) != 1 {
+		t.Fatalf("Collect() = %+v, %v", removed, err)
+	}
+	for _, path := range []string{unused, download} {
+		if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%s was not removed: %v", path, err)
+		}
+	}
+
+	toolchains, err = inv.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(toolchains) != 7 {
+		t.Errorf("Scan() after Collect() found %d toolchains, want 7", len(toolchains))
+	}
+}
+
+func TestMatchesVersion(t *testing.T) {
+	tests := []struct {
+		toolchain, version string
+		want               bool
+	}{
+		{"1.24.1", "1.24.1", true},
+		{"1.24.1", "1.24", true},
+		{"1.24.1", "1.24.0", false},
+		{"1.24.1", "1.2", false},
+		{"1.24rc1", "1.24", false},
+	}
+
+	for _, tt := range tests {
+		if have := matchesVersion(tt.toolchain, tt.version); have != tt.want {
+			t.Errorf("matchesVersion(%q, %q) = %v, want %v", tt.toolchain, tt.version, have, tt.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package gotools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// lockFileName is the name of the lock file within the installation directory
+const lockFileName = ".updatego.lock"
+
+// lockRetryInterval is how often a waiting process retries to take the lock
+const lockRetryInterval = 500 * time.Millisecond
+
+// ErrLocked is returned if the lock is held by another process
+var ErrLocked = errors.New("locked by another process")
+
+// LockOwner identifies the process holding a lock
+type LockOwner struct {
+	PID      int
+	Hostname string
+}
+
+func (o LockOwner) String() string {
+	return fmt.Sprintf("pid %d on %s", o.PID, o.Hostname)
+}
+
+// Lock is an advisory flock(2) lock on a file, cooperating processes take it
+// before modifying the installation directory. The lock file is never
+// removed, the kernel releases the lock when its process dies.
+type Lock struct {
+	path  string
+	owner LockOwner
+	file  *os.File
+}
+
+// Lock takes the lock on the installation directory. If block is set, it
+// retries until the lock is free or ctx is done, otherwise it fails with
+// ErrLocked right away.
+func (i *Installer) Lock(ctx context.Context, block bool) (*Lock, error) {
+	if err := os.MkdirAll(i.InstallDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create install directory %s: %w", i.InstallDir, err)
+	}
+
+	return AcquireLock(ctx, filepath.Join(i.InstallDir, lockFileName), block)
+}
+
+// AcquireLock takes the lock file at path, see Installer.Lock
+func AcquireLock(ctx context.Context, path string, block bool) (*Lock, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine hostname: %w", err)
+	}
+
+	lock := &Lock{
+		path:  path,
+		owner: LockOwner{PID: os.Getpid(), Hostname: hostname},
+	}
+
+	if !block {
+		if err := lock.tryAcquire(); err != nil {
+			return nil, err
+		}
+		return lock, nil
+	}
+
+	var lastSeenErr error
+	err = wait.PollUntilContextCancel(ctx, lockRetryInterval, immediate, func(ctx context.Context) (bool, error) {
+		lastSeenErr = lock.tryAcquire()
+		if errors.Is(lastSeenErr, ErrLocked) {
+			return false, nil // Held by someone else, retry
+		}
+		return lastSeenErr == nil, lastSeenErr
+	})
+	if err != nil {
+		if lastSeenErr != nil {
+			return nil, fmt.Errorf("failed to wait for lock: %w", lastSeenErr)
+		}
+		return nil, fmt.Errorf("failed to wait for lock: %w", err)
+	}
+
+	return lock, nil
+}
+
+// tryAcquire takes the lock once and records the owner in the lock file for
+// the error messages of other processes
+func (l *Lock) tryAcquire() error {
+	file, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			return fmt.Errorf("failed to lock %s: %w", l.path, err)
+		}
+		if owner, err := readLockOwner(l.path); err == nil {
+			return fmt.Errorf("%w: %s holds %s", ErrLocked, owner, l.path)
+		}
+		return fmt.Errorf("%w: %s", ErrLocked, l.path)
+	}
+
+	if err := file.Truncate(0); err == nil {
+		_, err = fmt.Fprintf(file, "%d %s\n", l.owner.PID, l.owner.Hostname)
+	}
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+
+	l.file = file
+	return nil
+}
+
+// Release releases the lock, the lock file stays for the next process
+func (l *Lock) Release() error {
+	if l.file == nil {
+		return fmt.Errorf("lock %s is not held", l.path)
+	}
+	file := l.file
+	l.file = nil
+
+	// The owner is gone once the lock is released.
+	truncateErr := file.Truncate(0)
+	// Closing the file releases the lock.
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	if truncateErr != nil {
+		return fmt.Errorf("failed to clear lock file: %w", truncateErr)
+	}
+
+	return nil
+}
+
+// readLockOwner parses the "<pid> <hostname>" content of a lock file
+func readLockOwner(path string) (LockOwner, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return LockOwner{}, err
+	}
+
+	fields := strings.Fields(string(content))
+	if len(fields) != 2 {
+		return LockOwner{}, fmt.Errorf("malformed lock file %s: %q", path, content)
+	}
+
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return LockOwner{}, fmt.Errorf("malformed pid in lock file %s: %w", path, err)
+	}
+
+	return LockOwner{PID: pid, Hostname: fields[1]}, nil
+}
@@ -0,0 +1,273 @@
+package gotools
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLockExclusive(t *testing.T) {
+	ctx := context.Background()
+	installer := &Installer{InstallDir: t.TempDir()}
+
+	lock, err := installer.Lock(ctx, false)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+
+	if _, err := installer.Lock(ctx, false); !errors.Is(err, ErrLocked) {
+		t.Errorf("second Lock() error = %v, want %v", err, ErrLocked)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+
+	lock, err = installer.Lock(ctx, false)
+	if err != nil {
+		t.Fatalf("Lock() after Release() error = %v", err)
+	}
+	lock.Release()
+}
+
+func TestLockWait(t *testing.T) {
+	installer := &Installer{InstallDir: t.TempDir()}
+
+	lock, err := installer.Lock(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+
+	t.Run("gives up when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*lockRetryInterval)
+		defer cancel()
+
+		if _, err := installer.Lock(ctx, true); err == nil {
+			t.Fatal("Lock() expected error while lock is held")
+		}
+	})
+
+	t.Run("takes the lock once released", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		time.AfterFunc(lockRetryInterval, func() { lock.Release() })
+
+		waited, err := installer.Lock(ctx, true)
+		if err != nil {
+			t.Fatalf("Lock() error = %v", err)
+		}
+		waited.Release()
+	})
+}
+
+func TestLockStale(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("lock file left behind", func(t *testing.T) {
+		// Files of earlier versions only name their owner, a file without a
+		// flock is free whatever its content.
+		installer := &Installer{InstallDir: t.TempDir()}
+		owner := fmt.Sprintf("%d %s\n", os.Getppid(), hostname)
+		if err := os.WriteFile(filepath.Join(installer.InstallDir, lockFileName), []byte(owner), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		lock, err := installer.Lock(context.Background(), false)
+		if err != nil {
+			t.Fatalf("Lock() error = %v", err)
+		}
+		lock.Release()
+	})
+
+	t.Run("killed holder", func(t *testing.T) {
+		dir := t.TempDir()
+		cmd := exec.Command(os.Args[0], "-test.run=^TestLockHolderProcess$")
+		cmd.Env = append(os.Environ(), "GOTOOLS_LOCK_HOLDER=1", "GOTOOLS_LOCK_DIR="+dir)
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		defer cmd.Process.Kill()
+		if line, err := bufio.NewReader(stdout).ReadString('\n'); err != nil || line != "locked\n" {
+			t.Fatalf("holder process printed %q, %v", line, err)
+		}
+
+		installer := &Installer{InstallDir: dir}
+		_, err = installer.Lock(context.Background(), false)
+		if !errors.Is(err, ErrLocked) || !strings.Contains(err.Error(), fmt.Sprintf("pid %d on %s", cmd.Process.Pid, hostname)) {
+			t.Fatalf("Lock() error = %v, want %v naming the holder", err, ErrLocked)
+		}
+
+		cmd.Process.Kill()
+		cmd.Wait()
+
+		lock, err := installer.Lock(context.Background(), false)
+		if err != nil {
+			t.Fatalf("Lock() after the holder was killed error = %v", err)
+		}
+		lock.Release()
+	})
+}
+
+// TestLockHolderProcess is not a real test, it takes the lock for
+// TestLockStale and holds it until it is killed.
+func TestLockHolderProcess(t *testing.T) {
+	if os.Getenv("GOTOOLS_LOCK_HOLDER") != "1" {
+		t.Skip("helper process for TestLockStale")
+	}
+
+	installer := &Installer{InstallDir: os.Getenv("GOTOOLS_LOCK_DIR")}
+	if _, err := installer.Lock(context.Background(), false); err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	fmt.Println("locked")
+	time.Sleep(time.Minute)
+}
+
+// TestLockContention takes and releases the lock from many goroutines at
+// once, each with its own file description, and checks that it is never
+// held twice.
+func TestLockContention(t *testing.T) {
+	installer := &Installer{InstallDir: t.TempDir()}
+
+	var holders, acquired atomic.Int32
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				lock, err := installer.Lock(context.Background(), false)
+				if errors.Is(err, ErrLocked) {
+					continue
+				}
+				if err != nil {
+					t.Errorf("Lock() error = %v", err)
+					return
+				}
+				if n := holders.Add(1); n > 1 {
+					t.Errorf("lock held by %d at once", n)
+				}
+				acquired.Add(1)
+				holders.Add(-1)
+				if err := lock.Release(); err != nil {
+					t.Errorf("Release() error = %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if acquired.Load() == 0 {
+		t.Error("lock was never acquired")
+	}
+	if _, err := os.Stat(filepath.Join(installer.InstallDir, lockFileName)); err != nil {
+		t.Errorf("lock file was removed: %v", err)
+	}
+}
+
+// TestLockConcurrentInstallers runs several installer processes against the
+// same installation directory and checks that their installs never overlap.
+func TestLockConcurrentInstallers(t *testing.T) {
+	dir := t.TempDir()
+	tarball := writeTestTarball(t, testGoTarball)
+	journal := filepath.Join(dir, "journal")
+
+	const installers = 4
+	cmds := make([]*exec.Cmd, 0, installers)
+	for range installers {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestLockHelperProcess$")
+		cmd.Env = append(os.Environ(),
+			"GOTOOLS_LOCK_HELPER=1",
+			"GOTOOLS_LOCK_DIR="+dir,
+			"GOTOOLS_LOCK_TARBALL="+tarball,
+			"GOTOOLS_LOCK_JOURNAL="+journal,
+		)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		cmds = append(cmds, cmd)
+	}
+
+	for _, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Errorf("installer process failed: %v", err)
+		}
+	}
+
+	file, err := os.Open(journal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	// Every start must be followed by the end of the same process.
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != 2*installers {
+		t.Fatalf("journal has %d lines, want %d:\n%s", len(lines), 2*installers, strings.Join(lines, "\n"))
+	}
+	for i := 0; i < len(lines); i += 2 {
+		start, end := strings.Fields(lines[i]), strings.Fields(lines[i+1])
+		if start[0] != "start" || end[0] != "end" || start[1] != end[1] {
+			t.Errorf("installs overlap:\n%s", strings.Join(lines, "\n"))
+			break
+		}
+	}
+}
+
+// TestLockHelperProcess is not a real test, it is an installer process
+// spawned by TestLockConcurrentInstallers.
+func TestLockHelperProcess(t *testing.T) {
+	if os.Getenv("GOTOOLS_LOCK_HELPER") != "1" {
+		t.Skip("helper process for TestLockConcurrentInstallers")
+	}
+
+	dir := os.Getenv("GOTOOLS_LOCK_DIR")
+	installer := &Installer{
+		InstallDir: filepath.Join(dir, "lib"),
+		BinDir:     filepath.Join(dir, "bin"),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	lock, err := installer.Lock(ctx, true)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	defer lock.Release()
+
+	journal, err := os.OpenFile(os.Getenv("GOTOOLS_LOCK_JOURNAL"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer journal.Close()
+
+	fmt.Fprintf(journal, "start %d\n", os.Getpid())
+	if err := installer.Install(ctx, os.Getenv("GOTOOLS_LOCK_TARBALL")); err != nil {
+		t.Errorf("Install() error = %v", err)
+	}
+	fmt.Fprintf(journal, "end %d\n", os.Getpid())
+}
@@ -0,0 +1,171 @@
+package gotools
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RunMetrics describes an updatego run for the node_exporter textfile collector
+type RunMetrics struct {
+	// Installed is the installed version, empty if Go isn't installed
+	Installed string
+	// Latest is the latest release, empty if the run didn't get to check it
+	Latest string
+	// Checked is when the releases were checked, zero if they weren't
+	Checked time.Time
+	// Finished is when the run ended
+	Finished time.Time
+	Duration time.Duration
+	// DownloadBytes is the number of bytes downloaded by the run
+	DownloadBytes int64
+	// Err is the error the run failed with, nil if it succeeded
+	Err error
+}
+
+// metricStages are the stages failures are counted for, so that every
+// counter exists from the first run on
+var metricStages = []Stage{
+	StageCheck, StageLock, StagePreflight, StageDownload, StageVerify, StageInstall, StageHook, StageOther,
+}
+
+const (
+	metricLatestVersion = "updatego_latest_version_info"
+	metricLastCheck     = "updatego_last_check_timestamp_seconds"
+	metricFailures      = "updatego_failures_total"
+)
+
+// WriteMetrics writes m in the Prometheus text format to path, atomically so
+// the collector never reads a partial file. The failure counters are carried
+// over from the existing file, like the latest version and the time of the
+// last check if the run didn't check.
+func WriteMetrics(path string, m RunMetrics) error {
+	previous, err := readMetrics(path)
+	if err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	m.write(buf, previous)
+
+	if err := writeFileAtomic(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	return nil
+}
+
+// write writes the metrics, previous are the samples of the last run by name
+// and labels, e.g. `updatego_failures_total{stage="download"}`
+func (m RunMetrics) write(w io.Writer, previous map[string]float64) {
+	latest, checked := m.Latest, float64(m.Checked.Unix())
+	if latest == "" {
+		for sample := range previous {
+			if rest, ok := strings.CutPrefix(sample, metricLatestVersion+`{version="`); ok {
+				latest = strings.TrimSuffix(rest, `"}`)
+			}
+		}
+	}
+	if m.Checked.IsZero() {
+		checked = previous[metricLastCheck]
+	}
+
+	gauge := func(name, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	}
+	versionInfo := func(name, help, version string) {
+		gauge(name, help)
+		if version != "" {
+			fmt.Fprintf(w, "%s{version=%q} 1\n", name, version)
+		}
+	}
+
+	versionInfo("updatego_installed_version_info", "Installed Go version.", m.Installed)
+	versionInfo(metricLatestVersion, "Latest Go release at the last check.", latest)
+
+	gauge("updatego_update_available", "Whether a newer Go release than the installed one is available.")
+	fmt.Fprintf(w, "updatego_update_available %d\n", boolMetric(latest != "" && isNewerVersion(latest, m.Installed)))
+
+	if checked > 0 {
+		gauge(metricLastCheck, "Time of the last successful release check.")
+		fmt.Fprintf(w, "%s %s\n", metricLastCheck, formatMetric(checked))
+	}
+
+	gauge("updatego_last_run_timestamp_seconds", "Time the last run finished.")
+	fmt.Fprintf(w, "updatego_last_run_timestamp_seconds %d\n", m.Finished.Unix())
+	gauge("updatego_last_run_duration_seconds", "Duration of the last run.")
+	fmt.Fprintf(w, "updatego_last_run_duration_seconds %s\n", formatMetric(m.Duration.Seconds()))
+	gauge("updatego_last_run_download_bytes", "Bytes downloaded by the last run.")
+	fmt.Fprintf(w, "updatego_last_run_download_bytes %d\n", m.DownloadBytes)
+	gauge("updatego_last_run_success", "Whether the last run succeeded.")
+	fmt.Fprintf(w, "updatego_last_run_success %d\n", boolMetric(m.Err == nil))
+
+	fmt.Fprintf(w, "# HELP %s Failed runs by pipeline stage.\n# TYPE %s counter\n", metricFailures, metricFailures)
+	stages := slices.Clone(metricStages)
+	if m.Err != nil && !slices.Contains(stages, FailedStage(m.Err)) {
+		stages = append(stages, FailedStage(m.Err))
+	}
+	for _, stage := range stages {
+		sample := fmt.Sprintf("%s{stage=%q}", metricFailures, stage)
+		count := previous[sample]
+		if m.Err != nil && FailedStage(m.Err) == stage {
+			count++
+		}
+		fmt.Fprintf(w, "%s %s\n", sample, formatMetric(count))
+	}
+}
+
+// readMetrics reads the samples of a metrics file by name and labels, a
+// missing file has none
+func readMetrics(path string) (map[string]float64, error) {
+	samples := make(map[string]float64)
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return samples, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metrics: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		if i < 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			continue
+		}
+		samples[line[:i]] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read metrics: %w", err)
+	}
+
+	return samples, nil
+}
+
+func boolMetric(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func formatMetric(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
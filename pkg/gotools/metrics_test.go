@@ -0,0 +1,143 @@
+package gotools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "updatego.prom")
+	checked := time.Date(2025, 3, 4, 10, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		name    string
+		metrics RunMetrics
+		// want are lines the file must contain
+		want []string
+	}{
+		{
+			name: "update available",
+			metrics: RunMetrics{
+				Installed: "1.24.0",
+				Latest:    "1.24.1",
+				Checked:   checked,
+				Finished:  checked.Add(2 * time.Second),
+				Duration:  1500 * time.Millisecond,
+			},
+			want: []string{
+				`updatego_installed_version_info{version="1.24.0"} 1`,
+				`updatego_latest_version_info{version="1.24.1"} 1`,
+				`updatego_update_available 1`,
+				fmt.Sprintf(`updatego_last_check_timestamp_seconds %d`, checked.Unix()),
+				fmt.Sprintf(`updatego_last_run_timestamp_seconds %d`, checked.Add(2*time.Second).Unix()),
+				`updatego_last_run_duration_seconds 1.5`,
+				`updatego_last_run_download_bytes 0`,
+				`updatego_last_run_success 1`,
+				`updatego_failures_total{stage="download"} 0`,
+				`updatego_failures_total{stage="check"} 0`,
+			},
+		},
+		{
+			name: "download failed",
+			metrics: RunMetrics{
+				Installed:     "1.24.0",
+				Latest:        "1.24.1",
+				Checked:       checked.Add(time.Hour),
+				Finished:      checked.Add(time.Hour),
+				DownloadBytes: 1024,
+				Err:           fmt.Errorf("update failed: %w", &StageError{Stage: StageDownload, Err: errors.New("timeout")}),
+			},
+			want: []string{
+				`updatego_update_available 1`,
+				`updatego_last_run_download_bytes 1024`,
+				`updatego_last_run_success 0`,
+				`updatego_failures_total{stage="download"} 1`,
+			},
+		},
+		{
+			name: "check failed",
+			metrics: RunMetrics{
+				Installed: "1.24.0",
+				Finished:  checked.Add(2 * time.Hour),
+				Err:       &StageError{Stage: StageCheck, Err: errors.New("offline")},
+			},
+			want: []string{
+				`updatego_latest_version_info{version="1.24.1"} 1`,
+				`updatego_update_available 1`,
+				fmt.Sprintf(`updatego_last_check_timestamp_seconds %d`, checked.Add(time.Hour).Unix()),
+				`updatego_failures_total{stage="download"} 1`,
+				`updatego_failures_total{stage="check"} 1`,
+			},
+		},
+		{
+			name: "updated",
+			metrics: RunMetrics{
+				Installed: "1.24.1",
+				Latest:    "1.24.1",
+				Checked:   checked.Add(3 * time.Hour),
+				Finished:  checked.Add(3 * time.Hour),
+			},
+			want: []string{
+				`updatego_installed_version_info{version="1.24.1"} 1`,
+				`updatego_update_available 0`,
+				`updatego_last_run_success 1`,
+				`updatego_failures_total{stage="download"} 1`,
+				`updatego_failures_total{stage="check"} 1`,
+				`updatego_failures_total{stage="other"} 0`,
+			},
+		},
+	}
+
+	for _, step := range steps {
+		if err := WriteMetrics(path, step.metrics); err != nil {
+			t.Fatalf("%s: WriteMetrics() error = %v", step.name, err)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(string(content), "\n")
+		for _, want := range step.want {
+			found := false
+			for _, line := range lines {
+				found = found || line == want
+			}
+			if !found {
+				t.Errorf("%s: metrics lack %q:\n%s", step.name, want, content)
+			}
+		}
+	}
+
+	// Only the metrics file is left, the collector reads every *.prom file.
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 1 {
+		t.Errorf("metrics directory = %v, %v, want only updatego.prom", entries, err)
+	}
+}
+
+func TestFailedStage(t *testing.T) {
+	installer, _, _ := newMemInstaller(t)
+	updater := &Updater{
+		Checker:    NewChecker(),
+		Downloader: NewDownloader(),
+		Installer:  installer,
+		Hooks:      NewHooks(),
+	}
+	updater.Hooks.Add(HookBeforeDownload, Hook{Command: "exit 1"})
+
+	err := updater.Update(context.Background(), &Status{Installed: "1.23.0", Latest: "1.24.0"})
+	if stage := FailedStage(err); stage != StageHook {
+		t.Errorf("FailedStage(%v) = %q, want %q", err, stage, StageHook)
+	}
+
+	if stage := FailedStage(errors.New("usage")); stage != StageOther {
+		t.Errorf("FailedStage() of a plain error = %q, want %q", stage, StageOther)
+	}
+}
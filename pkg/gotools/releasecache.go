@@ -0,0 +1,156 @@
+package gotools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ReleaseCache is the cached release metadata of a Checker along with the
+// validators for conditional requests
+type ReleaseCache struct {
+	URL          string          `json:"url"`
+	Fetched      time.Time       `json:"fetched"`
+	ETag         string          `json:"etag,omitempty"`
+	LastModified string          `json:"lastModified,omitempty"`
+	Releases     json.RawMessage `json:"releases"`
+}
+
+// DefaultRevalidateTimeout bounds the revalidation of cached release metadata
+// unless a Checker sets RevalidateTimeout
+const DefaultRevalidateTimeout = 10 * time.Second
+
+// DefaultReleaseCachePath returns the release metadata cache in the user's
+// cache directory
+func DefaultReleaseCachePath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, "go-scripts", "releases.json"), nil
+}
+
+// LoadCache reads the cached release metadata of ReleasesURL
+func (c *Checker) LoadCache() (*ReleaseCache, error) {
+	return c.loadCache(c.ReleasesURL)
+}
+
+// loadCache reads the cached release metadata of url
+func (c *Checker) loadCache(url string) (*ReleaseCache, error) {
+	content, err := os.ReadFile(c.CachePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read release cache: %w", err)
+	}
+
+	var cache ReleaseCache
+	if err := json.Unmarshal(content, &cache); err != nil {
+		return nil, fmt.Errorf("failed to parse release cache: %w", err)
+	}
+	if cache.URL != url {
+		return nil, fmt.Errorf("release cache is for %s", cache.URL)
+	}
+
+	return &cache, nil
+}
+
+// saveCache writes the release metadata cache
+func (c *Checker) saveCache(cache *ReleaseCache) error {
+	content, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode release cache: %w", err)
+	}
+
+	return writeFileAtomic(c.CachePath, content, 0644)
+}
+
+// releases returns the release metadata. With a CachePath, a cached copy
+// younger than MaxAge is used as is, an older one is revalidated with a
+// conditional request and used with a warning if the server can't be reached.
+func (c *Checker) releases(ctx context.Context, url string) ([]GoRelease, error) {
+	logger := loggerOrDefault(c.Logger)
+
+	var cached *ReleaseCache
+	if c.CachePath != "" {
+		var err error
+		if cached, err = c.loadCache(url); err != nil {
+			logger.Debug("No usable release cache", "error", err)
+		}
+	}
+
+	if cached != nil && c.MaxAge > 0 {
+		if age := time.Since(cached.Fetched); age < c.MaxAge {
+			logger.Debug("Using cached release metadata", "age", age.Round(time.Second))
+			return parseReleases(cached.Releases)
+		}
+	}
+
+	logger.Debug("Fetching release metadata", "url", url)
+	fetchCtx, cancel := ctx, context.CancelFunc(func() {})
+	if cached != nil {
+		fetchCtx, cancel = c.revalidateContext(ctx)
+	}
+	releases, err := c.fetchReleases(fetchCtx, url, cached)
+	cancel()
+	if err != nil {
+		if cached == nil || ctx.Err() != nil {
+			return nil, err
+		}
+		logger.Warn("Failed to fetch release metadata, using cached copy",
+			"fetched", cached.Fetched.Format(time.DateTime), "age", time.Since(cached.Fetched).Round(time.Second), "error", err)
+		return parseReleases(cached.Releases)
+	}
+
+	return releases, nil
+}
+
+// revalidateContext bounds the revalidation of a cached copy to
+// RevalidateTimeout and half of the time left until the deadline of ctx. The
+// retries would otherwise use up the caller's time, leaving none to fall back
+// to the copy.
+func (c *Checker) revalidateContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := c.RevalidateTimeout
+	if timeout <= 0 {
+		timeout = DefaultRevalidateTimeout
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = min(timeout, time.Until(deadline)/2)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// fetchReleases fetches and parses the release metadata, conditional on
+// cached if it is set, and updates the cache
+func (c *Checker) fetchReleases(ctx context.Context, url string, cached *ReleaseCache) ([]GoRelease, error) {
+	response, err := c.getReleasesWithRetry(ctx, url, cached)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := &ReleaseCache{
+		URL:          url,
+		Fetched:      time.Now().UTC(),
+		ETag:         response.etag,
+		LastModified: response.lastModified,
+		Releases:     response.body,
+	}
+	if response.notModified {
+		loggerOrDefault(c.Logger).Debug("Release metadata not modified")
+		cache.ETag, cache.LastModified, cache.Releases = cached.ETag, cached.LastModified, cached.Releases
+	}
+
+	releases, err := parseReleases(cache.Releases)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.CachePath != "" {
+		if err := c.saveCache(cache); err != nil {
+			loggerOrDefault(c.Logger).Warn("Failed to cache release metadata", "error", err)
+		}
+	}
+
+	return releases, nil
+}
@@ -0,0 +1,187 @@
+package gotools
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// releaseServer serves release metadata with an ETag and Last-Modified and
+// answers matching conditional requests with 304 Not Modified
+type releaseServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	version  string
+	down     bool
+	requests []string
+}
+
+func newReleaseServer(t *testing.T, version string) *releaseServer {
+	t.Helper()
+
+	s := &releaseServer{version: version}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		etag := `"` + s.version + `"`
+		s.requests = append(s.requests, r.Header.Get("If-None-Match")+"|"+r.Header.Get("If-Modified-Since"))
+		switch {
+		case s.down:
+			http.Error(w, "maintenance", http.StatusServiceUnavailable)
+		case r.Header.Get("If-None-Match") == etag:
+			w.WriteHeader(http.StatusNotModified)
+		default:
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Last-Modified", "Mon, 06 Jan 2025 10:00:00 GMT")
+			w.Write([]byte(`[{"version": "` + s.version + `", "stable": true}]`))
+		}
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *releaseServer) set(version string, down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version, s.down = version, down
+}
+
+// takeRequests returns the conditional headers of the requests since the last call
+func (s *releaseServer) takeRequests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := s.requests
+	s.requests = nil
+	return requests
+}
+
+func TestCheckerCache(t *testing.T) {
+	server := newReleaseServer(t, "go1.24.0")
+	logs := &strings.Builder{}
+	checker := NewChecker()
+	checker.ReleasesURL = server.URL
+	checker.CachePath = filepath.Join(t.TempDir(), "releases.json")
+	checker.Retry = RetryPolicy{Interval: 10 * time.Millisecond, Timeout: 50 * time.Millisecond}
+	checker.Logger = slog.New(slog.NewTextHandler(logs, nil))
+
+	steps := []struct {
+		name    string
+		version string
+		down    bool
+		maxAge  time.Duration
+		want    string
+		// wantRequests are the conditional headers sent, "If-None-Match|If-Modified-Since"
+		wantRequests []string
+		wantWarning  bool
+	}{
+		{
+			name:         "first fetch",
+			version:      "go1.24.0",
+			want:         "1.24.0",
+			wantRequests: []string{"|"},
+		},
+		{
+			name:         "not modified",
+			version:      "go1.24.0",
+			want:         "1.24.0",
+			wantRequests: []string{`"go1.24.0"|Mon, 06 Jan 2025 10:00:00 GMT`},
+		},
+		{
+			name:    "fresh cache",
+			version: "go1.24.1",
+			maxAge:  time.Hour,
+			want:    "1.24.0",
+		},
+		{
+			name:         "modified",
+			version:      "go1.24.1",
+			want:         "1.24.1",
+			wantRequests: []string{`"go1.24.0"|Mon, 06 Jan 2025 10:00:00 GMT`},
+		},
+		{
+			name:        "server down",
+			version:     "go1.24.2",
+			down:        true,
+			want:        "1.24.1",
+			wantWarning: true,
+		},
+	}
+
+	for _, step := range steps {
+		server.set(step.version, step.down)
+		checker.MaxAge = step.maxAge
+		logs.Reset()
+
+		version, err := checker.GetLatestVersion(context.Background())
+		if err != nil {
+			t.Fatalf("%s: GetLatestVersion() error = %v", step.name, err)
+		}
+		if version != step.want {
+			t.Errorf("%s: GetLatestVersion() = %q, want %q", step.name, version, step.want)
+		}
+
+		requests := server.takeRequests()
+		if step.down {
+			if len(requests) == 0 {
+				t.Errorf("%s: server wasn't asked", step.name)
+			}
+		} else if strings.Join(requests, "\n") != strings.Join(step.wantRequests, "\n") {
+			t.Errorf("%s: requests = %q, want %q", step.name, requests, step.wantRequests)
+		}
+		if warned := strings.Contains(logs.String(), "using cached copy"); warned != step.wantWarning {
+			t.Errorf("%s: staleness warning = %v, want %v:\n%s", step.name, warned, step.wantWarning, logs)
+		}
+	}
+}
+
+func TestCheckerCacheMisses(t *testing.T) {
+	server := newReleaseServer(t, "go1.24.0")
+	cachePath := filepath.Join(t.TempDir(), "releases.json")
+
+	newChecker := func(url string) *Checker {
+		checker := NewChecker()
+		checker.ReleasesURL = url
+		checker.CachePath = cachePath
+		checker.MaxAge = time.Hour
+		checker.Retry = RetryPolicy{Interval: 10 * time.Millisecond, Timeout: 50 * time.Millisecond}
+		checker.Logger = slog.New(slog.NewTextHandler(&strings.Builder{}, nil))
+		return checker
+	}
+
+	// Offline without a cache fails.
+	server.set("go1.24.0", true)
+	if _, err := newChecker(server.URL).GetLatestVersion(context.Background()); err == nil {
+		t.Fatal("GetLatestVersion() without server and cache expected an error")
+	}
+
+	mirror := newReleaseServer(t, "go1.23.5")
+	if _, err := newChecker(mirror.URL).GetLatestVersion(context.Background()); err != nil {
+		t.Fatalf("GetLatestVersion() from mirror error = %v", err)
+	}
+
+	// The cache of another URL isn't used.
+	server.set("go1.24.0", false)
+	version, err := newChecker(server.URL).GetLatestVersion(context.Background())
+	if err != nil || version != "1.24.0" {
+		t.Errorf("GetLatestVersion() = %q, %v, want 1.24.0", version, err)
+	}
+
+	// Cancellation isn't hidden by the cache.
+	server.set("go1.24.0", true)
+	checker := newChecker(server.URL)
+	checker.MaxAge = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := checker.GetLatestVersion(ctx); err == nil {
+		t.Error("GetLatestVersion() with cancelled context expected an error")
+	}
+}
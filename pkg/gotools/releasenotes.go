@@ -0,0 +1,307 @@
+package gotools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ReleaseNote summarizes a single Go release from the release history
+type ReleaseNote struct {
+	// Version is the release version, e.g. "1.24.1"
+	Version string `json:"version"`
+	// Date is the release date as YYYY-MM-DD
+	Date string `json:"date"`
+	// Summary is the release history's description of the release
+	Summary string `json:"summary"`
+	// Security is set if the release includes security fixes
+	Security bool `json:"security"`
+	// Issues are the issues fixed by a patch release, nil if not fetched yet
+	Issues []ReleaseIssue `json:"issues"`
+}
+
+// ReleaseIssue is an issue fixed by a patch release
+type ReleaseIssue struct {
+	Number int      `json:"number"`
+	Title  string   `json:"title"`
+	URL    string   `json:"url"`
+	CVEs   []string `json:"cves,omitempty"`
+}
+
+// Patch reports whether the release is a patch release
+func (n *ReleaseNote) Patch() bool {
+	parts, err := parseVersion(n.Version)
+	return err == nil && parts[2] > 0
+}
+
+// NotesURL returns the link to the release notes of the minor release of n
+func (n *ReleaseNote) NotesURL() string {
+	parts, _ := parseVersion(n.Version)
+	return fmt.Sprintf("https://go.dev/doc/go%d.%d", parts[0], parts[1])
+}
+
+// MilestoneURL returns the link to the issues fixed in the release
+func (n *ReleaseNote) MilestoneURL() string {
+	return "https://github.com/golang/go/issues?q=" + url.QueryEscape(milestoneQuery(n.Version))
+}
+
+func milestoneQuery(version string) string {
+	return fmt.Sprintf("milestone:Go%s label:CherryPickApproved", version)
+}
+
+// ReleaseHistory is the cached release history
+type ReleaseHistory struct {
+	Fetched  time.Time     `json:"fetched"`
+	Releases []ReleaseNote `json:"releases"`
+}
+
+// ReleaseNotes looks up what changed between Go versions. The release history
+// is cached on disk, so it works offline once fetched.
+type ReleaseNotes struct {
+	historyURL string
+	issuesURL  string
+	client     *http.Client
+	// CachePath is the file the release history is cached in
+	CachePath string
+	// Logger receives progress messages
+	Logger *slog.Logger
+}
+
+// NewReleaseNotes creates release notes cached in the user's cache directory
+func NewReleaseNotes() (*ReleaseNotes, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine cache directory: %w", err)
+	}
+
+	return &ReleaseNotes{
+		historyURL: "https://go.dev/doc/devel/release",
+		issuesURL:  "https://api.github.com/search/issues",
+		client:     NewHTTPClient(),
+		CachePath:  filepath.Join(cacheDir, "go-scripts", "release-history.json"),
+		Logger:     slog.Default(),
+	}, nil
+}
+
+// SetHTTPClient makes the release notes use client, e.g. one from NewHTTPClientWithOptions
+func (r *ReleaseNotes) SetHTTPClient(client *http.Client) {
+	r.client = client
+}
+
+// Load reads the cached release history
+func (r *ReleaseNotes) Load() (*ReleaseHistory, error) {
+	content, err := os.ReadFile(r.CachePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read release history cache: %w", err)
+	}
+
+	var history ReleaseHistory
+	if err := json.Unmarshal(content, &history); err != nil {
+		return nil, fmt.Errorf("failed to parse release history cache: %w", err)
+	}
+
+	return &history, nil
+}
+
+// Save writes the release history to the cache
+func (r *ReleaseNotes) Save(history *ReleaseHistory) error {
+	content, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode release history: %w", err)
+	}
+
+	return writeFileAtomic(r.CachePath, content, 0644)
+}
+
+// Refresh fetches the release history, keeping already fetched issues, and
+// updates the cache.
+func (r *ReleaseNotes) Refresh(ctx context.Context) (*ReleaseHistory, error) {
+	loggerOrDefault(r.Logger).Info("Fetching Go release history", "url", r.historyURL)
+
+	page, err := fetch(ctx, r.client, r.historyURL, r.Logger)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &ReleaseHistory{
+		Fetched:  time.Now().UTC(),
+		Releases: parseReleaseHistory(string(page)),
+	}
+	if len(history.Releases) == 0 {
+		return nil, fmt.Errorf("no releases found in %s", r.historyURL)
+	}
+
+	if cached, err := r.Load(); err == nil {
+		for i := range history.Releases {
+			if old := cached.find(history.Releases[i].Version); old != nil {
+				history.Releases[i].Issues = old.Issues
+			}
+		}
+	}
+
+	if err := r.Save(history); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// Changes returns the releases after from up to and including to, oldest
+// first. The cached history is refreshed if it is missing, doesn't know to
+// yet or refresh is set. Fixed issues of patch releases are fetched on a best
+// effort basis and cached as well.
+func (r *ReleaseNotes) Changes(ctx context.Context, from, to string, refresh bool) ([]ReleaseNote, error) {
+	history, err := r.Load()
+	if err != nil || refresh || history.find(to) == nil {
+		if err != nil {
+			loggerOrDefault(r.Logger).Debug("No usable release history cache", "error", err)
+		}
+
+		refreshed, refreshErr := r.Refresh(ctx)
+		if refreshErr != nil {
+			if history == nil {
+				return nil, refreshErr
+			}
+			loggerOrDefault(r.Logger).Warn("Failed to refresh release history, using cached copy",
+				"fetched", history.Fetched.Format(time.DateOnly), "error", refreshErr)
+		} else {
+			history = refreshed
+		}
+	}
+
+	var changes []ReleaseNote
+	// Don't retry every release once fetching issues failed, e.g. when offline.
+	fetchIssues := true
+	for i := range history.Releases {
+		note := &history.Releases[i]
+
+		afterFrom, err := compareVersions(note.Version, from)
+		if err != nil {
+			return nil, err
+		}
+		untilTo, err := compareVersions(note.Version, to)
+		if err != nil {
+			return nil, err
+		}
+		if afterFrom <= 0 || untilTo > 0 {
+			continue
+		}
+
+		if fetchIssues && note.Patch() && note.Issues == nil {
+			issues, err := r.fetchIssues(ctx, note.Version)
+			if err != nil {
+				loggerOrDefault(r.Logger).Warn("Failed to fetch fixed issues", "version", note.Version, "error", err)
+				fetchIssues = false
+			} else {
+				note.Issues = issues
+			}
+		}
+
+		changes = append(changes, *note)
+	}
+
+	if err := r.Save(history); err != nil {
+		loggerOrDefault(r.Logger).Warn("Failed to cache fixed issues", "error", err)
+	}
+
+	slices.SortFunc(changes, func(a, b ReleaseNote) int {
+		c, _ := compareVersions(a.Version, b.Version)
+		return c
+	})
+
+	return changes, nil
+}
+
+// fetchIssues fetches the issues fixed in a patch release from its milestone
+func (r *ReleaseNotes) fetchIssues(ctx context.Context, version string) ([]ReleaseIssue, error) {
+	query := url.Values{
+		"q":        {"repo:golang/go is:closed " + milestoneQuery(version)},
+		"per_page": {"100"},
+	}
+
+	body, err := fetch(ctx, r.client, r.issuesURL+"?"+query.Encode(), r.Logger)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Items []struct {
+			Number  int    `json:"number"`
+			Title   string `json:"title"`
+			HTMLURL string `json:"html_url"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse issues: %w", err)
+	}
+
+	issues := make([]ReleaseIssue, 0, len(result.Items))
+	for _, item := range result.Items {
+		issues = append(issues, ReleaseIssue{
+			Number: item.Number,
+			Title:  item.Title,
+			URL:    item.HTMLURL,
+			CVEs:   cvePattern.FindAllString(item.Title, -1),
+		})
+	}
+	slices.SortFunc(issues, func(a, b ReleaseIssue) int { return a.Number - b.Number })
+
+	return issues, nil
+}
+
+func (h *ReleaseHistory) find(version string) *ReleaseNote {
+	for i := range h.Releases {
+		if c, err := compareVersions(h.Releases[i].Version, version); err == nil && c == 0 {
+			return &h.Releases[i]
+		}
+	}
+	return nil
+}
+
+var (
+	// blockPattern matches the paragraphs and headings of the release history page
+	blockPattern = regexp.MustCompile(`(?s)<(p|h2)\b[^>]*>(.*?)</(?:p|h2)>`)
+	tagPattern   = regexp.MustCompile(`<[^>]*>`)
+	// releasedPattern matches the start of a release entry, e.g. "go1.24.1 (released 2025-03-04)"
+	releasedPattern = regexp.MustCompile(`^go(\d+\.\d+(?:\.\d+)?) \(released (\d{4}-\d{2}-\d{2})\)\s*`)
+	cvePattern      = regexp.MustCompile(`CVE-\d{4}-\d{4,}`)
+)
+
+// parseReleaseHistory extracts the releases from the go.dev/doc/devel/release page.
+// Minor releases are headings followed by a paragraph, patch releases are a
+// single paragraph starting with the version.
+func parseReleaseHistory(page string) []ReleaseNote {
+	var releases []ReleaseNote
+
+	for _, block := range blockPattern.FindAllStringSubmatch(page, -1) {
+		text := strings.Join(strings.Fields(html.UnescapeString(tagPattern.ReplaceAllString(block[2], ""))), " ")
+
+		if m := releasedPattern.FindStringSubmatch(text); m != nil {
+			summary := strings.TrimSpace(text[len(m[0]):])
+			releases = append(releases, ReleaseNote{
+				Version:  m[1],
+				Date:     m[2],
+				Summary:  summary,
+				Security: strings.Contains(summary, "security fix"),
+			})
+			continue
+		}
+
+		// The paragraph following a minor release heading describes it.
+		if last := len(releases) - 1; last >= 0 && releases[last].Summary == "" && block[1] == "p" {
+			releases[last].Summary = text
+		}
+	}
+
+	return releases
+}
@@ -0,0 +1,163 @@
+package gotools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testReleaseHistoryPage mimics the structure of go.dev/doc/devel/release
+const testReleaseHistoryPage = `<html><body>
+<h2 id="go1.24">go1.24.0 (released 2025-02-11)</h2>
+<p>
+Go 1.24.0 is a major release of Go.
+Read the <a href="/doc/go1.24">Go 1.24 Release Notes</a> for more information.
+</p>
+<h3 id="go1.24.minor">Minor revisions</h3>
+<p id="go1.24.1">
+go1.24.1 (released 2025-03-04) includes security fixes to the <code>net/http</code> package,
+as well as bug fixes to cgo, the compiler and the <code>go</code> command.
+See the <a href="https://github.com/golang/go/issues?q=milestone%3AGo1.24.1+label%3ACherryPickApproved">Go 1.24.1 milestone</a> on our issue tracker for details.
+</p>
+<p id="go1.24.2">
+go1.24.2 (released 2025-04-01) includes fixes to the compiler &amp; the runtime.
+</p>
+<h2 id="go1.23">go1.23.0 (released 2024-08-13)</h2>
+<p>
+Go 1.23.0 is a major release of Go.
+</p>
+<p id="go1.23.6">
+go1.23.6 (released 2025-02-04) includes security fixes to the <code>crypto/elliptic</code> package.
+</p>
+<h2 id="go1.20">go1.20 (released 2023-02-01)</h2>
+<p>Go 1.20 is a major release of Go.</p>
+</body></html>`
+
+func TestParseReleaseHistory(t *testing.T) {
+	releases := parseReleaseHistory(testReleaseHistoryPage)
+
+	versions := make([]string, 0, len(releases))
+	for _, release := range releases {
+		versions = append(versions, release.Version)
+	}
+	if want := []string{"1.24.0", "1.24.1", "1.24.2", "1.23.0", "1.23.6", "1.20"}; !slices.Equal(versions, want) {
+		t.Fatalf("versions = %v, want %v", versions, want)
+	}
+
+	minor := releases[0]
+	if minor.Date != "2025-02-11" || minor.Patch() || minor.NotesURL() != "https://go.dev/doc/go1.24" {
+		t.Errorf("unexpected minor release %+v", minor)
+	}
+	if minor.Summary != "Go 1.24.0 is a major release of Go. Read the Go 1.24 Release Notes for more information." {
+		t.Errorf("minor release summary = %q", minor.Summary)
+	}
+
+	patch := releases[1]
+	if !patch.Patch() || !patch.Security {
+		t.Errorf("go1.24.1 should be a security patch release: %+v", patch)
+	}
+	if releases[2].Security {
+		t.Errorf("go1.24.2 has no security fixes: %+v", releases[2])
+	}
+	if releases[2].Summary != "includes fixes to the compiler & the runtime." {
+		t.Errorf("go1.24.2 summary = %q", releases[2].Summary)
+	}
+}
+
+func TestReleaseNotesChanges(t *testing.T) {
+	online := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !online {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/history":
+			w.Write([]byte(testReleaseHistoryPage))
+		case "/issues":
+			if r.URL.Query().Get("q") != "repo:golang/go is:closed milestone:Go1.24.1 label:CherryPickApproved" {
+				w.Write([]byte(`{"items": []}`))
+				return
+			}
+			w.Write([]byte(`{"items": [
+				{"number": 71985, "title": "net/http: request smuggling [CVE-2025-22871] [1.24 backport]", "html_url": "https://github.com/golang/go/issues/71985"},
+				{"number": 71900, "title": "cmd/compile: internal compiler error [1.24 backport]", "html_url": "https://github.com/golang/go/issues/71900"}
+			]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	notes := &ReleaseNotes{
+		historyURL: server.URL + "/history",
+		issuesURL:  server.URL + "/issues",
+		client:     server.Client(),
+		CachePath:  filepath.Join(t.TempDir(), "release-history.json"),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	changes, err := notes.Changes(ctx, "1.23.6", "1.24.1", false)
+	if err != nil {
+		t.Fatalf("Changes() error = %v", err)
+	}
+
+	versions := make([]string, 0, len(changes))
+	for _, change := range changes {
+		versions = append(versions, change.Version)
+	}
+	if want := []string{"1.24.0", "1.24.1"}; !slices.Equal(versions, want) {
+		t.Fatalf("Changes() versions = %v, want %v", versions, want)
+	}
+
+	issues := changes[1].Issues
+	if len(issues) != 2 || issues[0].Number != 71900 {
+		t.Fatalf("go1.24.1 issues = %+v", issues)
+	}
+	if !slices.Equal(issues[1].CVEs, []string{"CVE-2025-22871"}) {
+		t.Errorf("go1.24.1 CVEs = %v, want CVE-2025-22871", issues[1].CVEs)
+	}
+
+	// Offline, the cached history and issues are used.
+	online = false
+	changes, err = notes.Changes(ctx, "1.24.0", "1.24.2", false)
+	if err != nil {
+		t.Fatalf("Changes() offline error = %v", err)
+	}
+	if len(changes) != 2 || len(changes[0].Issues) != 2 {
+		t.Errorf("Changes() offline = %+v, want cached go1.24.1 and go1.24.2", changes)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "1.24.1", b: "1.24.1", want: 0},
+		{a: "1.20", b: "1.20.0", want: 0},
+		{a: "1.24.0", b: "1.23.12", want: 1},
+		{a: "1.9.1", b: "1.10", want: -1},
+	}
+
+	for _, tt := range tests {
+		have, err := compareVersions(tt.a, tt.b)
+		if err != nil {
+			t.Fatalf("compareVersions(%q, %q) error = %v", tt.a, tt.b, err)
+		}
+		if have != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, have, tt.want)
+		}
+	}
+
+	if _, err := compareVersions("1.25rc1", "1.24"); err == nil {
+		t.Error("compareVersions() expected error for prerelease version")
+	}
+}
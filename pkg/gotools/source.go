@@ -0,0 +1,253 @@
+package gotools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SourceBuild describes a Go toolchain to build from source
+type SourceBuild struct {
+	// Source is a source archive like go1.24.1.src.tar.gz or a checkout of
+	// the Go repository
+	Source string
+	// Name is the version the toolchain is installed as, e.g. "go1.24-custom"
+	Name string
+	// Bootstrap is the toolchain used as GOROOT_BOOTSTRAP, empty uses the Go
+	// installation in InstallDir. It must be absolute, make.bash runs in the
+	// src directory of the staged tree.
+	Bootstrap string
+}
+
+// sourceNamePattern matches the names of source builds. They need the "go"
+// prefix of a VERSION file and must not collide with the managed "go".
+var sourceNamePattern = regexp.MustCompile(`^go[0-9][0-9A-Za-z.+_-]*$`)
+
+// SourceRoot returns the directory the source build called name is installed to
+func (i *Installer) SourceRoot(name string) string {
+	return filepath.Join(i.InstallDir, name)
+}
+
+// InstallSource builds Go from source with make.bash and installs it side by
+// side with the managed installation: the tree goes to SourceRoot(build.Name)
+// and BinDir/<name> links to its go binary. The managed installation and an
+// existing build of the same name are only touched once the build succeeded.
+// It returns the root of the installed toolchain.
+func (i *Installer) InstallSource(ctx context.Context, build SourceBuild) (string, error) {
+	logger := loggerOrDefault(i.Logger)
+	fsys := i.filesystem()
+
+	if !sourceNamePattern.MatchString(build.Name) {
+		return "", fmt.Errorf("invalid version name %q, expected something like go1.24-custom", build.Name)
+	}
+
+	bootstrap := build.Bootstrap
+	if bootstrap == "" {
+		bootstrap = filepath.Join(i.InstallDir, "go")
+	} else if !filepath.IsAbs(bootstrap) {
+		return "", fmt.Errorf("bootstrap toolchain %q must be an absolute path", bootstrap)
+	}
+	if _, err := fsys.Stat(filepath.Join(bootstrap, "bin", "go")); err != nil {
+		return "", fmt.Errorf("no bootstrap toolchain in %s: %w", bootstrap, err)
+	}
+
+	if err := i.ensureDirectories(); err != nil {
+		return "", fmt.Errorf("failed to create installation directories: %w", err)
+	}
+
+	start := i.now()
+	staging := filepath.Join(i.InstallDir, "."+build.Name+".build")
+	if err := fsys.RemoveAll(staging); err != nil {
+		return "", fmt.Errorf("failed to remove previous build directory: %w", err)
+	}
+	defer fsys.RemoveAll(staging)
+
+	logger.Info("Preparing Go source", "source", build.Source, "name", build.Name)
+	goroot := filepath.Join(staging, "go")
+	if err := i.stageSource(ctx, build.Source, staging); err != nil {
+		return "", fmt.Errorf("failed to prepare source: %w", err)
+	}
+
+	// make.bash takes the version from the VERSION file instead of asking git.
+	if err := writeVersionFile(fsys, goroot, build.Name); err != nil {
+		return "", err
+	}
+
+	logger.Info("Building Go from source, this takes a few minutes", "bootstrap", bootstrap)
+	env := []string{"GOROOT_BOOTSTRAP=" + bootstrap, "GOROOT=", "GOTOOLCHAIN=local"}
+	src := filepath.Join(goroot, "src")
+	output, err := i.commandRunner().Run(ctx, src, env, filepath.Join(src, "make.bash"))
+	if err != nil {
+		return "", fmt.Errorf("make.bash failed: %s: %w", lastLines(output, 20), err)
+	}
+	logger.Debug("Built Go", "output", strings.TrimSpace(string(output)))
+
+	// The go command finds its GOROOT relative to the binary, so the tree
+	// can be verified here and moved afterwards.
+	output, err = i.commandRunner().Run(ctx, "", nil, filepath.Join(goroot, "bin", "go"), "version")
+	if err != nil {
+		return "", fmt.Errorf("built toolchain doesn't work: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+
+	root := i.SourceRoot(build.Name)
+	if err := fsys.RemoveAll(root); err != nil {
+		return "", fmt.Errorf("failed to remove existing %s: %w", build.Name, err)
+	}
+	if err := fsys.Rename(goroot, root); err != nil {
+		return "", fmt.Errorf("failed to install %s: %w", build.Name, err)
+	}
+
+	link := filepath.Join(i.BinDir, build.Name)
+	if _, err := fsys.Lstat(link); err == nil {
+		if err := fsys.Remove(link); err != nil {
+			return "", fmt.Errorf("failed to remove existing %s symlink: %w", build.Name, err)
+		}
+	}
+	if err := fsys.Symlink(filepath.Join(root, "bin", "go"), link); err != nil {
+		return "", fmt.Errorf("failed to create symlink for %s: %w", build.Name, err)
+	}
+
+	logger.Info("Installed Go from source", "name", build.Name, "root", root, "duration", i.now().Sub(start))
+	return root, nil
+}
+
+// stageSource puts the Go tree of source, an archive or a checkout, into
+// staging/go
+func (i *Installer) stageSource(ctx context.Context, source, staging string) error {
+	fsys := i.filesystem()
+	goroot := filepath.Join(staging, "go")
+
+	info, err := fsys.Stat(source)
+	if err != nil {
+		return fmt.Errorf("failed to stat source: %w", err)
+	}
+
+	if info.IsDir() {
+		if err := copyTree(ctx, fsys, source, goroot); err != nil {
+			return err
+		}
+	} else {
+		e := i.newExtractor(staging, nil)
+		if err := e.extractFile(ctx, source); err != nil {
+			return fmt.Errorf("extraction failed: %w", err)
+		}
+	}
+
+	if _, err := fsys.Stat(filepath.Join(goroot, "src", "make.bash")); err != nil {
+		return fmt.Errorf("%s is not a Go source tree, src/make.bash is missing", source)
+	}
+
+	return nil
+}
+
+// copyTree copies the regular files, directories and symlinks below src to
+// dst, leaving out the .git directory
+func copyTree(ctx context.Context, fsys FileSystem, src, dst string) error {
+	return fsys.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("copy cancelled: %w", err)
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if d.Name() == ".git" {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			// The owner needs write access for make.bash.
+			if err := fsys.MkdirAll(target, info.Mode().Perm()|0700); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", target, err)
+			}
+		case d.Type()&fs.ModeSymlink != 0:
+			linkname, err := fsys.Readlink(path)
+			if err != nil {
+				return fmt.Errorf("failed to read symlink %s: %w", path, err)
+			}
+			if err := fsys.Symlink(linkname, target); err != nil {
+				return fmt.Errorf("failed to create symlink %s: %w", target, err)
+			}
+		case d.Type().IsRegular():
+			if err := copyFile(fsys, path, target, info.Mode().Perm()); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// copyFile copies the regular file src to dst with mode perm
+func copyFile(fsys FileSystem, src, dst string, perm fs.FileMode) error {
+	in, err := fsys.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer in.Close()
+
+	out, err := fsys.Create(dst, perm)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", dst, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write file %s: %w", dst, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to close file %s: %w", dst, err)
+	}
+
+	return fsys.Chmod(dst, perm)
+}
+
+// writeVersionFile replaces the VERSION file of the Go tree at goroot
+func writeVersionFile(fsys FileSystem, goroot, version string) error {
+	path := filepath.Join(goroot, "VERSION")
+	// A checkout's VERSION may be read-only, so it is replaced instead of written through.
+	if err := fsys.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to replace VERSION file: %w", err)
+	}
+
+	file, err := fsys.Create(path, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create VERSION file: %w", err)
+	}
+	if _, err := io.WriteString(file, version+"\n"); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write VERSION file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write VERSION file: %w", err)
+	}
+
+	return nil
+}
+
+// lastLines returns the last n lines of output, the interesting part of a failed build
+func lastLines(output []byte, n int) string {
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,234 @@
+package gotools
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testGoSource is a minimal Go source tree
+var testGoSource = []tarEntry{
+	{Name: "go/", Type: tar.TypeDir},
+	{Name: "go/VERSION", Content: "go1.24.1\n"},
+	{Name: "go/src/make.bash", Mode: 0755, Content: "#!/usr/bin/env bash\n"},
+	{Name: "go/src/fmt/print.go", Content: "package fmt\n"},
+	{Name: "go/misc/link", Type: tar.TypeSymlink, Linkname: "../src/fmt/print.go"},
+}
+
+// writeTestCheckout writes testGoSource as a git checkout to /src/go
+func writeTestCheckout(t *testing.T, mem *memFS) {
+	t.Helper()
+
+	for _, entry := range testGoSource {
+		path := "/src/" + entry.Name
+		switch entry.Type {
+		case tar.TypeDir:
+			if err := mem.MkdirAll(path, 0755); err != nil {
+				t.Fatal(err)
+			}
+		case tar.TypeSymlink:
+			if err := mem.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := mem.Symlink(entry.Linkname, path); err != nil {
+				t.Fatal(err)
+			}
+		default:
+			mode := fs.FileMode(entry.Mode)
+			if mode == 0 {
+				mode = 0644
+			}
+			mem.writeFile(t, path, []byte(entry.Content), mode)
+		}
+	}
+	mem.writeFile(t, "/src/go/.git/HEAD", []byte("ref: refs/heads/master\n"), 0644)
+}
+
+func TestInstallSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		setup  func(t *testing.T, mem *memFS)
+	}{
+		{
+			name:   "checkout",
+			source: "/src/go",
+			setup:  writeTestCheckout,
+		},
+		{
+			name:   "archive",
+			source: "/tmp/go1.24.1.src.tar.gz",
+			setup: func(t *testing.T, mem *memFS) {
+				mem.writeFile(t, "/tmp/go1.24.1.src.tar.gz", gzipTestTar(t, testGoSource), 0644)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			installer, mem, goBinary := newMemInstaller(t)
+			tt.setup(t, mem)
+
+			root, err := installer.InstallSource(context.Background(), SourceBuild{Source: tt.source, Name: "go1.24-custom"})
+			if err != nil {
+				t.Fatalf("InstallSource() error = %v", err)
+			}
+
+			if root != "/home/gopher/.local/lib/go1.24-custom" {
+				t.Errorf("InstallSource() root = %q", root)
+			}
+			if content := mem.readFile(t, root+"/VERSION"); content != "go1.24-custom\n" {
+				t.Errorf("VERSION = %q", content)
+			}
+			if content := mem.readFile(t, root+"/misc/link"); content != "package fmt\n" {
+				t.Errorf("content through copied symlink = %q", content)
+			}
+			if _, err := mem.Lstat(root + "/.git"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf(".git was copied: %v", err)
+			}
+			if _, err := mem.Lstat("/home/gopher/.local/lib/.go1.24-custom.build"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("build directory was left behind: %v", err)
+			}
+			if target, err := mem.Readlink("/home/gopher/.local/bin/go1.24-custom"); err != nil || target != root+"/bin/go" {
+				t.Errorf("symlink = %q, %v", target, err)
+			}
+			if content := mem.readFile(t, "/home/gopher/.local/lib/go/VERSION"); content != "go1.23.0\n" {
+				t.Errorf("managed installation changed, VERSION = %q", content)
+			}
+
+			build := "/home/gopher/.local/lib/.go1.24-custom.build/go/src"
+			want := []string{
+				build + " " + build + "/make.bash",
+				"/home/gopher/.local/lib/.go1.24-custom.build/go/bin/go version",
+			}
+			if !slices.Equal(goBinary.calls, want) {
+				t.Errorf("commands = %q, want %q", goBinary.calls, want)
+			}
+			if !slices.Contains(goBinary.envs[0], "GOROOT_BOOTSTRAP=/home/gopher/.local/lib/go") {
+				t.Errorf("make.bash env = %q", goBinary.envs[0])
+			}
+
+			output, err := installer.commandRunner().Run(context.Background(), "", nil, "/home/gopher/.local/bin/go1.24-custom", "version")
+			if err != nil || string(output) != "go version go1.24-custom linux/amd64\n" {
+				t.Errorf("go1.24-custom version = %q, %v", output, err)
+			}
+		})
+	}
+}
+
+func TestInstallSourceFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		build   SourceBuild
+		setup   func(installer *Installer, mem *memFS, goBinary *fakeGo)
+		wantErr string
+	}{
+		{
+			name:    "name of the managed installation",
+			build:   SourceBuild{Source: "/src/go", Name: "go"},
+			wantErr: "invalid version name",
+		},
+		{
+			name:    "name with a path",
+			build:   SourceBuild{Source: "/src/go", Name: "go1/../../etc"},
+			wantErr: "invalid version name",
+		},
+		{
+			name:    "missing bootstrap",
+			build:   SourceBuild{Source: "/src/go", Name: "go1.24-custom", Bootstrap: "/opt/go"},
+			wantErr: "no bootstrap toolchain in /opt/go",
+		},
+		{
+			name:    "relative bootstrap",
+			build:   SourceBuild{Source: "/src/go", Name: "go1.24-custom", Bootstrap: "go1.22"},
+			wantErr: "must be an absolute path",
+		},
+		{
+			name:    "missing source",
+			build:   SourceBuild{Source: "/src/missing", Name: "go1.24-custom"},
+			wantErr: "failed to stat source",
+		},
+		{
+			name:  "not a source tree",
+			build: SourceBuild{Source: "/src/go", Name: "go1.24-custom"},
+			setup: func(_ *Installer, mem *memFS, _ *fakeGo) {
+				mem.Remove("/src/go/src/make.bash")
+			},
+			wantErr: "src/make.bash is missing",
+		},
+		{
+			name:  "build fails",
+			build: SourceBuild{Source: "/src/go", Name: "go1.24-custom"},
+			setup: func(_ *Installer, _ *memFS, goBinary *fakeGo) {
+				goBinary.err = errors.New("exit status 2")
+			},
+			wantErr: "make.bash failed: go: broken: exit status 2",
+		},
+		{
+			name:  "symlink not creatable",
+			build: SourceBuild{Source: "/src/go", Name: "go1.24-custom"},
+			setup: func(_ *Installer, mem *memFS, _ *fakeGo) {
+				mem.failOn("symlink", "/home/gopher/.local/bin/go1.24-custom")
+			},
+			wantErr: "failed to create symlink for go1.24-custom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			installer, mem, goBinary := newMemInstaller(t)
+			writeTestCheckout(t, mem)
+			mem.writeFile(t, "/home/gopher/.local/lib/go1.24-custom/VERSION", []byte("go1.24-custom\n"), 0644)
+			if tt.setup != nil {
+				tt.setup(installer, mem, goBinary)
+			}
+
+			_, err := installer.InstallSource(context.Background(), tt.build)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("InstallSource() error = %v, want %q", err, tt.wantErr)
+			}
+			if _, err := mem.Lstat("/home/gopher/.local/lib/.go1.24-custom.build"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("build directory was left behind: %v", err)
+			}
+		})
+	}
+}
+
+func TestInstallSourceKeepsBuildOnFailure(t *testing.T) {
+	installer, mem, goBinary := newMemInstaller(t)
+	writeTestCheckout(t, mem)
+	mem.writeFile(t, "/home/gopher/.local/lib/go1.24-custom/VERSION", []byte("go1.24-custom\n"), 0644)
+	mem.writeFile(t, "/home/gopher/.local/lib/go1.24-custom/old.go", nil, 0644)
+	goBinary.err = errors.New("exit status 2")
+
+	if _, err := installer.InstallSource(context.Background(), SourceBuild{Source: "/src/go", Name: "go1.24-custom"}); err == nil {
+		t.Fatal("InstallSource() expected an error")
+	}
+	if _, err := mem.Lstat("/home/gopher/.local/lib/go1.24-custom/old.go"); err != nil {
+		t.Errorf("existing build was removed: %v", err)
+	}
+}
+
+func TestInstallSourceCancelled(t *testing.T) {
+	installer, mem, goBinary := newMemInstaller(t)
+	writeTestCheckout(t, mem)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := installer.InstallSource(ctx, SourceBuild{Source: "/src/go", Name: "go1.24-custom"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("InstallSource() error = %v, want context.Canceled", err)
+	}
+	if len(goBinary.calls) != 0 {
+		t.Errorf("commands ran after cancellation: %q", goBinary.calls)
+	}
+	if _, err := mem.Lstat("/home/gopher/.local/lib/go1.24-custom"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("cancelled build was installed: %v", err)
+	}
+}
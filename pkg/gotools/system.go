@@ -0,0 +1,130 @@
+package gotools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// FileSystem is the filesystem an Installer works on. The methods behave like
+// the functions of the same name in the os and path/filepath packages.
+type FileSystem interface {
+	Open(name string) (io.ReadCloser, error)
+	// Create truncates or creates name for writing with perm before the umask
+	Create(name string, perm fs.FileMode) (io.WriteCloser, error)
+	MkdirAll(path string, perm fs.FileMode) error
+	Remove(name string) error
+	Rename(oldpath, newpath string) error
+	RemoveAll(path string) error
+	Stat(name string) (fs.FileInfo, error)
+	Lstat(name string) (fs.FileInfo, error)
+	Symlink(oldname, newname string) error
+	Link(oldname, newname string) error
+	Readlink(name string) (string, error)
+	Chmod(name string, mode fs.FileMode) error
+	Chtimes(name string, atime, mtime time.Time) error
+	WalkDir(root string, fn fs.WalkDirFunc) error
+}
+
+// CommandRunner runs external commands
+type CommandRunner interface {
+	// Run runs name with args in dir, or the current directory if dir is
+	// empty, with extra environment variables and returns its combined output
+	Run(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error)
+}
+
+// Clock tells the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// OSFileSystem is the FileSystem of the host
+type OSFileSystem struct{}
+
+var _ FileSystem = OSFileSystem{}
+
+func (OSFileSystem) Open(name string) (io.ReadCloser, error) { return os.Open(name) }
+
+func (OSFileSystem) Create(name string, perm fs.FileMode) (io.WriteCloser, error) {
+	return os.OpenFile(name, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+}
+
+func (OSFileSystem) MkdirAll(path string, perm fs.FileMode) error { return os.MkdirAll(path, perm) }
+func (OSFileSystem) Remove(name string) error                     { return os.Remove(name) }
+func (OSFileSystem) Rename(oldpath, newpath string) error         { return os.Rename(oldpath, newpath) }
+func (OSFileSystem) RemoveAll(path string) error                  { return os.RemoveAll(path) }
+func (OSFileSystem) Stat(name string) (fs.FileInfo, error)        { return os.Stat(name) }
+func (OSFileSystem) Lstat(name string) (fs.FileInfo, error)       { return os.Lstat(name) }
+func (OSFileSystem) Symlink(oldname, newname string) error        { return os.Symlink(oldname, newname) }
+func (OSFileSystem) Link(oldname, newname string) error           { return os.Link(oldname, newname) }
+func (OSFileSystem) Readlink(name string) (string, error)         { return os.Readlink(name) }
+func (OSFileSystem) Chmod(name string, mode fs.FileMode) error    { return os.Chmod(name, mode) }
+
+func (OSFileSystem) Chtimes(name string, atime, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+
+func (OSFileSystem) WalkDir(root string, fn fs.WalkDirFunc) error {
+	return filepath.WalkDir(root, fn)
+}
+
+// ExecRunner is the CommandRunner of the host
+type ExecRunner struct{}
+
+// commandWaitDelay is how long a cancelled command gets to exit and close its
+// output before Run gives up on it
+const commandWaitDelay = 5 * time.Second
+
+// Run runs the command and captures its output. Cancelling ctx kills the
+// command with all processes it started, e.g. the compilers of make.bash.
+func (ExecRunner) Run(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	// The command gets its own process group, so that killing it doesn't
+	// leave children behind that keep the output open.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = commandWaitDelay
+
+	err := cmd.Run()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return output.Bytes(), fmt.Errorf("command cancelled: %w", ctxErr)
+	}
+	if err != nil {
+		return output.Bytes(), fmt.Errorf("command failed: %w", err)
+	}
+
+	return output.Bytes(), nil
+}
+
+// SystemClock is the Clock of the host
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time { return time.Now() }
+
+// fileChecksum returns the hex encoded SHA256 checksum of a file in fsys
+func fileChecksum(fsys FileSystem, path string) (string, error) {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file for checksum calculation: %w", err)
+	}
+	defer file.Close()
+
+	return readerChecksum(file)
+}
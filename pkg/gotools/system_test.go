@@ -0,0 +1,720 @@
+package gotools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memFS is an in-memory FileSystem. Paths are absolute, hardlinks share their
+// node and permissions are recorded but not enforced.
+type memFS struct {
+	mu    sync.Mutex
+	nodes map[string]*memNode
+	// failures makes an operation fail, keyed by "op path", e.g. "symlink /bin/go"
+	failures map[string]error
+}
+
+type memNode struct {
+	mode    fs.FileMode
+	data    []byte
+	target  string
+	modTime time.Time
+}
+
+func newMemFS() *memFS {
+	return &memFS{
+		nodes:    map[string]*memNode{"/": {mode: fs.ModeDir | 0755}},
+		failures: make(map[string]error),
+	}
+}
+
+var _ FileSystem = (*memFS)(nil)
+
+// failOn makes op on path fail with an error wrapping errInjected
+func (m *memFS) failOn(op, path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures[op+" "+path] = fmt.Errorf("%s %s: %w", op, path, errInjected)
+}
+
+var errInjected = errors.New("injected failure")
+
+// check returns the injected failure of op on path, if any
+func (m *memFS) check(op, path string) error {
+	return m.failures[op+" "+filepath.Clean(path)]
+}
+
+// resolve returns the path of name with all symlinks in its parents, and with
+// follow also in its last component, resolved
+func (m *memFS) resolve(op, name string, follow bool) (string, error) {
+	name = filepath.Clean(name)
+	if !filepath.IsAbs(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	resolved := "/"
+	components := strings.Split(strings.TrimPrefix(name, "/"), "/")
+	for hops := 0; len(components) > 0; {
+		component := components[0]
+		components = components[1:]
+		if component == "" {
+			continue
+		}
+
+		next := filepath.Join(resolved, component)
+		node, ok := m.nodes[next]
+		if !ok || node.mode&fs.ModeSymlink == 0 || (len(components) == 0 && !follow) {
+			if !ok && len(components) > 0 {
+				return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+			}
+			if ok && len(components) > 0 && node.mode&fs.ModeSymlink == 0 && !node.mode.IsDir() {
+				return "", &fs.PathError{Op: op, Path: name, Err: errors.New("not a directory")}
+			}
+			resolved = next
+			continue
+		}
+
+		if hops++; hops > 40 {
+			return "", &fs.PathError{Op: op, Path: name, Err: errors.New("too many links")}
+		}
+		target := node.target
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(resolved, target)
+		}
+		components = append(strings.Split(strings.TrimPrefix(filepath.Clean(target), "/"), "/"), components...)
+		resolved = "/"
+	}
+
+	return resolved, nil
+}
+
+// lookup returns the node at name
+func (m *memFS) lookup(op, name string, follow bool) (string, *memNode, error) {
+	path, err := m.resolve(op, name, follow)
+	if err != nil {
+		return "", nil, err
+	}
+	node, ok := m.nodes[path]
+	if !ok {
+		return path, nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return path, node, nil
+}
+
+// create adds node at name, whose parent has to be an existing directory
+func (m *memFS) create(op, name string, node *memNode) error {
+	path, err := m.resolve(op, name, false)
+	if err != nil {
+		return err
+	}
+	if _, ok := m.nodes[path]; ok {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrExist}
+	}
+	if parent, ok := m.nodes[filepath.Dir(path)]; !ok || !parent.mode.IsDir() {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	m.nodes[path] = node
+	return nil
+}
+
+func (m *memFS) Open(name string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.check("open", name); err != nil {
+		return nil, err
+	}
+	_, node, err := m.lookup("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if !node.mode.IsRegular() {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("not a regular file")}
+	}
+	return io.NopCloser(bytes.NewReader(slices.Clone(node.data))), nil
+}
+
+func (m *memFS) Create(name string, perm fs.FileMode) (io.WriteCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.check("create", name); err != nil {
+		return nil, err
+	}
+	_, node, err := m.lookup("create", name, true)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		node = &memNode{mode: perm.Perm(), modTime: time.Now()}
+		if err := m.create("create", name, node); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case !node.mode.IsRegular():
+		return nil, &fs.PathError{Op: "create", Path: name, Err: errors.New("is a directory")}
+	}
+	node.data = nil
+
+	return &memWriter{fs: m, node: node}, nil
+}
+
+// memWriter stores the written data in its node on Close
+type memWriter struct {
+	fs   *memFS
+	node *memNode
+	buf  bytes.Buffer
+}
+
+func (w *memWriter) Write(p []byte) (int, error) { return w.buf.Write(p) }
+
+func (w *memWriter) Close() error {
+	w.fs.mu.Lock()
+	defer w.fs.mu.Unlock()
+	w.node.data = w.buf.Bytes()
+	return nil
+}
+
+func (m *memFS) MkdirAll(path string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.check("mkdir", path); err != nil {
+		return err
+	}
+
+	current := "/"
+	for _, component := range strings.Split(strings.TrimPrefix(filepath.Clean(path), "/"), "/") {
+		if component == "" {
+			continue
+		}
+		current = filepath.Join(current, component)
+
+		_, node, err := m.lookup("mkdir", current, true)
+		if errors.Is(err, fs.ErrNotExist) {
+			if err := m.create("mkdir", current, &memNode{mode: fs.ModeDir | perm.Perm(), modTime: time.Now()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !node.mode.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: current, Err: errors.New("not a directory")}
+		}
+	}
+
+	return nil
+}
+
+func (m *memFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.check("remove", name); err != nil {
+		return err
+	}
+	path, node, err := m.lookup("remove", name, false)
+	if err != nil {
+		return err
+	}
+	if node.mode.IsDir() && len(m.children(path)) > 0 {
+		return &fs.PathError{Op: "remove", Path: name, Err: errors.New("directory not empty")}
+	}
+	delete(m.nodes, path)
+	return nil
+}
+
+func (m *memFS) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.check("rename", newpath); err != nil {
+		return err
+	}
+	from, node, err := m.lookup("rename", oldpath, false)
+	if err != nil {
+		return err
+	}
+	to, err := m.resolve("rename", newpath, false)
+	if err != nil {
+		return err
+	}
+	if parent, ok := m.nodes[filepath.Dir(to)]; !ok || !parent.mode.IsDir() {
+		return &fs.PathError{Op: "rename", Path: newpath, Err: fs.ErrNotExist}
+	}
+	if existing, ok := m.nodes[to]; ok {
+		if existing.mode.IsDir() != node.mode.IsDir() || len(m.children(to)) > 0 {
+			return &fs.PathError{Op: "rename", Path: newpath, Err: fs.ErrExist}
+		}
+	}
+
+	moved := make(map[string]*memNode)
+	for path, n := range m.nodes {
+		if path == from || strings.HasPrefix(path, from+"/") {
+			moved[to+strings.TrimPrefix(path, from)] = n
+			delete(m.nodes, path)
+		}
+	}
+	maps.Copy(m.nodes, moved)
+	return nil
+}
+
+func (m *memFS) RemoveAll(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.check("removeall", name); err != nil {
+		return err
+	}
+	path, err := m.resolve("removeall", name, false)
+	if err != nil {
+		return err
+	}
+	for p := range m.nodes {
+		if p == path || strings.HasPrefix(p, path+"/") {
+			delete(m.nodes, p)
+		}
+	}
+	return nil
+}
+
+func (m *memFS) Stat(name string) (fs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	path, node, err := m.lookup("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return memInfo{name: filepath.Base(path), node: *node}, nil
+}
+
+func (m *memFS) Lstat(name string) (fs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	path, node, err := m.lookup("lstat", name, false)
+	if err != nil {
+		return nil, err
+	}
+	return memInfo{name: filepath.Base(path), node: *node}, nil
+}
+
+func (m *memFS) Symlink(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.check("symlink", newname); err != nil {
+		return err
+	}
+	return m.create("symlink", newname, &memNode{mode: fs.ModeSymlink | 0777, target: oldname, modTime: time.Now()})
+}
+
+func (m *memFS) Link(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.check("link", newname); err != nil {
+		return err
+	}
+	_, node, err := m.lookup("link", oldname, false)
+	if err != nil {
+		return err
+	}
+	return m.create("link", newname, node)
+}
+
+func (m *memFS) Readlink(name string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, node, err := m.lookup("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	if node.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return node.target, nil
+}
+
+func (m *memFS) Chmod(name string, mode fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.check("chmod", name); err != nil {
+		return err
+	}
+	_, node, err := m.lookup("chmod", name, true)
+	if err != nil {
+		return err
+	}
+	node.mode = node.mode.Type() | mode.Perm()
+	return nil
+}
+
+func (m *memFS) Chtimes(name string, _, mtime time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, node, err := m.lookup("chtimes", name, true)
+	if err != nil {
+		return err
+	}
+	node.modTime = mtime
+	return nil
+}
+
+func (m *memFS) WalkDir(root string, fn fs.WalkDirFunc) error {
+	m.mu.Lock()
+	path, node, err := m.lookup("lstat", root, false)
+	m.mu.Unlock()
+	if err != nil {
+		return fn(root, nil, err)
+	}
+
+	err = m.walk(path, memInfo{name: filepath.Base(path), node: *node}, fn)
+	if errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+func (m *memFS) walk(path string, info memInfo, fn fs.WalkDirFunc) error {
+	if err := fn(path, fs.FileInfoToDirEntry(info), nil); err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return nil
+	}
+
+	m.mu.Lock()
+	children := m.children(path)
+	infos := make(map[string]memInfo, len(children))
+	for _, child := range children {
+		infos[child] = memInfo{name: filepath.Base(child), node: *m.nodes[child]}
+	}
+	m.mu.Unlock()
+
+	for _, child := range children {
+		err := m.walk(child, infos[child], fn)
+		if errors.Is(err, fs.SkipDir) && infos[child].IsDir() {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// children returns the sorted paths of the direct children of dir
+func (m *memFS) children(dir string) []string {
+	var children []string
+	for path := range m.nodes {
+		if path != dir && filepath.Dir(path) == dir {
+			children = append(children, path)
+		}
+	}
+	slices.Sort(children)
+	return children
+}
+
+// readFile returns the content of name, failing the test if it can't be read
+func (m *memFS) readFile(t *testing.T, name string) string {
+	t.Helper()
+
+	file, err := m.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// writeFile creates name with its parents
+func (m *memFS) writeFile(t testing.TB, name string, data []byte, perm fs.FileMode) {
+	t.Helper()
+
+	if err := m.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	file, err := m.Create(name, perm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// memInfo is a snapshot of a node
+type memInfo struct {
+	name string
+	node memNode
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return int64(len(i.node.data)) }
+func (i memInfo) Mode() fs.FileMode  { return i.node.mode }
+func (i memInfo) ModTime() time.Time { return i.node.modTime }
+func (i memInfo) IsDir() bool        { return i.node.mode.IsDir() }
+func (i memInfo) Sys() any           { return nil }
+
+// fakeGo is a CommandRunner that acts like the go binaries in its filesystem,
+// answering "go version" with the VERSION file of the installation. Running
+// make.bash creates the binaries of its tree.
+type fakeGo struct {
+	fs *memFS
+
+	mu    sync.Mutex
+	calls []string
+	// envs are the extra environment variables of the calls
+	envs [][]string
+	// err makes every call fail
+	err error
+}
+
+func (g *fakeGo) Run(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+	g.mu.Lock()
+	g.calls = append(g.calls, strings.TrimSpace(dir+" "+strings.Join(append([]string{name}, args...), " ")))
+	g.envs = append(g.envs, env)
+	g.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if g.err != nil {
+		return []byte("go: broken\n"), g.err
+	}
+
+	g.fs.mu.Lock()
+	binary, err := g.fs.resolve("exec", name, true)
+	g.fs.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if info, err := g.fs.Stat(binary); err != nil || info.Mode().Perm()&0100 == 0 {
+		return nil, fmt.Errorf("failed to start command: %s is not executable", name)
+	}
+	if filepath.Base(binary) == "make.bash" {
+		return g.makeBash(filepath.Dir(filepath.Dir(binary)))
+	}
+	if len(args) != 1 || args[0] != "version" {
+		return nil, fmt.Errorf("fake go only supports version, got %q", args)
+	}
+
+	root := filepath.Dir(filepath.Dir(binary))
+	file, err := g.fs.Open(filepath.Join(root, "VERSION"))
+	if err != nil {
+		return nil, fmt.Errorf("command failed: %w", err)
+	}
+	defer file.Close()
+	data, _ := io.ReadAll(file)
+	version, _, _ := strings.Cut(string(data), "\n")
+
+	return []byte("go version " + version + " linux/amd64\n"), nil
+}
+
+// makeBash "builds" the Go tree at root
+func (g *fakeGo) makeBash(root string) ([]byte, error) {
+	for _, name := range []string{"go", "gofmt"} {
+		path := filepath.Join(root, "bin", name)
+		if err := g.fs.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		file, err := g.fs.Create(path, 0755)
+		if err != nil {
+			return nil, err
+		}
+		file.Close()
+	}
+	return []byte("Installed Go for linux/amd64 in " + root + "\n"), nil
+}
+
+// fakeClock advances by step on every call
+type fakeClock struct {
+	mu   sync.Mutex
+	now  time.Time
+	step time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(c.step)
+	return c.now
+}
+
+// TestMemFSMatchesOS runs the same operations against the host filesystem and
+// memFS, so that tests using memFS can be trusted
+func TestMemFSMatchesOS(t *testing.T) {
+	run := func(t *testing.T, fsys FileSystem, root string) []string {
+		var results []string
+		record := func(op string, err error) {
+			results = append(results, fmt.Sprintf("%s: %v", op, err == nil))
+		}
+		path := func(name string) string { return filepath.Join(root, name) }
+
+		record("mkdir", fsys.MkdirAll(path("a/b"), 0755))
+		file, err := fsys.Create(path("a/b/file"), 0600)
+		record("create", err)
+		if err == nil {
+			file.Write([]byte("content"))
+			file.Close()
+		}
+		record("create in missing dir", func() error { _, err := fsys.Create(path("x/file"), 0644); return err }())
+		record("symlink", fsys.Symlink("b/file", path("a/link")))
+		record("symlink exists", fsys.Symlink("b/file", path("a/link")))
+		record("link", fsys.Link(path("a/b/file"), path("a/hard")))
+		record("chmod", fsys.Chmod(path("a/link"), 0640))
+		record("remove non-empty", fsys.Remove(path("a/b")))
+
+		if info, err := fsys.Stat(path("a/link")); err == nil {
+			results = append(results, fmt.Sprintf("stat link: %v %d", info.Mode(), info.Size()))
+		}
+		if info, err := fsys.Lstat(path("a/link")); err == nil {
+			results = append(results, fmt.Sprintf("lstat link: %v", info.Mode().Type()))
+		}
+		if info, err := fsys.Stat(path("a/hard")); err == nil {
+			results = append(results, fmt.Sprintf("stat hardlink: %v", info.Mode()))
+		}
+		if target, err := fsys.Readlink(path("a/link")); err == nil {
+			results = append(results, "readlink: "+target)
+		}
+		if file, err := fsys.Open(path("a/link")); err == nil {
+			data, _ := io.ReadAll(file)
+			file.Close()
+			results = append(results, "read through link: "+string(data))
+		}
+		_, err = fsys.Stat(path("missing"))
+		results = append(results, fmt.Sprintf("stat missing: %v", errors.Is(err, fs.ErrNotExist)))
+
+		fsys.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(root, p)
+			results = append(results, fmt.Sprintf("walk: %s %v", rel, d.Type()))
+			return nil
+		})
+
+		record("rename", fsys.Rename(path("a/b"), path("a/c")))
+		record("rename onto non-empty", fsys.Rename(path("a/c"), path("a")))
+		if _, err := fsys.Stat(path("a/c/file")); err == nil {
+			results = append(results, "renamed")
+		}
+		record("remove link", fsys.Remove(path("a/link")))
+		record("removeall", fsys.RemoveAll(path("a")))
+		_, err = fsys.Lstat(path("a/b/file"))
+		results = append(results, fmt.Sprintf("removed: %v", errors.Is(err, fs.ErrNotExist)))
+
+		return results
+	}
+
+	host := run(t, OSFileSystem{}, t.TempDir())
+
+	mem := newMemFS()
+	if err := mem.MkdirAll("/root", 0755); err != nil {
+		t.Fatal(err)
+	}
+	inMemory := run(t, mem, "/root")
+
+	if !slices.Equal(host, inMemory) {
+		t.Errorf("memFS differs from the host\nhost:   %q\nmemory: %q", host, inMemory)
+	}
+}
+
+// writeScript writes an executable shell script to a temporary directory
+func writeScript(t *testing.T, script string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "script")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExecRunner(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     string
+		env        []string
+		wantOutput string
+		wantErr    string
+	}{
+		{
+			name:       "output",
+			script:     "echo out; echo err >&2\n",
+			wantOutput: "out\nerr\n",
+		},
+		{
+			name:       "environment and directory",
+			script:     "echo \"$GREETING $(basename \"$PWD\")\"\n",
+			env:        []string{"GREETING=hello"},
+			wantOutput: "hello dir\n",
+		},
+		{
+			name:       "failure keeps output",
+			script:     "echo broken; exit 3\n",
+			wantOutput: "broken\n",
+			wantErr:    "command failed: exit status 3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "dir")
+			if err := os.Mkdir(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			output, err := ExecRunner{}.Run(context.Background(), dir, tt.env, writeScript(t, tt.script))
+			if string(output) != tt.wantOutput {
+				t.Errorf("Run() output = %q, want %q", output, tt.wantOutput)
+			}
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Run() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecRunnerCancelled(t *testing.T) {
+	// The background child keeps the output open after its parent was killed.
+	script := writeScript(t, "echo started\nsleep 60 &\nsleep 60\n")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := ExecRunner{}.Run(ctx, "", nil, script)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > commandWaitDelay/2 {
+		t.Errorf("Run() returned after %s, the command wasn't killed", elapsed)
+	}
+}
@@ -0,0 +1,121 @@
+package gotools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+)
+
+// TempPattern is the pattern of the temporary directories downloads go to
+const TempPattern = "goupdate-*"
+
+// tempOwnerFile is the file within a temporary directory naming the process
+// that created it, in the format of the lock file
+const tempOwnerFile = ".owner"
+
+// TempArtifact is a temporary directory left behind by an earlier run
+type TempArtifact struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// createTempDir creates a temporary directory below parent, empty meaning
+// os.TempDir(), and records the current process as its owner
+func createTempDir(parent string) (string, error) {
+	dir, err := os.MkdirTemp(parent, TempPattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+
+	hostname, err := os.Hostname()
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, tempOwnerFile), fmt.Appendf(nil, "%d %s\n", os.Getpid(), hostname), 0644)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("failed to record owner of temporary directory: %w", err)
+	}
+
+	return dir, nil
+}
+
+// FindTempArtifacts returns the temporary directories in dir, empty meaning
+// os.TempDir(), whose process is gone. Directories without a known owner,
+// e.g. from older versions, count as orphaned once they weren't modified for
+// minAge.
+func FindTempArtifacts(dir string, minAge time.Duration) ([]TempArtifact, error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine hostname: %w", err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, TempPattern))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list temporary directories: %w", err)
+	}
+
+	var artifacts []TempArtifact
+	for _, path := range paths {
+		info, err := os.Lstat(path)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+
+		owner, err := readLockOwner(filepath.Join(path, tempOwnerFile))
+		if err == nil && !owner.stale(hostname) {
+			continue
+		}
+		if err != nil && time.Since(info.ModTime()) < minAge {
+			continue
+		}
+
+		artifacts = append(artifacts, TempArtifact{Path: path, Size: diskUsage(path), ModTime: info.ModTime()})
+	}
+
+	slices.SortFunc(artifacts, func(a, b TempArtifact) int { return a.ModTime.Compare(b.ModTime) })
+	return artifacts, nil
+}
+
+// stale reports whether the process that created a temporary directory is
+// gone. Only processes on hostname can be checked, directories of other hosts
+// sharing the temporary directory are never stale.
+func (o LockOwner) stale(hostname string) bool {
+	if o.Hostname != hostname {
+		return false
+	}
+
+	// Signal 0 checks for existence without sending anything. EPERM means the
+	// process exists but belongs to another user.
+	err := syscall.Kill(o.PID, 0)
+	return errors.Is(err, syscall.ESRCH)
+}
+
+// RemoveTempArtifacts removes the artifacts, it continues after failures and
+// returns all of them
+func RemoveTempArtifacts(artifacts []TempArtifact) error {
+	var errs []error
+	for _, artifact := range artifacts {
+		if err := os.RemoveAll(artifact.Path); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove %s: %w", artifact.Path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// TempArtifactsSize returns the disk usage of artifacts in a human readable form
+func TempArtifactsSize(artifacts []TempArtifact) string {
+	var total int64
+	for _, artifact := range artifacts {
+		total += artifact.Size
+	}
+	return formatBytes(uint64(total))
+}
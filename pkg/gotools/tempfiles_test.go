@@ -0,0 +1,79 @@
+package gotools
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFindTempArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The pid of a process that exited is free, unless it was reused in the meantime.
+	exited := exec.Command("true")
+	if err := exited.Run(); err != nil {
+		t.Fatal(err)
+	}
+	deadPID := exited.ProcessState.Pid()
+
+	tempDir := func(name, owner string, age time.Duration) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if owner != "" {
+			writeTestFile(t, filepath.Join(path, tempOwnerFile), owner)
+		}
+		writeTestFile(t, filepath.Join(path, "go.tar.gz"), "archive")
+		modTime := time.Now().Add(-age)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	running, err := createTempDir(dir)
+	if err != nil {
+		t.Fatalf("createTempDir() error = %v", err)
+	}
+	dead := tempDir("goupdate-dead", fmt.Sprintf("%d %s\n", deadPID, hostname), 0)
+	tempDir("goupdate-remote", fmt.Sprintf("%d other-host\n", deadPID), 2*time.Hour)
+	old := tempDir("goupdate-old", "", 2*time.Hour)
+	tempDir("goupdate-recent", "", time.Minute)
+	tempDir("unrelated", "", 2*time.Hour)
+	writeTestFile(t, filepath.Join(dir, "goupdate-file"), "")
+
+	artifacts, err := FindTempArtifacts(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("FindTempArtifacts() error = %v", err)
+	}
+
+	var paths []string
+	for _, artifact := range artifacts {
+		paths = append(paths, artifact.Path)
+		if artifact.Size < int64(len("archive")) {
+			t.Errorf("%s has size %d", artifact.Path, artifact.Size)
+		}
+	}
+	if want := []string{old, dead}; !slices.Equal(paths, want) {
+		t.Fatalf("FindTempArtifacts() = %q, want %q", paths, want)
+	}
+
+	if err := RemoveTempArtifacts(artifacts); err != nil {
+		t.Fatalf("RemoveTempArtifacts() error = %v", err)
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed: %v", path, err)
+		}
+	}
+	if _, err := os.Stat(running); err != nil {
+		t.Errorf("directory of the running process was removed: %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package gotools
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Tool is a Go command installed with go install next to the toolchain
+type Tool struct {
+	// Package is the import path of the main package, e.g. "golang.org/x/tools/gopls"
+	Package string
+	// Version is a module version or query, e.g. "v0.18.1" or "latest"
+	Version string
+	// Toolchain limits the tool to the Go releases it matches, e.g. "1.22"
+	// for all 1.22 releases, empty applies it to all
+	Toolchain string
+}
+
+// ParseTool parses a tool definition of the form "[go<version>:]package@version",
+// e.g. "golang.org/x/tools/gopls@latest" or "go1.22:honnef.co/go/tools/cmd/staticcheck@2023.1.7".
+func ParseTool(def string) (Tool, error) {
+	var tool Tool
+
+	spec := strings.TrimSpace(def)
+	if toolchain, rest, ok := strings.Cut(spec, ":"); ok {
+		if _, err := parseVersion(toolchain); err != nil || !strings.HasPrefix(toolchain, "go") {
+			return Tool{}, fmt.Errorf("invalid toolchain %q in tool %q: expected e.g. go1.24", toolchain, def)
+		}
+		tool.Toolchain, spec = strings.TrimPrefix(toolchain, "go"), rest
+	}
+
+	pkg, version, ok := strings.Cut(spec, "@")
+	if !ok || pkg == "" || version == "" {
+		return Tool{}, fmt.Errorf("invalid tool %q: expected package@version", def)
+	}
+	tool.Package, tool.Version = pkg, version
+
+	return tool, nil
+}
+
+func (t Tool) String() string {
+	return t.Package + "@" + t.Version
+}
+
+// majorSuffix matches the major version suffix of a module path, e.g. "v2"
+var majorSuffix = regexp.MustCompile(`^v[0-9]+$`)
+
+// Name returns the name of the installed command, the last element of the
+// package path without a major version suffix
+func (t Tool) Name() string {
+	name := path.Base(t.Package)
+	if majorSuffix.MatchString(name) && path.Dir(t.Package) != "." {
+		name = path.Base(path.Dir(t.Package))
+	}
+	return name
+}
+
+// ToolsFor selects the tools for the Go version. Of the definitions of a
+// package, the last one pinned to a matching toolchain wins over the last
+// one for all toolchains.
+func ToolsFor(tools []Tool, goVersion string) []Tool {
+	var packages []string
+	general := make(map[string]Tool)
+	pinned := make(map[string]Tool)
+
+	for _, tool := range tools {
+		if !slices.Contains(packages, tool.Package) {
+			packages = append(packages, tool.Package)
+		}
+		switch {
+		case tool.Toolchain == "":
+			general[tool.Package] = tool
+		case matchesVersion(goVersion, tool.Toolchain):
+			pinned[tool.Package] = tool
+		}
+	}
+
+	var selected []Tool
+	for _, pkg := range packages {
+		if tool, ok := pinned[pkg]; ok {
+			selected = append(selected, tool)
+		} else if tool, ok := general[pkg]; ok {
+			selected = append(selected, tool)
+		}
+	}
+	return selected
+}
+
+// ToolResult is the outcome of installing a tool
+type ToolResult struct {
+	Tool Tool
+	// Output is the output of a failed go install
+	Output string
+	Err    error
+}
+
+// InstallTools installs or upgrades the tools selected for the installed Go
+// with its go command into BinDir. A failing tool doesn't stop the others,
+// the results report every tool. The error is only set if Go isn't
+// installed or ctx is done.
+func (i *Installer) InstallTools(ctx context.Context, tools []Tool) ([]ToolResult, error) {
+	version, err := i.InstalledVersion()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine installed version: %w", err)
+	}
+
+	logger := loggerOrDefault(i.Logger)
+	goPath := filepath.Join(i.InstallDir, "go", "bin", "go")
+	// The tools are built with exactly this toolchain, go.mod toolchain lines
+	// of the tools mustn't switch to another one, and a GOROOT inherited from
+	// the environment mustn't point it at another standard library.
+	env := []string{"GOBIN=" + i.BinDir, "GOTOOLCHAIN=local", "GOROOT="}
+
+	var results []ToolResult
+	for _, tool := range ToolsFor(tools, version) {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		logger.Info("Installing tool", "tool", tool.String(), "go", version)
+		output, err := i.commandRunner().Run(ctx, i.InstallDir, env, goPath, "install", tool.String())
+		result := ToolResult{Tool: tool}
+		if err != nil {
+			result.Err = fmt.Errorf("failed to build %s with Go %s: %w", tool, version, err)
+			result.Output = strings.TrimSpace(string(output))
+			logger.Warn("Failed to install tool", "tool", tool.String(), "go", version, "error", err)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// FailedTools returns the results of the tools that failed to install
+func FailedTools(results []ToolResult) []ToolResult {
+	var failed []ToolResult
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
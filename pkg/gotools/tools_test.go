@@ -0,0 +1,183 @@
+package gotools
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseTool(t *testing.T) {
+	tests := []struct {
+		def      string
+		want     Tool
+		wantName string
+		wantErr  bool
+	}{
+		{
+			def:      "golang.org/x/tools/gopls@latest",
+			want:     Tool{Package: "golang.org/x/tools/gopls", Version: "latest"},
+			wantName: "gopls",
+		},
+		{
+			def:      "go1.22:honnef.co/go/tools/cmd/staticcheck@2023.1.7",
+			want:     Tool{Package: "honnef.co/go/tools/cmd/staticcheck", Version: "2023.1.7", Toolchain: "1.22"},
+			wantName: "staticcheck",
+		},
+		{
+			def:      "github.com/golangci/golangci-lint/v2/cmd/golangci-lint@v2.1.0",
+			want:     Tool{Package: "github.com/golangci/golangci-lint/v2/cmd/golangci-lint", Version: "v2.1.0"},
+			wantName: "golangci-lint",
+		},
+		{
+			def:      "example.com/tool/v3@latest",
+			want:     Tool{Package: "example.com/tool/v3", Version: "latest"},
+			wantName: "tool",
+		},
+		{def: "golang.org/x/tools/gopls", wantErr: true},
+		{def: "@latest", wantErr: true},
+		{def: "gopls@", wantErr: true},
+		{def: "1.22:golang.org/x/tools/gopls@latest", wantErr: true},
+		{def: "gonext:golang.org/x/tools/gopls@latest", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.def, func(t *testing.T) {
+			got, err := ParseTool(tt.def)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseTool(%q) = %+v, want an error", tt.def, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTool(%q) error = %v", tt.def, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTool(%q) = %+v, want %+v", tt.def, got, tt.want)
+			}
+			if name := got.Name(); name != tt.wantName {
+				t.Errorf("Name() = %q, want %q", name, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestToolsFor(t *testing.T) {
+	tools := []Tool{
+		{Package: "golang.org/x/tools/gopls", Version: "latest"},
+		{Package: "honnef.co/go/tools/cmd/staticcheck", Version: "latest"},
+		{Package: "honnef.co/go/tools/cmd/staticcheck", Version: "2023.1.7", Toolchain: "1.22"},
+		{Package: "example.com/legacy", Version: "v1.0.0", Toolchain: "1.21"},
+	}
+
+	tests := []struct {
+		goVersion string
+		want      []string
+	}{
+		{
+			goVersion: "1.24.1",
+			want:      []string{"golang.org/x/tools/gopls@latest", "honnef.co/go/tools/cmd/staticcheck@latest"},
+		},
+		{
+			goVersion: "1.22.12",
+			want:      []string{"golang.org/x/tools/gopls@latest", "honnef.co/go/tools/cmd/staticcheck@2023.1.7"},
+		},
+		{
+			goVersion: "1.21.0",
+			want: []string{
+				"golang.org/x/tools/gopls@latest",
+				"honnef.co/go/tools/cmd/staticcheck@latest",
+				"example.com/legacy@v1.0.0",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.goVersion, func(t *testing.T) {
+			var got []string
+			for _, tool := range ToolsFor(tools, tt.goVersion) {
+				got = append(got, tool.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ToolsFor(%s) = %q, want %q", tt.goVersion, got, tt.want)
+			}
+		})
+	}
+}
+
+// toolRunner records go install calls and fails for the packages in fail
+type toolRunner struct {
+	commands []string
+	envs     [][]string
+	fail     map[string]bool
+}
+
+func (r *toolRunner) Run(_ context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+	r.commands = append(r.commands, dir+" "+strings.Join(append([]string{name}, args...), " "))
+	r.envs = append(r.envs, env)
+
+	pkg, _, _ := strings.Cut(args[len(args)-1], "@")
+	if r.fail[pkg] {
+		return []byte("# " + pkg + "\nundefined: types.Alias\n"), errors.New("exit status 1")
+	}
+	return nil, nil
+}
+
+func TestInstallTools(t *testing.T) {
+	installer, _, _ := newMemInstaller(t)
+	runner := &toolRunner{fail: map[string]bool{"honnef.co/go/tools/cmd/staticcheck": true}}
+	installer.Runner = runner
+
+	tools := []Tool{
+		{Package: "golang.org/x/tools/gopls", Version: "latest"},
+		{Package: "honnef.co/go/tools/cmd/staticcheck", Version: "2023.1.7"},
+		{Package: "github.com/go-delve/delve/cmd/dlv", Version: "v1.24.0", Toolchain: "1.23"},
+		{Package: "example.com/legacy", Version: "v1.0.0", Toolchain: "1.21"},
+	}
+
+	results, err := installer.InstallTools(context.Background(), tools)
+	if err != nil {
+		t.Fatalf("InstallTools() error = %v", err)
+	}
+
+	goPath := "/home/gopher/.local/lib/go/bin/go"
+	wantCommands := []string{
+		"/home/gopher/.local/lib " + goPath + " install golang.org/x/tools/gopls@latest",
+		"/home/gopher/.local/lib " + goPath + " install honnef.co/go/tools/cmd/staticcheck@2023.1.7",
+		"/home/gopher/.local/lib " + goPath + " install github.com/go-delve/delve/cmd/dlv@v1.24.0",
+	}
+	if !slices.Equal(runner.commands, wantCommands) {
+		t.Errorf("commands = %q, want %q", runner.commands, wantCommands)
+	}
+	wantEnv := []string{"GOBIN=/home/gopher/.local/bin", "GOTOOLCHAIN=local", "GOROOT="}
+	if len(runner.envs) != len(wantCommands) {
+		t.Errorf("got %d environments, want one per command", len(runner.envs))
+	}
+	for _, env := range runner.envs {
+		if !slices.Equal(env, wantEnv) {
+			t.Errorf("env = %q, want %q", env, wantEnv)
+		}
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("results = %+v, want 3", results)
+	}
+	failed := FailedTools(results)
+	if len(failed) != 1 || failed[0].Tool.Name() != "staticcheck" {
+		t.Fatalf("FailedTools() = %+v, want staticcheck", failed)
+	}
+	if !strings.Contains(failed[0].Err.Error(), "Go 1.23.0") {
+		t.Errorf("error = %v, want the Go version", failed[0].Err)
+	}
+	if !strings.Contains(failed[0].Output, "undefined: types.Alias") {
+		t.Errorf("output = %q, want the build output", failed[0].Output)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := installer.InstallTools(ctx, tools); !errors.Is(err, context.Canceled) {
+		t.Errorf("InstallTools() with canceled context error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,257 @@
+package gotools
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// HTTPOptions customizes the HTTP client for mirrors behind an internal CA,
+// mTLS or per-host proxies
+type HTTPOptions struct {
+	// CAFiles are PEM files with root CAs trusted in addition to the system ones
+	CAFiles []string
+	// ClientCert and ClientKey are the PEM files of the client certificate
+	// presented to servers requiring mTLS
+	ClientCert string
+	ClientKey  string
+	// Proxies maps hosts to the proxy used for them, see ParseProxyOverride
+	Proxies map[string]string
+}
+
+// directProxy is the proxy override to connect without a proxy
+const directProxy = "direct"
+
+// ParseProxyOverride parses a "host=proxy" override. host is a hostname, a
+// ".domain" matching all hosts below it or "*" for all hosts, proxy is a URL
+// or "direct". Hosts in NO_PROXY are never proxied.
+func ParseProxyOverride(def string) (string, string, error) {
+	host, proxy, ok := strings.Cut(def, "=")
+	host, proxy = strings.ToLower(strings.TrimSpace(host)), strings.TrimSpace(proxy)
+	if !ok || host == "" || proxy == "" {
+		return "", "", fmt.Errorf("invalid proxy override %q, expected host=proxy", def)
+	}
+	if proxy != directProxy {
+		if _, err := parseProxyURL(proxy); err != nil {
+			return "", "", err
+		}
+	}
+	return host, proxy, nil
+}
+
+// NewHTTPClientWithOptions creates an HTTP client like NewHTTPClient with the
+// certificates and proxies of opts
+func NewHTTPClientWithOptions(opts HTTPOptions) (*http.Client, error) {
+	client := NewHTTPClient()
+	transport := client.Transport.(*http.Transport)
+
+	tlsConfig, err := opts.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	if len(opts.Proxies) > 0 {
+		proxy, err := newProxyFunc(opts.Proxies, os.Getenv("NO_PROXY")+","+os.Getenv("no_proxy"), http.ProxyFromEnvironment)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = proxy
+	}
+
+	return client, nil
+}
+
+// tlsConfig returns the TLS configuration of the options, nil for the defaults
+func (opts HTTPOptions) tlsConfig() (*tls.Config, error) {
+	if len(opts.CAFiles) == 0 && opts.ClientCert == "" && opts.ClientKey == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if len(opts.CAFiles) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, path := range opts.CAFiles {
+			pem, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA file: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in CA file %s", path)
+			}
+		}
+		config.RootCAs = pool
+	}
+
+	if opts.ClientCert != "" || opts.ClientKey != "" {
+		if opts.ClientCert == "" || opts.ClientKey == "" {
+			return nil, fmt.Errorf("client certificate and key must be given together")
+		}
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// proxyFunc is the type of Transport.Proxy
+type proxyFunc func(*http.Request) (*url.URL, error)
+
+// newProxyFunc returns a Transport.Proxy function choosing the proxy by the
+// overrides, falling back to fallback for hosts without one
+func newProxyFunc(overrides map[string]string, noProxyList string, fallback proxyFunc) (proxyFunc, error) {
+	proxies := make(map[string]*url.URL, len(overrides))
+	for host, proxy := range overrides {
+		if proxy == directProxy {
+			proxies[host] = nil
+			continue
+		}
+		u, err := parseProxyURL(proxy)
+		if err != nil {
+			return nil, err
+		}
+		proxies[host] = u
+	}
+	bypass := parseNoProxy(noProxyList)
+
+	return func(req *http.Request) (*url.URL, error) {
+		host := strings.ToLower(req.URL.Hostname())
+		if bypass.matches(host, requestPort(req.URL)) {
+			return nil, nil
+		}
+		if proxy, ok := lookupProxy(proxies, host); ok {
+			return proxy, nil
+		}
+		return fallback(req)
+	}, nil
+}
+
+// lookupProxy returns the override for host: an exact match, then the
+// longest matching ".domain", then "*"
+func lookupProxy(proxies map[string]*url.URL, host string) (*url.URL, bool) {
+	if proxy, ok := proxies[host]; ok {
+		return proxy, true
+	}
+	for domain := host; ; {
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok {
+			break
+		}
+		if proxy, ok := proxies["."+parent]; ok {
+			return proxy, true
+		}
+		domain = parent
+	}
+	proxy, ok := proxies["*"]
+	return proxy, ok
+}
+
+// parseProxyURL parses a proxy URL, defaulting to http:// like the environment
+func parseProxyURL(proxy string) (*url.URL, error) {
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q", proxy)
+	}
+	return u, nil
+}
+
+// noProxy is a parsed NO_PROXY list
+type noProxy struct {
+	all      bool
+	networks []*net.IPNet
+	// hosts are hostnames or IPs with an optional port, a leading "." only
+	// matches subdomains
+	hosts []noProxyHost
+}
+
+type noProxyHost struct {
+	name string
+	port string
+}
+
+// parseNoProxy parses NO_PROXY the way the go command does: comma separated
+// hostnames, domains with a leading ".", IPs, CIDRs and "*", optionally with
+// a port
+func parseNoProxy(value string) noProxy {
+	var p noProxy
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			p.all = true
+			continue
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			p.networks = append(p.networks, network)
+			continue
+		}
+
+		host, port, err := net.SplitHostPort(entry)
+		if err != nil {
+			host, port = entry, ""
+		}
+		p.hosts = append(p.hosts, noProxyHost{name: strings.Trim(host, "[]"), port: port})
+	}
+	return p
+}
+
+// requestPort returns the port of u, the default port of its scheme if it
+// has none, like x/net/http/httpproxy does for NO_PROXY entries with a port
+func requestPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	switch u.Scheme {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+	return ""
+}
+
+// matches reports whether a request to host and port must not be proxied
+func (p noProxy) matches(host, port string) bool {
+	if p.all {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		for _, network := range p.networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+	}
+	for _, h := range p.hosts {
+		if h.port != "" && h.port != port {
+			continue
+		}
+		if name, ok := strings.CutPrefix(h.name, "."); ok {
+			if strings.HasSuffix(host, "."+name) {
+				return true
+			}
+			continue
+		}
+		if host == h.name || strings.HasSuffix(host, "."+h.name) {
+			return true
+		}
+	}
+	return false
+}
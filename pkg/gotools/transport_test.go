@@ -0,0 +1,330 @@
+package gotools
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCert is a certificate with its key, written to PEM files
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	tls      tls.Certificate
+	certFile string
+	keyFile  string
+}
+
+// newTestCert creates a certificate signed by parent, or a self-signed CA if
+// parent is nil
+func newTestCert(t *testing.T, name string, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	c := &testCert{
+		cert:     cert,
+		key:      key,
+		tls:      tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key},
+		certFile: filepath.Join(dir, name+".pem"),
+		keyFile:  filepath.Join(dir, name+"-key.pem"),
+	}
+	writeTestFile(t, c.certFile, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+	writeTestFile(t, c.keyFile, string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})))
+	return c
+}
+
+func TestHTTPClientTLS(t *testing.T) {
+	ca := newTestCA(t)
+	server := newTestCert(t, "server", ca)
+	client := newTestCert(t, "client", ca)
+	otherCA := newTestCA(t)
+	untrusted := newTestCert(t, "untrusted", otherCA)
+
+	tests := []struct {
+		name       string
+		clientAuth tls.ClientAuthType
+		opts       HTTPOptions
+		// wantErr is a substring of the request error, empty if it succeeds
+		wantErr string
+	}{
+		{
+			name:    "unknown CA",
+			wantErr: "certificate signed by unknown authority",
+		},
+		{
+			name: "custom CA",
+			opts: HTTPOptions{CAFiles: []string{otherCA.certFile, ca.certFile}},
+		},
+		{
+			name:       "mTLS without client certificate",
+			clientAuth: tls.RequireAndVerifyClientCert,
+			opts:       HTTPOptions{CAFiles: []string{ca.certFile}},
+			wantErr:    "certificate required",
+		},
+		{
+			name:       "mTLS with untrusted client certificate",
+			clientAuth: tls.RequireAndVerifyClientCert,
+			opts:       HTTPOptions{CAFiles: []string{ca.certFile}, ClientCert: untrusted.certFile, ClientKey: untrusted.keyFile},
+			wantErr:    "unknown certificate authority",
+		},
+		{
+			name:       "mTLS",
+			clientAuth: tls.RequireAndVerifyClientCert,
+			opts:       HTTPOptions{CAFiles: []string{ca.certFile}, ClientCert: client.certFile, ClientKey: client.keyFile},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, "ok")
+			}))
+			clientCAs := x509.NewCertPool()
+			clientCAs.AddCert(ca.cert)
+			srv.TLS = &tls.Config{Certificates: []tls.Certificate{server.tls}, ClientAuth: tt.clientAuth, ClientCAs: clientCAs}
+			srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+			srv.StartTLS()
+			defer srv.Close()
+
+			httpClient, err := NewHTTPClientWithOptions(tt.opts)
+			if err != nil {
+				t.Fatalf("NewHTTPClientWithOptions() error = %v", err)
+			}
+
+			resp, err := httpClient.Get(srv.URL)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Get() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			defer resp.Body.Close()
+			if body, _ := io.ReadAll(resp.Body); string(body) != "ok" {
+				t.Errorf("body = %q", body)
+			}
+		})
+	}
+}
+
+func newTestCA(t *testing.T) *testCert {
+	t.Helper()
+	return newTestCert(t, "ca", nil)
+}
+
+func TestNewHTTPClientWithOptionsErrors(t *testing.T) {
+	client := newTestCert(t, "client", newTestCA(t))
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	writeTestFile(t, empty, "")
+
+	tests := []struct {
+		name    string
+		opts    HTTPOptions
+		wantErr string
+	}{
+		{
+			name:    "missing CA file",
+			opts:    HTTPOptions{CAFiles: []string{filepath.Join(t.TempDir(), "missing.pem")}},
+			wantErr: "failed to read CA file",
+		},
+		{
+			name:    "CA file without certificates",
+			opts:    HTTPOptions{CAFiles: []string{empty}},
+			wantErr: "no certificates found",
+		},
+		{
+			name:    "client certificate without key",
+			opts:    HTTPOptions{ClientCert: client.certFile},
+			wantErr: "must be given together",
+		},
+		{
+			name:    "client key mismatch",
+			opts:    HTTPOptions{ClientCert: client.certFile, ClientKey: client.certFile},
+			wantErr: "failed to load client certificate",
+		},
+		{
+			name:    "invalid proxy",
+			opts:    HTTPOptions{Proxies: map[string]string{"*": "http://"}},
+			wantErr: "invalid proxy URL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHTTPClientWithOptions(tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewHTTPClientWithOptions() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProxyFunc(t *testing.T) {
+	envProxy := func(*http.Request) (*url.URL, error) {
+		return url.Parse("http://env-proxy:3128")
+	}
+
+	overrides := map[string]string{
+		"dl.google.com":       "http://google-proxy:8080",
+		".corp.example":       "corp-proxy:3128",
+		"direct.corp.example": "direct",
+	}
+	noProxy := "internal.corp.example, .lan, 10.0.0.0/8, cache.example:8443, mirror.example:443, plain.example:80"
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://dl.google.com/go/go1.24.1.linux-amd64.tar.gz", "http://google-proxy:8080"},
+		{"https://mirror.corp.example/go/", "http://corp-proxy:3128"},
+		{"https://a.b.corp.example/go/", "http://corp-proxy:3128"},
+		{"https://direct.corp.example/go/", ""},
+		{"https://internal.corp.example/go/", ""},
+		{"https://api.internal.corp.example/go/", ""},
+		{"https://mirror.lan/go/", ""},
+		{"http://10.1.2.3/go/", ""},
+		{"https://cache.example:8443/go/", ""},
+		{"https://cache.example/go/", "http://env-proxy:3128"},
+		{"https://mirror.example/go/", ""},
+		{"https://mirror.example:443/go/", ""},
+		{"http://mirror.example/go/", "http://env-proxy:3128"},
+		{"http://plain.example/go/", ""},
+		{"https://plain.example/go/", "http://env-proxy:3128"},
+		{"https://go.dev/dl/?mode=json", "http://env-proxy:3128"},
+	}
+
+	proxy, err := newProxyFunc(overrides, noProxy, envProxy)
+	if err != nil {
+		t.Fatalf("newProxyFunc() error = %v", err)
+	}
+
+	for _, tt := range tests {
+		req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u, err := proxy(req)
+		if err != nil {
+			t.Fatalf("proxy(%s) error = %v", tt.url, err)
+		}
+		have := ""
+		if u != nil {
+			have = u.String()
+		}
+		if have != tt.want {
+			t.Errorf("proxy(%s) = %q, want %q", tt.url, have, tt.want)
+		}
+	}
+
+	// A catch-all override replaces the environment.
+	proxy, err = newProxyFunc(map[string]string{"*": "http://all:3128"}, noProxy, envProxy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://go.dev/", nil)
+	if u, _ := proxy(req); u == nil || u.Host != "all:3128" {
+		t.Errorf("proxy with catch-all = %v", u)
+	}
+}
+
+func TestHTTPClientProxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "proxied "+r.URL.String())
+	}))
+	defer proxy.Close()
+	t.Setenv("NO_PROXY", "")
+	t.Setenv("no_proxy", "")
+
+	client, err := NewHTTPClientWithOptions(HTTPOptions{Proxies: map[string]string{"mirror.example": proxy.URL}})
+	if err != nil {
+		t.Fatalf("NewHTTPClientWithOptions() error = %v", err)
+	}
+
+	checker := NewChecker()
+	checker.SetHTTPClient(client)
+	body, err := fetch(context.Background(), checker.client, "http://mirror.example/go/", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("fetch() error = %v", err)
+	}
+	if want := "proxied http://mirror.example/go/"; string(body) != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+}
+
+func TestParseProxyOverride(t *testing.T) {
+	tests := []struct {
+		def       string
+		wantHost  string
+		wantProxy string
+		wantErr   bool
+	}{
+		{def: "dl.google.com=http://proxy:3128", wantHost: "dl.google.com", wantProxy: "http://proxy:3128"},
+		{def: " .Corp.Example = direct ", wantHost: ".corp.example", wantProxy: "direct"},
+		{def: "*=proxy:3128", wantHost: "*", wantProxy: "proxy:3128"},
+		{def: "dl.google.com", wantErr: true},
+		{def: "=http://proxy", wantErr: true},
+		{def: "dl.google.com=http://", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		host, proxy, err := ParseProxyOverride(tt.def)
+		if (err != nil) != tt.wantErr || host != tt.wantHost || proxy != tt.wantProxy {
+			t.Errorf("ParseProxyOverride(%q) = %q, %q, %v", tt.def, host, proxy, err)
+		}
+	}
+}
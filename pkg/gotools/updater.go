@@ -0,0 +1,296 @@
+package gotools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Status describes the installed and the latest available Go version
+type Status struct {
+	// Installed is the version in the installation directory, empty if Go
+	// isn't installed
+	Installed   string
+	Latest      string
+	NeedsUpdate bool
+	// Release is the metadata of the latest release
+	Release *GoRelease
+	// Vulnerabilities are the known vulnerabilities of the installed version,
+	// only set if the updater has a vulnerability database
+	Vulnerabilities []Vulnerability
+	// Tools are the results of installing the updater's tools, set by Update
+	Tools []ToolResult
+}
+
+// Vulnerable reports whether the installed version has known vulnerabilities
+func (s *Status) Vulnerable() bool {
+	return len(s.Vulnerabilities) > 0
+}
+
+// Stage is a step of the update pipeline
+type Stage string
+
+const (
+	StageCheck     Stage = "check"
+	StageLock      Stage = "lock"
+	StagePreflight Stage = "preflight"
+	StageDownload  Stage = "download"
+	StageVerify    Stage = "verify"
+	StageInstall   Stage = "install"
+	StageHook      Stage = "hook"
+	// StageOther is the stage of errors outside of the pipeline
+	StageOther Stage = "other"
+)
+
+// StageError is the error of a failed pipeline stage
+type StageError struct {
+	Stage Stage
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// stageError wraps err, if it isn't nil, in a StageError for stage
+func stageError(stage Stage, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &StageError{Stage: stage, Err: err}
+}
+
+// FailedStage returns the pipeline stage err happened in, StageOther if it
+// isn't a StageError
+func FailedStage(err error) Stage {
+	var stageErr *StageError
+	if errors.As(err, &stageErr) {
+		return stageErr.Stage
+	}
+	return StageOther
+}
+
+// Updater runs the check-download-verify-install pipeline
+type Updater struct {
+	Checker    *Checker
+	Downloader *Downloader
+	Installer  *Installer
+	// Hooks are run at the defined points of the pipeline, may be nil
+	Hooks *Hooks
+	// WaitForLock waits for a concurrent update to finish instead of failing
+	WaitForLock bool
+	// VulnDB is used to look up vulnerabilities of the installed version, may be nil
+	VulnDB *VulnDB
+	// Tools are installed with the new Go after an update, tools failing to
+	// build don't fail the update
+	Tools []Tool
+	// Timeout bounds Update until the new Go is installed and verified, zero
+	// uses DefaultUpdateTimeout
+	Timeout time.Duration
+	// ToolsTimeout bounds the installation of Tools after that, zero uses
+	// DefaultToolsTimeout
+	ToolsTimeout time.Duration
+}
+
+const (
+	// DefaultUpdateTimeout bounds locking, downloading, verifying and
+	// installing a new Go
+	DefaultUpdateTimeout = time.Minute
+	// DefaultToolsTimeout bounds the installation of tools, building them on
+	// a new machine takes minutes
+	DefaultToolsTimeout = 30 * time.Minute
+)
+
+// NewUpdater creates an updater with default checker, downloader and installer
+func NewUpdater() (*Updater, error) {
+	installer, err := NewInstaller()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create installer: %w", err)
+	}
+
+	return &Updater{
+		Checker:    NewChecker(),
+		Downloader: NewDownloader(),
+		Installer:  installer,
+		Hooks:      NewHooks(),
+	}, nil
+}
+
+// SetLogger makes all parts of the pipeline log to logger
+func (u *Updater) SetLogger(logger *slog.Logger) {
+	u.Checker.Logger = logger
+	u.Downloader.Logger = logger
+	u.Installer.Logger = logger
+	if u.Hooks != nil {
+		u.Hooks.Logger = logger
+	}
+}
+
+// SetHTTPClient makes the checker and the downloader share client
+func (u *Updater) SetHTTPClient(client *http.Client) {
+	u.Checker.SetHTTPClient(client)
+	u.Downloader.SetHTTPClient(client)
+}
+
+// Check compares the version in the installation directory with the latest
+// stable release. Without an installation the installed version is empty and
+// an update is needed.
+func (u *Updater) Check(ctx context.Context) (*Status, error) {
+	installed, err := u.Installer.InstalledVersion()
+	if errors.Is(err, fs.ErrNotExist) {
+		installed = ""
+	} else if err != nil {
+		return nil, stageError(StageCheck, fmt.Errorf("failed to determine installed version: %w", err))
+	}
+
+	release, err := u.Checker.GetLatestRelease(ctx)
+	if err != nil {
+		return nil, stageError(StageCheck, fmt.Errorf("failed to get latest version: %w", err))
+	}
+	latest := strings.TrimPrefix(release.Version, "go")
+
+	needsUpdate, err := u.Checker.NeedsUpdate(installed, latest)
+	if err != nil {
+		return nil, stageError(StageCheck, fmt.Errorf("failed to check if update is needed: %w", err))
+	}
+
+	status := &Status{
+		Installed:   installed,
+		Latest:      latest,
+		NeedsUpdate: needsUpdate,
+		Release:     release,
+	}
+
+	if u.VulnDB != nil && installed != "" {
+		status.Vulnerabilities, err = u.VulnDB.Affecting(installed)
+		if err != nil {
+			return nil, stageError(StageCheck, fmt.Errorf("failed to look up vulnerabilities: %w", err))
+		}
+	}
+
+	return status, nil
+}
+
+// Update downloads, verifies and installs the latest version from status.
+// The installation directory is locked for the whole pipeline, the on-failure
+// hooks run if any stage fails. The downloaded archive is removed afterwards,
+// whether the update succeeded or not.
+//
+// ctx is meant to be cancelled on Ctrl-C and nothing else: Update applies
+// Timeout and ToolsTimeout to its stages itself, so that the tools and the
+// hooks after the installation don't fail on the deadline of the download.
+func (u *Updater) Update(ctx context.Context, status *Status) (err error) {
+	installCtx, cancel := context.WithTimeout(ctx, u.timeout())
+	defer cancel()
+
+	lock, err := u.Installer.Lock(installCtx, u.WaitForLock)
+	if err != nil {
+		return stageError(StageLock, fmt.Errorf("failed to lock installation: %w", err))
+	}
+	defer func() {
+		if releaseErr := lock.Release(); err == nil && releaseErr != nil {
+			err = stageError(StageLock, fmt.Errorf("failed to unlock installation: %w", releaseErr))
+		}
+	}()
+
+	defer func() {
+		if cleanupErr := u.Downloader.Cleanup(); cleanupErr != nil {
+			loggerOrDefault(u.Downloader.Logger).Warn("Failed to remove temporary files", "error", cleanupErr)
+		}
+	}()
+
+	env := HookEnv{OldVersion: status.Installed, NewVersion: status.Latest}
+
+	if err := u.install(installCtx, status, env); err != nil {
+		return u.Hooks.RunOnFailure(ctx, env, err)
+	}
+	cancel()
+
+	// The tools are extras that can be retried later.
+	if len(u.Tools) > 0 {
+		toolsCtx, cancelTools := context.WithTimeout(ctx, u.toolsTimeout())
+		var err error
+		if status.Tools, err = u.Installer.InstallTools(toolsCtx, u.Tools); err != nil {
+			loggerOrDefault(u.Installer.Logger).Warn("Failed to install tools", "error", err)
+		}
+		cancelTools()
+	}
+
+	if err := u.Hooks.Run(ctx, HookAfterInstall, env); err != nil {
+		return u.Hooks.RunOnFailure(ctx, env, stageError(StageHook, err))
+	}
+
+	return nil
+}
+
+// install runs the pipeline up to the verified installation of the new Go
+func (u *Updater) install(ctx context.Context, status *Status, env HookEnv) error {
+	if status.Release != nil {
+		if err := u.Preflight(status.Release); err != nil {
+			return stageError(StagePreflight, fmt.Errorf("preflight failed: %w", err))
+		}
+	}
+
+	if err := u.Hooks.Run(ctx, HookBeforeDownload, env); err != nil {
+		return stageError(StageHook, err)
+	}
+
+	path, err := u.Downloader.Download(ctx, env.NewVersion)
+	if err != nil {
+		return stageError(StageDownload, fmt.Errorf("failed to download latest version: %w", err))
+	}
+
+	verified, err := u.Downloader.VerifyChecksum(ctx, path, env.NewVersion)
+	if err != nil {
+		return stageError(StageVerify, fmt.Errorf("failed to verify downloaded version: %w", err))
+	}
+	if !verified {
+		return stageError(StageVerify, fmt.Errorf("downloaded version could not be verified"))
+	}
+
+	if err := u.Hooks.Run(ctx, HookAfterVerify, env); err != nil {
+		return stageError(StageHook, err)
+	}
+
+	u.Installer.BeforeSwap = func(ctx context.Context) error {
+		return stageError(StageHook, u.Hooks.Run(ctx, HookBeforeSwap, env))
+	}
+	if err := u.Installer.Install(ctx, path); err != nil {
+		// A failing before-swap hook keeps its stage.
+		if FailedStage(err) == StageOther {
+			err = stageError(StageInstall, err)
+		}
+		return fmt.Errorf("failed to install Go: %w", err)
+	}
+
+	if err := u.Installer.Verify(ctx); err != nil {
+		return stageError(StageInstall, fmt.Errorf("failed to verify installation: %w", err))
+	}
+
+	return nil
+}
+
+// timeout returns Timeout, defaulting to DefaultUpdateTimeout
+func (u *Updater) timeout() time.Duration {
+	if u.Timeout <= 0 {
+		return DefaultUpdateTimeout
+	}
+	return u.Timeout
+}
+
+// toolsTimeout returns ToolsTimeout, defaulting to DefaultToolsTimeout
+func (u *Updater) toolsTimeout() time.Duration {
+	if u.ToolsTimeout <= 0 {
+		return DefaultToolsTimeout
+	}
+	return u.ToolsTimeout
+}
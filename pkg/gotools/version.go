@@ -6,83 +6,221 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"runtime"
 	"strconv"
 	"strings"
-
-	"k8s.io/apimachinery/pkg/util/wait"
+	"time"
 )
 
 // GoRelease represents a Go release from the official download page
 type GoRelease struct {
-	Version string `json:"version"`
-	Stable  bool   `json:"stable"`
+	Version string   `json:"version"`
+	Stable  bool     `json:"stable"`
+	Files   []GoFile `json:"files"`
+}
+
+// GoFile represents a downloadable file of a Go release
+type GoFile struct {
+	Filename string `json:"filename"`
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+	SHA256   string `json:"sha256"`
+	Size     int64  `json:"size"`
+	Kind     string `json:"kind"`
+}
+
+// Archive returns the binary archive of the release that Downloader fetches,
+// or nil if the release metadata doesn't list it.
+func (r *GoRelease) Archive() *GoFile {
+	filename := archiveFilename(strings.TrimPrefix(r.Version, "go"))
+	for i := range r.Files {
+		if r.Files[i].Filename == filename {
+			return &r.Files[i]
+		}
+	}
+	return nil
 }
 
 // Checker provides methods to check Go versions
 type Checker struct {
-	goVersionURL string
-	client       *http.Client
+	// ReleasesURL serves the release metadata in the format of DefaultReleasesURL
+	ReleasesURL string
+	// Retry controls how failed requests are retried
+	Retry  RetryPolicy
+	client *http.Client
+	// Logger receives progress and retry messages
+	Logger *slog.Logger
+	// CachePath is the file the release metadata is cached in, empty
+	// disables the cache
+	CachePath string
+	// MaxAge is how long the cached release metadata is used without asking
+	// the server, zero always revalidates it
+	MaxAge time.Duration
+	// RevalidateTimeout bounds the revalidation of cached release metadata,
+	// so that there is time left to fall back to the cached copy. Zero uses
+	// DefaultRevalidateTimeout.
+	RevalidateTimeout time.Duration
 }
 
+// DefaultReleasesURL lists the current Go releases as JSON
+const DefaultReleasesURL = "https://golang.org/dl/?mode=json"
+
 // NewChecker creates a new version checker with properly configured HTTP client
 func NewChecker() *Checker {
 	return &Checker{
-		goVersionURL: "https://golang.org/dl/?mode=json",
-		client:       NewHTTPClient(),
+		ReleasesURL: DefaultReleasesURL,
+		client:      NewHTTPClient(),
+		Logger:      slog.Default(),
 	}
 }
 
-// GetInstalledVersion checks the currently installed Go version using runtime.Version()
+// SetHTTPClient makes the checker use client, e.g. one from NewHTTPClientWithOptions
+func (c *Checker) SetHTTPClient(client *http.Client) {
+	c.client = client
+}
+
+// GetInstalledVersion returns the version of the Go that built the running
+// binary, see Installer.InstalledVersion for the managed installation
 func (c *Checker) GetInstalledVersion() string {
 	return strings.TrimPrefix(runtime.Version(), "go")
 }
 
 // GetLatestVersion fetches the latest stable Go release version
 func (c *Checker) GetLatestVersion(ctx context.Context) (string, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.goVersionURL, nil)
+	release, err := c.GetLatestRelease(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return "", err
+	}
+
+	return strings.TrimPrefix(release.Version, "go"), nil
+}
+
+// Channel selects the releases considered by GetLatestReleaseIn
+type Channel string
+
+const (
+	// ChannelStable are the stable releases
+	ChannelStable Channel = "stable"
+	// ChannelPrerelease are all releases including betas and release candidates
+	ChannelPrerelease Channel = "prerelease"
+)
+
+// ParseChannel parses a channel name
+func ParseChannel(name string) (Channel, error) {
+	switch channel := Channel(name); channel {
+	case ChannelStable, ChannelPrerelease:
+		return channel, nil
+	default:
+		return "", fmt.Errorf("unknown channel %q: must be %s or %s", name, ChannelStable, ChannelPrerelease)
 	}
+}
+
+// GetLatestRelease fetches the metadata of the latest stable Go release
+func (c *Checker) GetLatestRelease(ctx context.Context) (*GoRelease, error) {
+	return c.GetLatestReleaseIn(ctx, ChannelStable)
+}
 
-	releases, err := c.getReleasesWithRetry(ctx, req)
+// GetLatestReleaseIn fetches the metadata of the latest Go release in channel
+func (c *Checker) GetLatestReleaseIn(ctx context.Context, channel Channel) (*GoRelease, error) {
+	listURL, err := c.releasesURL(channel)
+	if err != nil {
+		return nil, err
+	}
+	releases, err := c.releases(ctx, listURL)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch releases: %w", err)
+		return nil, fmt.Errorf("failed to fetch releases: %w", err)
 	}
 
-	// Pick first stable release. Assume that it is ordered properly by version.
+	// Pick the first matching release. Assume that it is ordered properly by version.
 	for _, release := range releases {
-		if release.Stable && strings.HasPrefix(release.Version, "go") {
-			return strings.TrimPrefix(release.Version, "go"), nil
+		if (release.Stable || channel == ChannelPrerelease) && strings.HasPrefix(release.Version, "go") {
+			return &release, nil
 		}
 	}
 
-	return "", fmt.Errorf("no stable Go releases found")
+	return nil, fmt.Errorf("no %s Go releases found", channel)
+}
+
+// releasesURL returns the URL listing the releases of channel. ReleasesURL
+// only lists the current stable releases, include=all adds the older ones
+// along with betas and release candidates.
+func (c *Checker) releasesURL(channel Channel) (string, error) {
+	if channel != ChannelPrerelease {
+		return c.ReleasesURL, nil
+	}
+
+	u, err := url.Parse(c.ReleasesURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid releases URL %q: %w", c.ReleasesURL, err)
+	}
+	query := u.Query()
+	query.Set("include", "all")
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// releaseResponse is a response of ReleasesURL
+type releaseResponse struct {
+	body []byte
+	// notModified is set if the server confirmed the cached copy
+	notModified  bool
+	etag         string
+	lastModified string
 }
 
-// getReleasesWithRetry tries to fetch the Go releases with retries based on interval and timeout.
-func (c *Checker) getReleasesWithRetry(ctx context.Context, req *http.Request) ([]GoRelease, error) {
+// getReleasesWithRetry tries to fetch the Go releases with retries based on
+// interval and timeout. The request is conditional on cached, if it is set.
+func (c *Checker) getReleasesWithRetry(ctx context.Context, listURL string, cached *ReleaseCache) (*releaseResponse, error) {
 	// lastErrSeen is used to store the last error encountered during retries.
 	var lastErrSeen error
-	// body stores the response body from the HTTP request. Can be nil in err case.
-	var body io.ReadCloser
-	defer safeClose(body)
+	var response *releaseResponse
+
+	timeoutErr := c.Retry.poll(ctx, func(ctx context.Context) (bool, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, listURL, nil)
+		if err != nil {
+			return false, fmt.Errorf("failed to create request: %w", err)
+		}
+		if cached != nil {
+			if cached.ETag != "" {
+				req.Header.Set("If-None-Match", cached.ETag)
+			}
+			if cached.LastModified != "" {
+				req.Header.Set("If-Modified-Since", cached.LastModified)
+			}
+		}
 
-	timeoutErr := wait.PollUntilContextTimeout(ctx, interval, timeout, immediate, func(ctx context.Context) (bool, error) {
 		resp, err := c.client.Do(req)
 		if err != nil {
 			lastErrSeen = err
+			loggerOrDefault(c.Logger).Debug("Fetching release metadata failed, retrying", "error", err)
 			return false, nil
 		}
+		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusOK {
+		switch {
+		case resp.StatusCode == http.StatusNotModified && cached != nil:
+			response = &releaseResponse{notModified: true}
+			return true, nil
+		case resp.StatusCode != http.StatusOK:
 			lastErrSeen = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+			loggerOrDefault(c.Logger).Debug("Fetching release metadata failed, retrying", "error", lastErrSeen)
 			return false, nil
 		}
 
-		body = resp.Body
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			lastErrSeen = fmt.Errorf("failed to read response: %w", err)
+			return false, nil
+		}
 
+		response = &releaseResponse{
+			body:         body,
+			etag:         resp.Header.Get("ETag"),
+			lastModified: resp.Header.Get("Last-Modified"),
+		}
 		return true, nil
 	})
 
@@ -94,8 +232,13 @@ func (c *Checker) getReleasesWithRetry(ctx context.Context, req *http.Request) (
 		return nil, fmt.Errorf("failed to fetch version info: %w", timeoutErr)
 	}
 
+	return response, nil
+}
+
+// parseReleases parses the release metadata served by ReleasesURL
+func parseReleases(body []byte) ([]GoRelease, error) {
 	var releases []GoRelease
-	if err := json.NewDecoder(body).Decode(&releases); err != nil {
+	if err := json.Unmarshal(body, &releases); err != nil {
 		return nil, fmt.Errorf("failed to parse version info: %w", err)
 	}
 
@@ -172,3 +315,47 @@ func (c *Checker) NeedsUpdate(installed, latest string) (bool, error) {
 	// Versions are equal
 	return false, nil
 }
+
+// compareVersions compares two Go versions such as "1.24.1" and returns -1, 0
+// or +1. Missing parts count as zero, so "1.20" equals "1.20.0".
+func compareVersions(a, b string) (int, error) {
+	partsA, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	partsB, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range partsA {
+		if partsA[i] != partsB[i] {
+			if partsA[i] < partsB[i] {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+
+	return 0, nil
+}
+
+// parseVersion splits a version like "1.24.1" into major, minor and patch
+func parseVersion(version string) ([3]int, error) {
+	var parts [3]int
+
+	split := strings.Split(strings.TrimPrefix(version, "go"), ".")
+	if len(split) < 2 || len(split) > 3 {
+		return parts, fmt.Errorf("invalid version format %q", version)
+	}
+
+	for i, part := range split {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return parts, fmt.Errorf("invalid version %q: %w", version, err)
+		}
+		parts[i] = n
+	}
+
+	return parts, nil
+}
@@ -123,7 +123,7 @@ func TestGetLatestVersion(t *testing.T) {
 
 	// Create a checker that uses the test server
 	checker := NewChecker()
-	checker.goVersionURL = server.URL
+	checker.ReleasesURL = server.URL
 
 	// Test with a context
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -139,3 +139,50 @@ func TestGetLatestVersion(t *testing.T) {
 		t.Errorf("GetLatestVersion() = %v, want %v", version, expected)
 	}
 }
+
+func TestGoReleaseArchive(t *testing.T) {
+	release := GoRelease{
+		Version: "go1.24.1",
+		Stable:  true,
+		Files: []GoFile{
+			{Filename: "go1.24.1.src.tar.gz", Kind: "source", Size: 30000000},
+			{Filename: "go1.24.1.linux-amd64.tar.gz", OS: "linux", Arch: "amd64", Kind: "archive", Size: 75000000},
+		},
+	}
+
+	archive := release.Archive()
+	if archive == nil {
+		t.Fatal("Archive() = nil, want linux-amd64 archive")
+	}
+	if archive.Size != 75000000 {
+		t.Errorf("Archive().Size = %d, want %d", archive.Size, 75000000)
+	}
+
+	release.Files = release.Files[:1]
+	if archive := release.Archive(); archive != nil {
+		t.Errorf("Archive() = %+v, want nil", archive)
+	}
+}
+
+func TestReleasesURL(t *testing.T) {
+	tests := []struct {
+		channel Channel
+		want    string
+	}{
+		{channel: ChannelStable, want: DefaultReleasesURL},
+		{channel: ChannelPrerelease, want: "https://golang.org/dl/?include=all&mode=json"},
+	}
+
+	checker := NewChecker()
+	for _, tt := range tests {
+		t.Run(string(tt.channel), func(t *testing.T) {
+			got, err := checker.releasesURL(tt.channel)
+			if err != nil {
+				t.Fatalf("releasesURL(%s) error = %v", tt.channel, err)
+			}
+			if got != tt.want {
+				t.Errorf("releasesURL(%s) = %q, want %q", tt.channel, got, tt.want)
+			}
+		})
+	}
+}
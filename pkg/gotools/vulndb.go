@@ -0,0 +1,243 @@
+package gotools
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Modules under which the Go vulnerability database files standard library
+// and toolchain vulnerabilities
+const (
+	vulnModuleStdlib    = "stdlib"
+	vulnModuleToolchain = "toolchain"
+)
+
+// Vulnerability is a known vulnerability of a Go release
+type Vulnerability struct {
+	// ID is the Go vulnerability ID, e.g. "GO-2024-2887"
+	ID string
+	// Aliases are other IDs of the vulnerability, e.g. CVEs
+	Aliases []string
+	Summary string
+	// Module is "stdlib" or "toolchain"
+	Module string
+	// Packages are the affected packages, empty if unknown
+	Packages []string
+	// Fixed is the first release fixing the vulnerability, empty if unfixed
+	Fixed string
+}
+
+// osvEntry is the subset of an OSV entry that is relevant for Go releases,
+// see https://go.dev/doc/security/vuln/database#schema
+type osvEntry struct {
+	ID       string   `json:"id"`
+	Aliases  []string `json:"aliases"`
+	Summary  string   `json:"summary"`
+	Affected []struct {
+		Package struct {
+			Name      string `json:"name"`
+			Ecosystem string `json:"ecosystem"`
+		} `json:"package"`
+		Ranges []struct {
+			Type   string `json:"type"`
+			Events []struct {
+				Introduced string `json:"introduced"`
+				Fixed      string `json:"fixed"`
+			} `json:"events"`
+		} `json:"ranges"`
+		EcosystemSpecific struct {
+			Imports []struct {
+				Path string `json:"path"`
+			} `json:"imports"`
+		} `json:"ecosystem_specific"`
+	} `json:"affected"`
+}
+
+// VulnDB is a local copy of the Go vulnerability database
+type VulnDB struct {
+	entries []osvEntry
+}
+
+// OpenVulnDB reads the standard library and toolchain entries of a Go
+// vulnerability database. path is a directory or a zip file in the layout of
+// https://vuln.go.dev/vulndb.zip, optionally as a file:// URL like govulncheck
+// accepts it.
+func OpenVulnDB(path string) (*VulnDB, error) {
+	if strings.HasPrefix(path, "file://") {
+		u, err := url.Parse(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vulnerability database URL %q: %w", path, err)
+		}
+		path = u.Path
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vulnerability database: %w", err)
+	}
+
+	if info.IsDir() {
+		return readVulnDB(os.DirFS(path))
+	}
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vulnerability database %s: %w", path, err)
+	}
+	defer archive.Close()
+
+	return readVulnDB(archive)
+}
+
+// readVulnDB reads all entries below an "ID" directory of fsys
+func readVulnDB(fsys fs.FS) (*VulnDB, error) {
+	db := &VulnDB{}
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(name) != ".json" || path.Base(path.Dir(name)) != "ID" {
+			return nil
+		}
+
+		entry, err := readOSVEntry(fsys, name)
+		if err != nil {
+			return err
+		}
+		if entry.affectsGo() {
+			db.entries = append(db.entries, *entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vulnerability database: %w", err)
+	}
+	if len(db.entries) == 0 {
+		return nil, fmt.Errorf("no standard library entries found in vulnerability database")
+	}
+
+	return db, nil
+}
+
+func readOSVEntry(fsys fs.FS, name string) (*osvEntry, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	var entry osvEntry
+	if err := json.Unmarshal(content, &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+
+	return &entry, nil
+}
+
+func (e *osvEntry) affectsGo() bool {
+	for _, affected := range e.Affected {
+		if name := affected.Package.Name; name == vulnModuleStdlib || name == vulnModuleToolchain {
+			return true
+		}
+	}
+	return false
+}
+
+// Affecting returns the vulnerabilities of the standard library and toolchain
+// of the given Go version, ordered by ID.
+func (db *VulnDB) Affecting(version string) ([]Vulnerability, error) {
+	if _, err := parseVersion(version); err != nil {
+		return nil, err
+	}
+
+	var vulns []Vulnerability
+	for _, entry := range db.entries {
+		for _, affected := range entry.Affected {
+			module := affected.Package.Name
+			if module != vulnModuleStdlib && module != vulnModuleToolchain {
+				continue
+			}
+
+			for _, r := range affected.Ranges {
+				if r.Type != "SEMVER" {
+					continue
+				}
+
+				// Events alternate between introduced and fixed in version order.
+				introduced, vulnerable := false, false
+				fixed := ""
+				for _, event := range r.Events {
+					switch {
+					case event.Introduced != "":
+						introduced = event.Introduced == "0" || compareSemver(version, event.Introduced) >= 0
+					case event.Fixed != "" && introduced:
+						if compareSemver(version, event.Fixed) < 0 {
+							vulnerable, fixed = true, event.Fixed
+						}
+						introduced = false
+					}
+					if vulnerable {
+						break
+					}
+				}
+				// Introduced without a fix: still vulnerable in the latest release.
+				if !vulnerable && !introduced {
+					continue
+				}
+
+				vuln := Vulnerability{
+					ID:      entry.ID,
+					Aliases: entry.Aliases,
+					Summary: entry.Summary,
+					Module:  module,
+					Fixed:   fixed,
+				}
+				for _, imp := range affected.EcosystemSpecific.Imports {
+					vuln.Packages = append(vuln.Packages, imp.Path)
+				}
+				vulns = append(vulns, vuln)
+				break
+			}
+		}
+	}
+
+	slices.SortFunc(vulns, func(a, b Vulnerability) int { return strings.Compare(a.ID, b.ID) })
+	return slices.CompactFunc(vulns, func(a, b Vulnerability) bool { return a.ID == b.ID }), nil
+}
+
+// compareSemver compares Go versions in the semver notation of the
+// vulnerability database, where "1.22.0-0" precedes any 1.22 release.
+// Unparsable versions compare as equal, as they can't be ordered anyway.
+func compareSemver(a, b string) int {
+	baseA, preA, _ := strings.Cut(a, "-")
+	baseB, preB, _ := strings.Cut(b, "-")
+
+	if c, err := compareVersions(baseA, baseB); err != nil || c != 0 {
+		return c
+	}
+
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	default:
+		return strings.Compare(preA, preB)
+	}
+}
@@ -0,0 +1,201 @@
+package gotools
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// testVulnEntries are OSV entries in the format of vuln.go.dev
+var testVulnEntries = map[string]string{
+	"ID/GO-2024-2887.json": `{
+		"id": "GO-2024-2887",
+		"aliases": ["CVE-2024-24790"],
+		"summary": "Unexpected behavior from Is methods for IPv4-mapped IPv6 addresses in net/netip",
+		"affected": [{
+			"package": {"name": "stdlib", "ecosystem": "Go"},
+			"ranges": [{"type": "SEMVER", "events": [
+				{"introduced": "0"}, {"fixed": "1.21.11"},
+				{"introduced": "1.22.0-0"}, {"fixed": "1.22.4"}
+			]}],
+			"ecosystem_specific": {"imports": [{"path": "net/netip"}]}
+		}]
+	}`,
+	"ID/GO-2024-2963.json": `{
+		"id": "GO-2024-2963",
+		"aliases": ["CVE-2024-24791"],
+		"summary": "Denial of service due to improper 100-continue handling in net/http",
+		"affected": [{
+			"package": {"name": "stdlib", "ecosystem": "Go"},
+			"ranges": [{"type": "SEMVER", "events": [
+				{"introduced": "0"}, {"fixed": "1.21.12"},
+				{"introduced": "1.22.0-0"}, {"fixed": "1.22.5"}
+			]}],
+			"ecosystem_specific": {"imports": [{"path": "net/http"}]}
+		}]
+	}`,
+	"ID/GO-2023-1840.json": `{
+		"id": "GO-2023-1840",
+		"summary": "Unsafe behavior in setuid/setgid binaries in runtime",
+		"affected": [
+			{
+				"package": {"name": "stdlib", "ecosystem": "Go"},
+				"ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "1.19.10"}, {"introduced": "1.20.0-0"}, {"fixed": "1.20.5"}]}]
+			},
+			{
+				"package": {"name": "toolchain", "ecosystem": "Go"},
+				"ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "1.19.10"}, {"introduced": "1.20.0-0"}, {"fixed": "1.20.5"}]}]
+			}
+		]
+	}`,
+	"ID/GO-2099-0001.json": `{
+		"id": "GO-2099-0001",
+		"summary": "Unfixed toolchain vulnerability",
+		"affected": [{
+			"package": {"name": "toolchain", "ecosystem": "Go"},
+			"ranges": [{"type": "SEMVER", "events": [{"introduced": "1.22.0"}]}]
+		}]
+	}`,
+	"ID/GO-2022-0191.json": `{
+		"id": "GO-2022-0191",
+		"summary": "Third party module vulnerability",
+		"affected": [{
+			"package": {"name": "github.com/example/module", "ecosystem": "Go"},
+			"ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}]}]
+		}]
+	}`,
+	"index/db.json": `{"modified": "2024-07-02T00:00:00Z"}`,
+}
+
+func writeTestVulnDir(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range testVulnEntries {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func writeTestVulnZip(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "vulndb.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for name, content := range testVulnEntries {
+		entry, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := entry.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestVulnDBAffecting(t *testing.T) {
+	sources := map[string]string{
+		"directory": writeTestVulnDir(t),
+		"zip":       writeTestVulnZip(t),
+	}
+
+	tests := []struct {
+		version string
+		want    []string
+	}{
+		{version: "1.22.3", want: []string{"GO-2024-2887", "GO-2024-2963", "GO-2099-0001"}},
+		{version: "1.22.4", want: []string{"GO-2024-2963", "GO-2099-0001"}},
+		{version: "1.21.11", want: []string{"GO-2024-2963"}},
+		{version: "1.21.12", want: nil},
+		{version: "1.20.4", want: []string{"GO-2023-1840", "GO-2024-2887", "GO-2024-2963"}},
+	}
+
+	for source, path := range sources {
+		db, err := OpenVulnDB(path)
+		if err != nil {
+			t.Fatalf("OpenVulnDB(%s) error = %v", source, err)
+		}
+
+		for _, tt := range tests {
+			vulns, err := db.Affecting(tt.version)
+			if err != nil {
+				t.Fatalf("Affecting(%q) error = %v", tt.version, err)
+			}
+
+			var ids []string
+			for _, vuln := range vulns {
+				ids = append(ids, vuln.ID)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("%s: Affecting(%q) = %v, want %v", source, tt.version, ids, tt.want)
+			}
+		}
+	}
+}
+
+func TestVulnDBAffectingDetails(t *testing.T) {
+	db, err := OpenVulnDB("file://" + writeTestVulnDir(t))
+	if err != nil {
+		t.Fatalf("OpenVulnDB() error = %v", err)
+	}
+
+	vulns, err := db.Affecting("1.22.3")
+	if err != nil {
+		t.Fatalf("Affecting() error = %v", err)
+	}
+
+	netip := vulns[0]
+	if netip.Fixed != "1.22.4" || netip.Module != "stdlib" ||
+		!slices.Equal(netip.Packages, []string{"net/netip"}) || !slices.Equal(netip.Aliases, []string{"CVE-2024-24790"}) {
+		t.Errorf("unexpected vulnerability %+v", netip)
+	}
+
+	if unfixed := vulns[2]; unfixed.Fixed != "" || unfixed.Module != "toolchain" {
+		t.Errorf("unexpected unfixed vulnerability %+v", unfixed)
+	}
+}
+
+func TestOpenVulnDBErrors(t *testing.T) {
+	if _, err := OpenVulnDB(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("OpenVulnDB() expected error for missing database")
+	}
+	if _, err := OpenVulnDB(t.TempDir()); err == nil {
+		t.Error("OpenVulnDB() expected error for empty database")
+	}
+}
+
+func TestCompareSemver(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "1.22.0", b: "1.22.0-0", want: 1},
+		{a: "1.21.12", b: "1.22.0-0", want: -1},
+		{a: "1.22.0-0", b: "1.22.0-0", want: 0},
+		{a: "1.22.4", b: "1.22.4", want: 0},
+	}
+
+	for _, tt := range tests {
+		if have := compareSemver(tt.a, tt.b); have != tt.want {
+			t.Errorf("compareSemver(%q, %q) = %d, want %d", tt.a, tt.b, have, tt.want)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package gotools
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Announcement is a new Go release the user is told about
+type Announcement struct {
+	Channel Channel `json:"channel"`
+	Version string  `json:"version"`
+	// Installed is the installed version, empty if Go isn't installed
+	Installed string `json:"installed,omitempty"`
+}
+
+// Message describes the announcement for humans
+func (a Announcement) Message() string {
+	if a.Installed == "" {
+		return fmt.Sprintf("Go %s is available, run updatego to install it", a.Version)
+	}
+	return fmt.Sprintf("Go %s is available (installed: %s), run updatego to install it", a.Version, a.Installed)
+}
+
+// Notifier tells the user about a new release
+type Notifier interface {
+	Notify(ctx context.Context, announcement Announcement) error
+}
+
+// clearer is a Notifier whose announcement stays until it is cleared
+type clearer interface {
+	Clear() error
+}
+
+// WatchState is what a Watcher remembers between runs
+type WatchState struct {
+	// LastCheck is when the releases were last checked
+	LastCheck time.Time `json:"lastCheck"`
+	// Announced are the versions the user was already told about
+	Announced []string `json:"announced"`
+}
+
+// maxAnnounced bounds the versions kept in the watch state
+const maxAnnounced = 50
+
+// Watcher checks for new releases in a channel without installing them and
+// announces every new version once
+type Watcher struct {
+	Checker   *Checker
+	Installer *Installer
+	Channel   Channel
+	// StatePath is the file the watch state is kept in, empty keeps no state
+	// and announces new releases on every check
+	StatePath string
+	// Interval is the minimum time between two checks, zero checks every time
+	Interval time.Duration
+	// Notifiers are told about new releases
+	Notifiers []Notifier
+	// Clock decides whether a check is due, nil uses the system clock
+	Clock Clock
+	// Logger receives progress messages
+	Logger *slog.Logger
+}
+
+// DefaultWatchStatePath returns the watch state in $XDG_STATE_HOME,
+// ~/.local/state by default
+func DefaultWatchStatePath() (string, error) {
+	stateDir := os.Getenv("XDG_STATE_HOME")
+	if stateDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to determine state directory: %w", err)
+		}
+		stateDir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(stateDir, "go-scripts", "watch.json"), nil
+}
+
+// now returns the time of Clock, defaulting to the system clock
+func (w *Watcher) now() time.Time {
+	if w.Clock == nil {
+		return time.Now()
+	}
+	return w.Clock.Now()
+}
+
+// Watch checks for a new release once, unless the last check was less than
+// Interval ago. It returns the announcement of a new release, or nil if
+// there is none or it was announced before.
+func (w *Watcher) Watch(ctx context.Context) (*Announcement, error) {
+	logger := loggerOrDefault(w.Logger)
+
+	state, err := w.loadState()
+	if err != nil {
+		return nil, err
+	}
+
+	now := w.now()
+	if w.Interval > 0 && now.Sub(state.LastCheck) < w.Interval {
+		logger.Debug("Skipping check", "lastCheck", state.LastCheck, "interval", w.Interval)
+		return nil, nil
+	}
+
+	channel := cmp.Or(w.Channel, ChannelStable)
+	release, err := w.Checker.GetLatestReleaseIn(ctx, channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest version: %w", err)
+	}
+	latest := strings.TrimPrefix(release.Version, "go")
+
+	installed, err := w.Installer.InstalledVersion()
+	if err != nil {
+		logger.Debug("No installed Go version", "error", err)
+		installed = ""
+	}
+
+	state.LastCheck = now
+	var announcement *Announcement
+	switch {
+	case !isNewerVersion(latest, installed):
+		logger.Debug("Go is up to date", "installed", installed, "latest", latest)
+		w.clear()
+	case slices.Contains(state.Announced, latest):
+		logger.Debug("Release was already announced", "version", latest)
+	default:
+		announcement = &Announcement{Channel: channel, Version: latest, Installed: installed}
+		if err := w.notify(ctx, *announcement); err != nil {
+			// Keep the release unannounced, the next check tries again.
+			return nil, err
+		}
+		state.Announced = append(state.Announced, latest)
+		if len(state.Announced) > maxAnnounced {
+			state.Announced = state.Announced[len(state.Announced)-maxAnnounced:]
+		}
+	}
+
+	if err := w.saveState(state); err != nil {
+		return announcement, err
+	}
+
+	return announcement, nil
+}
+
+// notify tells all notifiers about announcement. It only fails if no
+// notifier succeeded, the failures of the others are logged.
+func (w *Watcher) notify(ctx context.Context, announcement Announcement) error {
+	logger := loggerOrDefault(w.Logger)
+	logger.Info("New Go release", "version", announcement.Version, "installed", announcement.Installed, "channel", announcement.Channel)
+
+	var errs []error
+	for _, notifier := range w.Notifiers {
+		if err := notifier.Notify(ctx, announcement); err != nil {
+			logger.Warn("Failed to send notification", "notifier", fmt.Sprintf("%T", notifier), "error", err)
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 && len(errs) == len(w.Notifiers) {
+		return fmt.Errorf("failed to send notifications: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// clear removes lasting announcements of releases that are installed by now
+func (w *Watcher) clear() {
+	for _, notifier := range w.Notifiers {
+		if c, ok := notifier.(clearer); ok {
+			if err := c.Clear(); err != nil {
+				loggerOrDefault(w.Logger).Warn("Failed to clear notification", "error", err)
+			}
+		}
+	}
+}
+
+// loadState reads the watch state, a missing file is an empty state
+func (w *Watcher) loadState() (*WatchState, error) {
+	state := &WatchState{}
+	if w.StatePath == "" {
+		return state, nil
+	}
+
+	content, err := os.ReadFile(w.StatePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watch state: %w", err)
+	}
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, fmt.Errorf("failed to parse watch state %s: %w", w.StatePath, err)
+	}
+
+	return state, nil
+}
+
+// saveState writes the watch state
+func (w *Watcher) saveState(state *WatchState) error {
+	if w.StatePath == "" {
+		return nil
+	}
+
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode watch state: %w", err)
+	}
+	if err := writeFileAtomic(w.StatePath, content, 0644); err != nil {
+		return fmt.Errorf("failed to save watch state: %w", err)
+	}
+
+	return nil
+}
+
+// isNewerVersion reports whether candidate is newer than installed. Versions
+// that can't be compared, like release candidates, are newer if they differ.
+func isNewerVersion(candidate, installed string) bool {
+	if installed == "" {
+		return true
+	}
+	result, err := compareVersions(candidate, installed)
+	if err != nil {
+		return candidate != installed
+	}
+	return result > 0
+}
+
+// DesktopNotifier shows a desktop notification with notify-send, which talks
+// to the notification service over D-Bus
+type DesktopNotifier struct {
+	// Runner runs notify-send, nil runs it on the host
+	Runner CommandRunner
+}
+
+// Notify shows the announcement as a desktop notification
+func (n DesktopNotifier) Notify(ctx context.Context, announcement Announcement) error {
+	runner := n.Runner
+	if runner == nil {
+		runner = ExecRunner{}
+	}
+
+	output, err := runner.Run(ctx, "", nil, "notify-send", "--app-name=updatego", "--icon=software-update-available",
+		"Go "+announcement.Version+" released", announcement.Message())
+	if err != nil {
+		return fmt.Errorf("failed to run notify-send: %w: %s", err, bytes.TrimSpace(output))
+	}
+
+	return nil
+}
+
+// MOTDNotifier writes the announcement to a file shown by new terminals,
+// e.g. a file in /etc/motd.d or one printed by the shell's rc file
+type MOTDNotifier struct {
+	Path string
+}
+
+// Notify replaces the file with the announcement
+func (n MOTDNotifier) Notify(_ context.Context, announcement Announcement) error {
+	if err := writeFileAtomic(n.Path, []byte(announcement.Message()+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write MOTD file: %w", err)
+	}
+	return nil
+}
+
+// Clear removes the announcement once Go is up to date
+func (n MOTDNotifier) Clear() error {
+	if err := os.Remove(n.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove MOTD file: %w", err)
+	}
+	return nil
+}
+
+// WebhookNotifier POSTs the announcement as JSON to a URL. The message is in
+// the "text" field, which chat webhooks like Slack's or Mattermost's show.
+type WebhookNotifier struct {
+	URL    string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier for url using client, nil uses
+// NewHTTPClient
+func NewWebhookNotifier(url string, client *http.Client) *WebhookNotifier {
+	if client == nil {
+		client = NewHTTPClient()
+	}
+	return &WebhookNotifier{URL: url, client: client}
+}
+
+// Notify POSTs the announcement
+func (n *WebhookNotifier) Notify(ctx context.Context, announcement Announcement) error {
+	payload, err := json.Marshal(struct {
+		Text string `json:"text"`
+		Announcement
+	}{announcement.Message(), announcement})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
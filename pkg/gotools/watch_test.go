@@ -0,0 +1,277 @@
+package gotools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingNotifier records the announced versions
+type recordingNotifier struct {
+	announced []string
+	err       error
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, announcement Announcement) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.announced = append(n.announced, announcement.Version)
+	return nil
+}
+
+func TestWatcher(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		releases []GoRelease
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		// Like go.dev, unstable releases are only listed with include=all.
+		listed := releases
+		if r.URL.Query().Get("include") != "all" {
+			listed = slices.DeleteFunc(slices.Clone(releases), func(release GoRelease) bool { return !release.Stable })
+		}
+		json.NewEncoder(w).Encode(listed)
+	}))
+	defer server.Close()
+
+	installer, mem, _ := newMemInstaller(t)
+	checker := NewChecker()
+	checker.ReleasesURL = server.URL + "/dl/?mode=json"
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	notifier := &recordingNotifier{}
+	motd := MOTDNotifier{Path: filepath.Join(t.TempDir(), "motd")}
+	watcher := &Watcher{
+		Checker:   checker,
+		Installer: installer,
+		StatePath: filepath.Join(t.TempDir(), "state", "watch.json"),
+		Notifiers: []Notifier{notifier, motd},
+		Clock:     clock,
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	stable := func(version string) GoRelease { return GoRelease{Version: version, Stable: true} }
+
+	steps := []struct {
+		name      string
+		releases  []GoRelease
+		installed string
+		channel   Channel
+		interval  time.Duration
+		advance   time.Duration
+		notifyErr error
+		// want is the announced version, empty if there is no announcement
+		want     string
+		wantMOTD string
+	}{
+		{
+			name:     "new release",
+			releases: []GoRelease{stable("go1.24.0")},
+			want:     "1.24.0",
+			wantMOTD: "Go 1.24.0 is available (installed: 1.23.0), run updatego to install it\n",
+		},
+		{
+			name:     "already announced",
+			releases: []GoRelease{stable("go1.24.0")},
+			wantMOTD: "Go 1.24.0 is available (installed: 1.23.0), run updatego to install it\n",
+		},
+		{
+			name:     "check not due",
+			releases: []GoRelease{stable("go1.24.1")},
+			interval: 24 * time.Hour,
+			advance:  time.Hour,
+			wantMOTD: "Go 1.24.0 is available (installed: 1.23.0), run updatego to install it\n",
+		},
+		{
+			name:     "check due",
+			releases: []GoRelease{stable("go1.24.1")},
+			interval: 24 * time.Hour,
+			advance:  24 * time.Hour,
+			want:     "1.24.1",
+			wantMOTD: "Go 1.24.1 is available (installed: 1.23.0), run updatego to install it\n",
+		},
+		{
+			name:     "prerelease channel",
+			releases: []GoRelease{{Version: "go1.25rc1"}, stable("go1.24.1")},
+			channel:  ChannelPrerelease,
+			want:     "1.25rc1",
+			wantMOTD: "Go 1.25rc1 is available (installed: 1.23.0), run updatego to install it\n",
+		},
+		{
+			name:      "notifications failing",
+			releases:  []GoRelease{stable("go1.24.2")},
+			notifyErr: errors.New("unreachable"),
+			// The MOTD notifier succeeded.
+			want:     "1.24.2",
+			wantMOTD: "Go 1.24.2 is available (installed: 1.23.0), run updatego to install it\n",
+		},
+		{
+			name:      "installed",
+			releases:  []GoRelease{stable("go1.24.2")},
+			installed: "go1.24.2",
+		},
+	}
+
+	for _, step := range steps {
+		mu.Lock()
+		releases = step.releases
+		mu.Unlock()
+		if step.installed != "" {
+			mem.writeFile(t, "/home/gopher/.local/lib/go/VERSION", []byte(step.installed+"\n"), 0644)
+		}
+		clock.now = clock.now.Add(step.advance)
+		watcher.Channel = step.channel
+		watcher.Interval = step.interval
+		notifier.err = step.notifyErr
+		notifier.announced = nil
+
+		announcement, err := watcher.Watch(context.Background())
+		if err != nil {
+			t.Fatalf("%s: Watch() error = %v", step.name, err)
+		}
+
+		have := ""
+		if announcement != nil {
+			have = announcement.Version
+		}
+		if have != step.want {
+			t.Errorf("%s: Watch() announced %q, want %q", step.name, have, step.want)
+		}
+		if step.notifyErr == nil && step.want != "" && !slices.Equal(notifier.announced, []string{step.want}) {
+			t.Errorf("%s: notified %q, want %q", step.name, notifier.announced, step.want)
+		}
+		if step.want == "" && len(notifier.announced) > 0 {
+			t.Errorf("%s: notified %q, want nothing", step.name, notifier.announced)
+		}
+
+		content, err := os.ReadFile(motd.Path)
+		if step.wantMOTD == "" {
+			if !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("%s: MOTD = %q, %v, want it removed", step.name, content, err)
+			}
+		} else if string(content) != step.wantMOTD {
+			t.Errorf("%s: MOTD = %q, %v, want %q", step.name, content, err, step.wantMOTD)
+		}
+	}
+}
+
+func TestWatcherNotificationsFailing(t *testing.T) {
+	server := newReleaseServer(t, "go1.24.0")
+	installer, _, _ := newMemInstaller(t)
+	checker := NewChecker()
+	checker.ReleasesURL = server.URL
+	notifier := &recordingNotifier{err: errors.New("unreachable")}
+	watcher := &Watcher{
+		Checker:   checker,
+		Installer: installer,
+		StatePath: filepath.Join(t.TempDir(), "watch.json"),
+		Notifiers: []Notifier{notifier},
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	if _, err := watcher.Watch(context.Background()); err == nil {
+		t.Fatal("Watch() with failing notifiers expected an error")
+	}
+
+	// The release wasn't announced, the next check tries again.
+	notifier.err = nil
+	announcement, err := watcher.Watch(context.Background())
+	if err != nil || announcement == nil || announcement.Version != "1.24.0" {
+		t.Fatalf("Watch() = %+v, %v, want an announcement of 1.24.0", announcement, err)
+	}
+}
+
+func TestIsNewerVersion(t *testing.T) {
+	tests := []struct {
+		candidate string
+		installed string
+		want      bool
+	}{
+		{"1.24.1", "1.24.0", true},
+		{"1.24.0", "1.24.0", false},
+		{"1.23.5", "1.24.0", false},
+		{"1.24.0", "", true},
+		{"1.25rc1", "1.24.0", true},
+		{"1.25rc1", "1.25rc1", false},
+	}
+
+	for _, tt := range tests {
+		if got := isNewerVersion(tt.candidate, tt.installed); got != tt.want {
+			t.Errorf("isNewerVersion(%q, %q) = %v, want %v", tt.candidate, tt.installed, got, tt.want)
+		}
+	}
+}
+
+// recordingRunner records the commands it is asked to run
+type recordingRunner struct {
+	commands []string
+	err      error
+}
+
+func (r *recordingRunner) Run(_ context.Context, _ string, _ []string, name string, args ...string) ([]byte, error) {
+	r.commands = append(r.commands, strings.Join(append([]string{name}, args...), " "))
+	return nil, r.err
+}
+
+func TestNotifiers(t *testing.T) {
+	announcement := Announcement{Channel: ChannelStable, Version: "1.24.1", Installed: "1.24.0"}
+
+	t.Run("desktop", func(t *testing.T) {
+		runner := &recordingRunner{}
+		if err := (DesktopNotifier{Runner: runner}).Notify(context.Background(), announcement); err != nil {
+			t.Fatalf("Notify() error = %v", err)
+		}
+		want := "notify-send --app-name=updatego --icon=software-update-available Go 1.24.1 released " + announcement.Message()
+		if !slices.Equal(runner.commands, []string{want}) {
+			t.Errorf("commands = %q, want %q", runner.commands, want)
+		}
+
+		runner.err = errors.New("exit status 1")
+		if err := (DesktopNotifier{Runner: runner}).Notify(context.Background(), announcement); err == nil {
+			t.Error("Notify() with failing notify-send expected an error")
+		}
+	})
+
+	t.Run("webhook", func(t *testing.T) {
+		var payloads []map[string]string
+		status := http.StatusOK
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var payload map[string]string
+			if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&payload) != nil {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			payloads = append(payloads, payload)
+			w.WriteHeader(status)
+		}))
+		defer server.Close()
+
+		notifier := NewWebhookNotifier(server.URL, nil)
+		if err := notifier.Notify(context.Background(), announcement); err != nil {
+			t.Fatalf("Notify() error = %v", err)
+		}
+		want := map[string]string{"text": announcement.Message(), "channel": "stable", "version": "1.24.1", "installed": "1.24.0"}
+		if len(payloads) != 1 || fmt.Sprint(payloads[0]) != fmt.Sprint(want) {
+			t.Errorf("payloads = %v, want %v", payloads, want)
+		}
+
+		status = http.StatusForbidden
+		if err := notifier.Notify(context.Background(), announcement); err == nil || !strings.Contains(err.Error(), "403") {
+			t.Errorf("Notify() error = %v, want status code 403", err)
+		}
+	})
+}
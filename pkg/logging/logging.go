@@ -0,0 +1,90 @@
+// Package logging provides the shared log/slog setup of the go-scripts commands
+package logging
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Supported log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Options holds the logging flags shared by all commands
+type Options struct {
+	Format  string
+	Verbose bool
+	Quiet   bool
+}
+
+// NewOptions returns the default options, logging text at info level
+func NewOptions() *Options {
+	return &Options{Format: FormatText}
+}
+
+// AddFlags registers the logging flags on fs
+func (o *Options) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Format, "log-format", o.Format, "Log format: text or json")
+	fs.BoolVar(&o.Verbose, "verbose", o.Verbose, "Log debug messages")
+	fs.BoolVar(&o.Quiet, "quiet", o.Quiet, "Log only warnings and errors")
+}
+
+// Validate ensures that the options are consistent
+func (o *Options) Validate() error {
+	if o.Format != FormatText && o.Format != FormatJSON {
+		return fmt.Errorf("invalid log format %q: must be %s or %s", o.Format, FormatText, FormatJSON)
+	}
+	if o.Verbose && o.Quiet {
+		return fmt.Errorf("verbose and quiet are mutually exclusive")
+	}
+	return nil
+}
+
+// Level returns the minimum level to log
+func (o *Options) Level() slog.Level {
+	switch {
+	case o.Verbose:
+		return slog.LevelDebug
+	case o.Quiet:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// NewLogger creates a logger writing to w according to the options
+func (o *Options) NewLogger(w io.Writer) (*slog.Logger, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: o.Level()}
+	if o.Format == FormatJSON {
+		return slog.New(slog.NewJSONHandler(w, handlerOpts)), nil
+	}
+	return slog.New(slog.NewTextHandler(w, handlerOpts)), nil
+}
+
+// Setup creates a logger writing to stderr and makes it the default logger.
+// Invalid options are reported on stderr and exit the process.
+func (o *Options) Setup() *slog.Logger {
+	logger, err := o.NewLogger(os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	slog.SetDefault(logger)
+	return logger
+}
+
+// Fatal logs err at error level and exits with a non-zero code
+func Fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
+}
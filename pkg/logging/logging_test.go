@@ -0,0 +1,89 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      Options
+		wantDebug bool
+		wantInfo  bool
+		wantErr   bool
+	}{
+		{
+			name:     "default",
+			opts:     Options{Format: FormatText},
+			wantInfo: true,
+		},
+		{
+			name:      "verbose",
+			opts:      Options{Format: FormatText, Verbose: true},
+			wantDebug: true,
+			wantInfo:  true,
+		},
+		{
+			name: "quiet",
+			opts: Options{Format: FormatJSON, Quiet: true},
+		},
+		{
+			name:    "verbose and quiet",
+			opts:    Options{Format: FormatText, Verbose: true, Quiet: true},
+			wantErr: true,
+		},
+		{
+			name:    "unknown format",
+			opts:    Options{Format: "xml"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			logger, err := tt.opts.NewLogger(out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewLogger() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			logger.Debug("debug message")
+			logger.Info("info message")
+			logger.Warn("warn message")
+
+			if have := strings.Contains(out.String(), "debug message"); have != tt.wantDebug {
+				t.Errorf("debug logged = %v, want %v", have, tt.wantDebug)
+			}
+			if have := strings.Contains(out.String(), "info message"); have != tt.wantInfo {
+				t.Errorf("info logged = %v, want %v", have, tt.wantInfo)
+			}
+			if !strings.Contains(out.String(), "warn message") {
+				t.Errorf("warn message not logged")
+			}
+		})
+	}
+}
+
+func TestNewLoggerJSON(t *testing.T) {
+	out := &bytes.Buffer{}
+	logger, err := (&Options{Format: FormatJSON}).NewLogger(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Info("installed", "version", "1.24.1")
+
+	var record map[string]any
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("log output is not JSON: %v: %s", err, out.String())
+	}
+	if record["msg"] != "installed" || record["version"] != "1.24.1" {
+		t.Errorf("unexpected log record: %v", record)
+	}
+}
@@ -0,0 +1,113 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// cronMarker tags the crontab line of a schedule, so it can be found again
+func cronMarker(name string) string {
+	return "# go-scripts schedule: " + name
+}
+
+// CronLine renders the crontab entry running the command of spec. The
+// success exit codes of spec are mapped to 0.
+func CronLine(spec Spec) string {
+	quoted := make([]string, 0, len(spec.Command))
+	for _, arg := range spec.Command {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	command := strings.Join(quoted, " ")
+	if len(spec.SuccessExitCodes) > 0 {
+		command = fmt.Sprintf("%s; code=$?; case $code in %s) code=0;; esac; exit $code",
+			command, joinCodes(spec.SuccessExitCodes, "|"))
+	}
+	// cron turns unescaped % into newlines.
+	command = strings.ReplaceAll(command, "%", `\%`)
+	return fmt.Sprintf("@%s %s %s", spec.Interval, command, cronMarker(spec.Name))
+}
+
+// shellQuote quotes s for /bin/sh unless it is made of safe characters only
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:,+@") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// setCronLine returns crontab with the line of schedule name replaced by line.
+// An empty line removes the schedule. found reports whether it existed.
+func setCronLine(crontab, name, line string) (updated string, found bool) {
+	var lines []string
+	for _, l := range strings.Split(strings.TrimRight(crontab, "\n"), "\n") {
+		if strings.HasSuffix(l, cronMarker(name)) {
+			found = true
+			continue
+		}
+		if l != "" || len(lines) > 0 {
+			lines = append(lines, l)
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return "", found
+	}
+	return strings.Join(lines, "\n") + "\n", found
+}
+
+// readCrontab returns the user's crontab, empty if there is none yet
+func (s *Scheduler) readCrontab(ctx context.Context) (string, error) {
+	out, err := s.Run(ctx, nil, "crontab", "-l")
+	if err != nil {
+		if strings.Contains(string(out), "no crontab") {
+			return "", nil
+		}
+		return "", err
+	}
+	return string(out), nil
+}
+
+func (s *Scheduler) writeCrontab(ctx context.Context, crontab string) error {
+	_, err := s.Run(ctx, strings.NewReader(crontab), "crontab", "-")
+	return err
+}
+
+func (s *Scheduler) installCron(ctx context.Context, spec Spec) error {
+	crontab, err := s.readCrontab(ctx)
+	if err != nil {
+		return err
+	}
+
+	updated, _ := setCronLine(crontab, spec.Name, CronLine(spec))
+	return s.writeCrontab(ctx, updated)
+}
+
+func (s *Scheduler) statusCron(ctx context.Context, name string) (string, error) {
+	crontab, err := s.readCrontab(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(crontab, "\n") {
+		if strings.HasSuffix(line, cronMarker(name)) {
+			return fmt.Sprintf("cron job\n%s\n", line), nil
+		}
+	}
+	return "", ErrNotScheduled
+}
+
+func (s *Scheduler) removeCron(ctx context.Context, name string) error {
+	crontab, err := s.readCrontab(ctx)
+	if err != nil {
+		return err
+	}
+
+	updated, found := setCronLine(crontab, name, "")
+	if !found {
+		return ErrNotScheduled
+	}
+	return s.writeCrontab(ctx, updated)
+}
@@ -0,0 +1,175 @@
+// Package schedule installs unattended runs of a command as a systemd user
+// timer, falling back to a cron job where systemd isn't available
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Backend is the mechanism that runs the scheduled command
+type Backend string
+
+const (
+	// BackendAuto picks systemd if a user manager is running, cron otherwise
+	BackendAuto    Backend = "auto"
+	BackendSystemd Backend = "systemd"
+	BackendCron    Backend = "cron"
+)
+
+// Interval is how often the scheduled command runs. The values are understood
+// by systemd's OnCalendar and, prefixed with "@", by cron.
+type Interval string
+
+const (
+	Hourly Interval = "hourly"
+	Daily  Interval = "daily"
+	Weekly Interval = "weekly"
+)
+
+// ParseInterval parses hourly, daily or weekly
+func ParseInterval(s string) (Interval, error) {
+	switch interval := Interval(s); interval {
+	case Hourly, Daily, Weekly:
+		return interval, nil
+	default:
+		return "", fmt.Errorf("invalid interval %q: must be hourly, daily or weekly", s)
+	}
+}
+
+// ErrNotScheduled is returned if there is nothing scheduled under a name
+var ErrNotScheduled = errors.New("not scheduled")
+
+// Spec describes a scheduled command
+type Spec struct {
+	// Name identifies the schedule, it names the systemd units and marks the crontab line
+	Name string
+	// Description is a human readable summary of what the command does
+	Description string
+	// Command is the executable and its arguments
+	Command []string
+	// Interval is how often the command runs
+	Interval Interval
+	// SuccessExitCodes are non-zero exit codes that don't mean failure, e.g.
+	// the codes a check uses to report its result
+	SuccessExitCodes []int
+}
+
+// Validate ensures that the spec can be scheduled
+func (s *Spec) Validate() error {
+	if s.Name == "" || strings.ContainsAny(s.Name, "/ \t\n") {
+		return fmt.Errorf("invalid schedule name %q", s.Name)
+	}
+	if len(s.Command) == 0 || !filepath.IsAbs(s.Command[0]) {
+		return fmt.Errorf("scheduled command must start with an absolute path")
+	}
+	if _, err := ParseInterval(string(s.Interval)); err != nil {
+		return err
+	}
+	for _, code := range s.SuccessExitCodes {
+		if code < 1 || code > 255 {
+			return fmt.Errorf("invalid success exit code %d", code)
+		}
+	}
+	return nil
+}
+
+// Runner runs an external command with the given stdin, which may be nil, and
+// returns its combined output
+type Runner func(ctx context.Context, stdin io.Reader, name string, args ...string) ([]byte, error)
+
+// runCommand is the default Runner, executing commands on the host
+func runCommand(ctx context.Context, stdin io.Reader, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = stdin
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return out, fmt.Errorf("%s %s failed: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
+
+// Scheduler installs, inspects and removes schedules
+type Scheduler struct {
+	// Backend is systemd or cron
+	Backend Backend
+	// UnitDir is where systemd user units are written
+	UnitDir string
+	// Run executes systemctl and crontab
+	Run Runner
+}
+
+// New creates a scheduler for the given backend. BackendAuto uses systemd if a
+// user manager is reachable and cron otherwise.
+func New(ctx context.Context, backend Backend) (*Scheduler, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine config directory: %w", err)
+	}
+
+	s := &Scheduler{
+		Backend: backend,
+		UnitDir: filepath.Join(configDir, "systemd", "user"),
+		Run:     runCommand,
+	}
+
+	switch backend {
+	case BackendSystemd, BackendCron:
+	case BackendAuto, "":
+		s.Backend = s.detect(ctx)
+	default:
+		return nil, fmt.Errorf("unknown backend %q: must be auto, systemd or cron", backend)
+	}
+
+	return s, nil
+}
+
+// detect checks for a running systemd user manager
+func (s *Scheduler) detect(ctx context.Context) Backend {
+	if _, err := exec.LookPath("systemctl"); err != nil {
+		return BackendCron
+	}
+
+	// A degraded manager exits with an error but still runs timers.
+	out, err := s.Run(ctx, nil, "systemctl", "--user", "is-system-running")
+	if err == nil || strings.HasPrefix(string(out), "degraded") {
+		return BackendSystemd
+	}
+	return BackendCron
+}
+
+// Install schedules spec, replacing an existing schedule of the same name
+func (s *Scheduler) Install(ctx context.Context, spec Spec) error {
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+
+	if s.Backend == BackendSystemd {
+		return s.installSystemd(ctx, spec)
+	}
+	return s.installCron(ctx, spec)
+}
+
+// Status describes the schedule called name, returning ErrNotScheduled if
+// there is none.
+func (s *Scheduler) Status(ctx context.Context, name string) (string, error) {
+	if s.Backend == BackendSystemd {
+		return s.statusSystemd(ctx, name)
+	}
+	return s.statusCron(ctx, name)
+}
+
+// Remove removes the schedule called name, returning ErrNotScheduled if there
+// is none.
+func (s *Scheduler) Remove(ctx context.Context, name string) error {
+	if s.Backend == BackendSystemd {
+		return s.removeSystemd(ctx, name)
+	}
+	return s.removeCron(ctx, name)
+}
@@ -0,0 +1,243 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Update the golden files in testdata")
+
+var testSpec = Spec{
+	Name:        "updatego",
+	Description: "Update Go to the latest stable release",
+	Command:     []string{"/home/gopher/.local/bin/updatego", "-hook", "after-install=-notify-send 'Go $UPDATEGO_NEW_VERSION installed' 100%", "update"},
+	Interval:    Daily,
+}
+
+func assertGolden(t *testing.T, name, have string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, []byte(have), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+	}
+	if have != string(want) {
+		t.Errorf("%s differs from %s:\n--- have\n%s--- want\n%s", name, path, have, want)
+	}
+}
+
+// testCheckSpec reports its result with exit codes
+var testCheckSpec = Spec{
+	Name:             "updatego",
+	Description:      "Check for Go updates and known vulnerabilities",
+	Command:          []string{"/home/gopher/.local/bin/updatego", "-wait", "check"},
+	Interval:         Weekly,
+	SuccessExitCodes: []int{3, 4},
+}
+
+func TestUnitsGolden(t *testing.T) {
+	assertGolden(t, "updatego.service", ServiceUnit(testSpec))
+	assertGolden(t, "updatego.timer", TimerUnit(testSpec))
+	assertGolden(t, "updatego.cron", CronLine(testSpec)+"\n")
+	assertGolden(t, "updatego-check.service", ServiceUnit(testCheckSpec))
+	assertGolden(t, "updatego-check.cron", CronLine(testCheckSpec)+"\n")
+}
+
+func TestCronLineExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		code, want int
+	}{
+		{code: 0, want: 0},
+		{code: 1, want: 1},
+		{code: 3, want: 0},
+		{code: 4, want: 0},
+		{code: 5, want: 5},
+	} {
+		spec := testCheckSpec
+		spec.Command = []string{"/bin/sh", "-c", "exit " + strconv.Itoa(tt.code)}
+		line := CronLine(spec)
+		// cron runs the line after the schedule with /bin/sh.
+		command := strings.ReplaceAll(strings.SplitN(line, " ", 2)[1], `\%`, "%")
+		cmd := exec.Command("/bin/sh", "-c", command)
+		cmd.Dir = dir
+		err := cmd.Run()
+		code := 0
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			code = exitErr.ExitCode()
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if code != tt.want {
+			t.Errorf("exit code %d became %d, want %d", tt.code, code, tt.want)
+		}
+	}
+}
+
+func TestSpecValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Spec)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(*Spec) {}},
+		{name: "relative command", modify: func(s *Spec) { s.Command = []string{"updatego"} }, wantErr: true},
+		{name: "no command", modify: func(s *Spec) { s.Command = nil }, wantErr: true},
+		{name: "name with slash", modify: func(s *Spec) { s.Name = "../updatego" }, wantErr: true},
+		{name: "unknown interval", modify: func(s *Spec) { s.Interval = "monthly" }, wantErr: true},
+		{name: "success exit codes", modify: func(s *Spec) { s.SuccessExitCodes = []int{3, 4} }},
+		{name: "invalid success exit code", modify: func(s *Spec) { s.SuccessExitCodes = []int{0} }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := testSpec
+			tt.modify(&spec)
+			if err := spec.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSetCronLine(t *testing.T) {
+	other := "0 3 * * * /usr/bin/backup\n"
+	line := CronLine(testSpec)
+
+	installed, found := setCronLine(other, "updatego", line)
+	if found || installed != other+line+"\n" {
+		t.Fatalf("install: setCronLine() = %q, %t", installed, found)
+	}
+
+	replaced, found := setCronLine(installed, "updatego", strings.Replace(line, "@daily", "@weekly", 1))
+	if !found || strings.Count(replaced, "updatego") != strings.Count(installed, "updatego") || !strings.Contains(replaced, "@weekly") {
+		t.Fatalf("replace: setCronLine() = %q, %t", replaced, found)
+	}
+
+	removed, found := setCronLine(replaced, "updatego", "")
+	if !found || removed != other {
+		t.Fatalf("remove: setCronLine() = %q, %t", removed, found)
+	}
+
+	if empty, _ := setCronLine(line+"\n", "updatego", ""); empty != "" {
+		t.Errorf("removing the only line left %q", empty)
+	}
+}
+
+// fakeRunner records commands and emulates crontab
+type fakeRunner struct {
+	commands []string
+	crontab  *string
+}
+
+func (f *fakeRunner) run(ctx context.Context, stdin io.Reader, name string, args ...string) ([]byte, error) {
+	f.commands = append(f.commands, strings.Join(append([]string{name}, args...), " "))
+
+	if name != "crontab" {
+		return nil, nil
+	}
+	if stdin != nil {
+		content, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, err
+		}
+		crontab := string(content)
+		f.crontab = &crontab
+		return nil, nil
+	}
+	if f.crontab == nil {
+		return []byte("no crontab for gopher\n"), errors.New("exit status 1")
+	}
+	return []byte(*f.crontab), nil
+}
+
+func TestSchedulerSystemd(t *testing.T) {
+	ctx := context.Background()
+	runner := &fakeRunner{}
+	s := &Scheduler{Backend: BackendSystemd, UnitDir: t.TempDir(), Run: runner.run}
+
+	if _, err := s.Status(ctx, "updatego"); !errors.Is(err, ErrNotScheduled) {
+		t.Fatalf("Status() before install error = %v, want ErrNotScheduled", err)
+	}
+
+	if err := s.Install(ctx, testSpec); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	for _, name := range []string{"updatego.service", "updatego.timer"} {
+		if _, err := os.Stat(filepath.Join(s.UnitDir, name)); err != nil {
+			t.Errorf("unit %s not written: %v", name, err)
+		}
+	}
+
+	if _, err := s.Status(ctx, "updatego"); err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+
+	if err := s.Remove(ctx, "updatego"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if entries, _ := os.ReadDir(s.UnitDir); len(entries) != 0 {
+		t.Errorf("units left after Remove(): %v", entries)
+	}
+
+	want := []string{
+		"systemctl --user daemon-reload",
+		"systemctl --user enable --now updatego.timer",
+		"systemctl --user list-timers --all updatego.timer",
+		"systemctl --user disable --now updatego.timer",
+		"systemctl --user daemon-reload",
+	}
+	if !slices.Equal(runner.commands, want) {
+		t.Errorf("commands = %q, want %q", runner.commands, want)
+	}
+}
+
+func TestSchedulerCron(t *testing.T) {
+	ctx := context.Background()
+	runner := &fakeRunner{}
+	s := &Scheduler{Backend: BackendCron, Run: runner.run}
+
+	if err := s.Remove(ctx, "updatego"); !errors.Is(err, ErrNotScheduled) {
+		t.Fatalf("Remove() without crontab error = %v, want ErrNotScheduled", err)
+	}
+
+	if err := s.Install(ctx, testSpec); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	// Installing again replaces the line.
+	if err := s.Install(ctx, testSpec); err != nil {
+		t.Fatalf("Install() again error = %v", err)
+	}
+	if *runner.crontab != CronLine(testSpec)+"\n" {
+		t.Errorf("crontab = %q", *runner.crontab)
+	}
+
+	status, err := s.Status(ctx, "updatego")
+	if err != nil || !strings.Contains(status, "@daily") {
+		t.Fatalf("Status() = %q, %v", status, err)
+	}
+
+	if err := s.Remove(ctx, "updatego"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := s.Status(ctx, "updatego"); !errors.Is(err, ErrNotScheduled) {
+		t.Errorf("Status() after remove error = %v, want ErrNotScheduled", err)
+	}
+}
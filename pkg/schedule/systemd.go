@@ -0,0 +1,138 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ServiceUnit renders the oneshot service running the command of spec
+func ServiceUnit(spec Spec) string {
+	var b strings.Builder
+	fmt.Fprintln(&b, "# Generated by go-scripts, manual changes are overwritten")
+	fmt.Fprintln(&b, "[Unit]")
+	fmt.Fprintf(&b, "Description=%s\n", spec.Description)
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "[Service]")
+	fmt.Fprintln(&b, "Type=oneshot")
+	fmt.Fprintf(&b, "ExecStart=%s\n", systemdCommandLine(spec.Command))
+	if len(spec.SuccessExitCodes) > 0 {
+		fmt.Fprintf(&b, "SuccessExitStatus=%s\n", joinCodes(spec.SuccessExitCodes, " "))
+	}
+	return b.String()
+}
+
+// TimerUnit renders the timer starting the service of spec. Missed runs are
+// caught up after boot and runs are spread to not hit the servers at once.
+func TimerUnit(spec Spec) string {
+	var b strings.Builder
+	fmt.Fprintln(&b, "# Generated by go-scripts, manual changes are overwritten")
+	fmt.Fprintln(&b, "[Unit]")
+	fmt.Fprintf(&b, "Description=Run %s %s\n", spec.Name, spec.Interval)
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "[Timer]")
+	fmt.Fprintf(&b, "OnCalendar=%s\n", spec.Interval)
+	fmt.Fprintln(&b, "RandomizedDelaySec=15m")
+	fmt.Fprintln(&b, "Persistent=true")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "[Install]")
+	fmt.Fprintln(&b, "WantedBy=timers.target")
+	return b.String()
+}
+
+// joinCodes joins exit codes with sep
+func joinCodes(codes []int, sep string) string {
+	strs := make([]string, 0, len(codes))
+	for _, code := range codes {
+		strs = append(strs, strconv.Itoa(code))
+	}
+	return strings.Join(strs, sep)
+}
+
+// systemdCommandLine quotes args for ExecStart, escaping specifiers and
+// variable expansion
+func systemdCommandLine(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		arg = strings.ReplaceAll(arg, "%", "%%")
+		arg = strings.ReplaceAll(arg, "$", "$$")
+		if arg != "" && !strings.ContainsAny(arg, " \t\"'\\;") {
+			quoted = append(quoted, arg)
+			continue
+		}
+		arg = strings.ReplaceAll(arg, `\`, `\\`)
+		arg = strings.ReplaceAll(arg, `"`, `\"`)
+		quoted = append(quoted, `"`+arg+`"`)
+	}
+	return strings.Join(quoted, " ")
+}
+
+func (s *Scheduler) unitPaths(name string) (service, timer string) {
+	return filepath.Join(s.UnitDir, name+".service"), filepath.Join(s.UnitDir, name+".timer")
+}
+
+func (s *Scheduler) systemctl(ctx context.Context, args ...string) ([]byte, error) {
+	return s.Run(ctx, nil, "systemctl", append([]string{"--user"}, args...)...)
+}
+
+func (s *Scheduler) installSystemd(ctx context.Context, spec Spec) error {
+	if err := os.MkdirAll(s.UnitDir, 0755); err != nil {
+		return fmt.Errorf("failed to create unit directory: %w", err)
+	}
+
+	service, timer := s.unitPaths(spec.Name)
+	if err := os.WriteFile(service, []byte(ServiceUnit(spec)), 0644); err != nil {
+		return fmt.Errorf("failed to write service unit: %w", err)
+	}
+	if err := os.WriteFile(timer, []byte(TimerUnit(spec)), 0644); err != nil {
+		return fmt.Errorf("failed to write timer unit: %w", err)
+	}
+
+	if _, err := s.systemctl(ctx, "daemon-reload"); err != nil {
+		return err
+	}
+	if _, err := s.systemctl(ctx, "enable", "--now", filepath.Base(timer)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Scheduler) statusSystemd(ctx context.Context, name string) (string, error) {
+	_, timer := s.unitPaths(name)
+	if _, err := os.Stat(timer); errors.Is(err, fs.ErrNotExist) {
+		return "", ErrNotScheduled
+	}
+
+	out, err := s.systemctl(ctx, "list-timers", "--all", filepath.Base(timer))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("systemd timer %s\n%s", timer, out), nil
+}
+
+func (s *Scheduler) removeSystemd(ctx context.Context, name string) error {
+	service, timer := s.unitPaths(name)
+	if _, err := os.Stat(timer); errors.Is(err, fs.ErrNotExist) {
+		return ErrNotScheduled
+	}
+
+	if _, err := s.systemctl(ctx, "disable", "--now", filepath.Base(timer)); err != nil {
+		return err
+	}
+
+	for _, path := range []string{timer, service} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+
+	_, err := s.systemctl(ctx, "daemon-reload")
+	return err
+}
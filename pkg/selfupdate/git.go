@@ -0,0 +1,93 @@
+package selfupdate
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GitSource builds go-scripts from a local git checkout
+type GitSource struct {
+	// Dir is the root of the checkout
+	Dir string
+	// Pull fast-forwards the checkout before looking for a newer version
+	Pull bool
+	// Run executes git and go
+	Run Runner
+}
+
+func (s *GitSource) git(ctx context.Context, args ...string) (string, error) {
+	out, err := s.Run(ctx, s.Dir, nil, "git", args...)
+	return strings.TrimSpace(string(out)), err
+}
+
+// Latest returns the commit checked out, after pulling if configured
+func (s *GitSource) Latest(ctx context.Context) (*Version, error) {
+	if s.Pull {
+		if _, err := s.git(ctx, "pull", "--ff-only"); err != nil {
+			return nil, err
+		}
+	}
+
+	out, err := s.git(ctx, "log", "-1", "--format=%H %ct")
+	if err != nil {
+		return nil, err
+	}
+	revision, timestamp, _ := strings.Cut(out, " ")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse commit time %q: %w", timestamp, err)
+	}
+
+	status, err := s.git(ctx, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Version{
+		Revision: revision,
+		Time:     time.Unix(seconds, 0).UTC(),
+		Modified: status != "",
+	}, nil
+}
+
+// Newer reports whether the checkout is ahead of the current build. Builds
+// without a revision, e.g. from a tarball, are always considered older.
+func (s *GitSource) Newer(ctx context.Context, current, latest *Version) (bool, error) {
+	if current.Revision == "" {
+		return true, nil
+	}
+	if current.Revision == latest.Revision {
+		// Uncommitted changes can't be compared, rebuild to be sure.
+		return current.Modified || latest.Modified, nil
+	}
+
+	// The build is older if its commit is an ancestor of the checkout.
+	_, err := s.git(ctx, "merge-base", "--is-ancestor", current.Revision, latest.Revision)
+	return err == nil, nil
+}
+
+// Changes lists the commits from current to latest
+func (s *GitSource) Changes(ctx context.Context, current, latest *Version) ([]string, error) {
+	revisions := latest.Revision
+	if current.Revision != "" {
+		revisions = current.Revision + ".." + latest.Revision
+	}
+
+	out, err := s.git(ctx, "log", "--oneline", "--no-decorate", revisions)
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// Install builds all commands of the checkout into gobin
+func (s *GitSource) Install(ctx context.Context, _ *Version, gobin string) error {
+	_, err := s.Run(ctx, s.Dir, buildEnv(gobin), "go", "install", "-trimpath", "./cmd/...")
+	return err
+}
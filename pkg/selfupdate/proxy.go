@@ -0,0 +1,206 @@
+package selfupdate
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProxySource installs go-scripts from a module proxy directory, i.e. a
+// directory in the GOPROXY layout as served by file:// URLs
+type ProxySource struct {
+	// Dir is the root of the proxy, containing github.com/ibihim/go-scripts/@v
+	Dir string
+	// Run executes go
+	Run Runner
+}
+
+func (s *ProxySource) versionDir() string {
+	return filepath.Join(s.Dir, filepath.FromSlash(ModulePath), "@v")
+}
+
+// versions returns the released versions in the proxy, oldest first
+func (s *ProxySource) versions() ([]string, error) {
+	content, err := os.ReadFile(filepath.Join(s.versionDir(), "list"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read version list: %w", err)
+	}
+
+	var versions []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if v := strings.TrimSpace(line); v != "" {
+			if _, ok := parseSemver(v); !ok {
+				return nil, fmt.Errorf("invalid version %q in version list", v)
+			}
+			versions = append(versions, v)
+		}
+	}
+	slices.SortFunc(versions, compareSemver)
+
+	return versions, nil
+}
+
+// Latest returns the highest version in the proxy
+func (s *ProxySource) Latest(ctx context.Context) (*Version, error) {
+	versions, err := s.versions()
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("no versions of %s in %s", ModulePath, s.Dir)
+	}
+
+	latest := &Version{Version: versions[len(versions)-1]}
+
+	// The .info file is optional for our purposes, it only adds the time.
+	if content, err := os.ReadFile(filepath.Join(s.versionDir(), latest.Version+".info")); err == nil {
+		var info struct {
+			Time time.Time
+		}
+		if json.Unmarshal(content, &info) == nil {
+			latest.Time = info.Time
+		}
+	}
+
+	return latest, nil
+}
+
+// Newer compares module versions. Local builds without a module version are
+// always considered older.
+func (s *ProxySource) Newer(_ context.Context, current, latest *Version) (bool, error) {
+	if current.Version == "" {
+		return true, nil
+	}
+	if _, ok := parseSemver(current.Version); !ok {
+		return false, fmt.Errorf("invalid current version %q", current.Version)
+	}
+	return compareSemver(current.Version, latest.Version) < 0, nil
+}
+
+// Changes lists the versions after current up to latest
+func (s *ProxySource) Changes(_ context.Context, current, latest *Version) ([]string, error) {
+	versions, err := s.versions()
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []string
+	for _, v := range versions {
+		if (current.Version == "" || compareSemver(v, current.Version) > 0) && compareSemver(v, latest.Version) <= 0 {
+			changes = append(changes, v)
+		}
+	}
+	return changes, nil
+}
+
+// Install builds all commands of latest from the proxy into gobin. The
+// checksum database can't know private versions, so it is skipped for the module.
+func (s *ProxySource) Install(ctx context.Context, latest *Version, gobin string) error {
+	proxy := url.URL{Scheme: "file", Path: filepath.ToSlash(s.Dir)}
+	env := append(buildEnv(gobin),
+		"GOPROXY="+proxy.String(),
+		"GONOSUMDB="+ModulePath,
+		"GOFLAGS=",
+	)
+
+	// Run outside of any module, so that go install ignores local go.mod files.
+	_, err := s.Run(ctx, os.TempDir(), env, "go", "install", "-trimpath", ModulePath+"/cmd/...@"+latest.Version)
+	return err
+}
+
+// semver is a parsed semantic version
+type semver struct {
+	core       [3]int
+	prerelease []string
+}
+
+// parseSemver parses versions like v1.2.3, v1.2.3-rc.1 and pseudo-versions
+func parseSemver(v string) (semver, bool) {
+	var parsed semver
+
+	rest, ok := strings.CutPrefix(v, "v")
+	if !ok {
+		return parsed, false
+	}
+	rest, _, _ = strings.Cut(rest, "+")
+	core, prerelease, hasPrerelease := strings.Cut(rest, "-")
+
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return parsed, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return parsed, false
+		}
+		parsed.core[i] = n
+	}
+
+	if hasPrerelease {
+		if prerelease == "" {
+			return parsed, false
+		}
+		parsed.prerelease = strings.Split(prerelease, ".")
+	}
+
+	return parsed, true
+}
+
+// compareSemver orders versions by semver precedence, invalid versions first
+func compareSemver(a, b string) int {
+	va, okA := parseSemver(a)
+	vb, okB := parseSemver(b)
+	if !okA || !okB {
+		return boolCompare(okA, okB)
+	}
+
+	for i := range va.core {
+		if va.core[i] != vb.core[i] {
+			return cmp.Compare(va.core[i], vb.core[i])
+		}
+	}
+
+	// A release ranks above its prereleases.
+	if va.prerelease == nil || vb.prerelease == nil {
+		return boolCompare(va.prerelease == nil, vb.prerelease == nil)
+	}
+
+	for i := 0; i < len(va.prerelease) && i < len(vb.prerelease); i++ {
+		pa, pb := va.prerelease[i], vb.prerelease[i]
+		na, errA := strconv.Atoi(pa)
+		nb, errB := strconv.Atoi(pb)
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				return cmp.Compare(na, nb)
+			}
+		case errA == nil || errB == nil:
+			// Numeric identifiers rank below alphanumeric ones.
+			return boolCompare(errA != nil, errB != nil)
+		case pa != pb:
+			return strings.Compare(pa, pb)
+		}
+	}
+	return cmp.Compare(len(va.prerelease), len(vb.prerelease))
+}
+
+// boolCompare orders false before true
+func boolCompare(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case b:
+		return -1
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,192 @@
+// Package selfupdate rebuilds the go-scripts commands from a newer version of
+// a local git checkout or module proxy directory
+package selfupdate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// ModulePath is the module the commands are built from
+const ModulePath = "github.com/ibihim/go-scripts"
+
+// Version identifies a build of go-scripts. Depending on the source either
+// Version or Revision is set.
+type Version struct {
+	// Version is the module version, e.g. "v1.2.0", empty for local builds
+	Version string
+	// Revision is the git commit the build is from
+	Revision string
+	// Time is the time of the commit or version
+	Time time.Time
+	// Modified is set if the build had uncommitted changes
+	Modified bool
+}
+
+// String returns the version, or the abbreviated revision if there is none
+func (v *Version) String() string {
+	switch {
+	case v.Version != "":
+		return v.Version
+	case v.Revision != "":
+		rev := v.Revision
+		if len(rev) > 12 {
+			rev = rev[:12]
+		}
+		if v.Modified {
+			rev += "+dirty"
+		}
+		return rev
+	default:
+		return "unknown"
+	}
+}
+
+// Current returns the version of the running binary
+func Current() (*Version, error) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil, fmt.Errorf("binary has no build information")
+	}
+	return versionFromBuildInfo(info), nil
+}
+
+func versionFromBuildInfo(info *debug.BuildInfo) *Version {
+	v := &Version{}
+	if info.Main.Version != "" && info.Main.Version != "(devel)" {
+		v.Version = info.Main.Version
+	}
+
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			v.Revision = setting.Value
+		case "vcs.time":
+			v.Time, _ = time.Parse(time.RFC3339, setting.Value)
+		case "vcs.modified":
+			v.Modified = setting.Value == "true"
+		}
+	}
+
+	return v
+}
+
+// Source provides newer versions of go-scripts
+type Source interface {
+	// Latest returns the newest version available
+	Latest(ctx context.Context) (*Version, error)
+	// Newer reports whether latest is newer than current
+	Newer(ctx context.Context, current, latest *Version) (bool, error)
+	// Changes describes what changed from current to latest, one entry per
+	// commit or version
+	Changes(ctx context.Context, current, latest *Version) ([]string, error)
+	// Install builds and installs all commands of latest into gobin
+	Install(ctx context.Context, latest *Version, gobin string) error
+}
+
+// Runner runs a command in dir with extra environment variables and returns
+// its combined output
+type Runner func(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error)
+
+// runCommand is the default Runner, executing commands on the host
+func runCommand(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return out, fmt.Errorf("%s %s failed: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
+
+// buildEnv makes go install use the toolchain found on PATH, even if go.mod
+// asks for a newer one
+func buildEnv(gobin string) []string {
+	return []string{"GOBIN=" + gobin, "GOTOOLCHAIN=local"}
+}
+
+// NewSource detects whether path is a git checkout of go-scripts or a module
+// proxy directory containing it
+func NewSource(path string) (Source, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source %q: %w", path, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+		return &GitSource{Dir: path, Run: runCommand}, nil
+	}
+	if _, err := os.Stat(filepath.Join(path, ModulePath, "@v", "list")); err == nil {
+		return &ProxySource{Dir: path, Run: runCommand}, nil
+	}
+
+	return nil, fmt.Errorf("%s is neither a git checkout nor a module proxy directory containing %s", path, ModulePath)
+}
+
+// Result describes a completed self-update
+type Result struct {
+	Current *Version
+	Latest  *Version
+	// Available is set if Latest is newer than Current
+	Available bool
+	// Changes lists the commits or versions between Current and Latest
+	Changes []string
+	// Updated is set if Latest was installed
+	Updated bool
+}
+
+// Update installs the latest version of source into gobin if it is newer than
+// current. With dryRun it only reports what would change.
+func Update(ctx context.Context, source Source, current *Version, gobin string, dryRun bool) (*Result, error) {
+	latest, err := source.Latest(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine latest version: %w", err)
+	}
+
+	result := &Result{Current: current, Latest: latest}
+
+	result.Available, err = source.Newer(ctx, current, latest)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Available {
+		return result, nil
+	}
+
+	result.Changes, err = source.Changes(ctx, current, latest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine changes: %w", err)
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	if err := source.Install(ctx, latest, gobin); err != nil {
+		return nil, fmt.Errorf("failed to install %s: %w", latest, err)
+	}
+	result.Updated = true
+
+	return result, nil
+}
+
+// Print writes a summary of the result to w
+func (r *Result) Print(w io.Writer) {
+	fmt.Fprintf(w, "Current version: %s\n", r.Current)
+	fmt.Fprintf(w, "Latest version: %s\n", r.Latest)
+
+	if len(r.Changes) > 0 {
+		fmt.Fprintln(w, "Changes:")
+		for _, change := range r.Changes {
+			fmt.Fprintf(w, "  %s\n", change)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package selfupdate
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestVersionFromBuildInfo(t *testing.T) {
+	tests := []struct {
+		name string
+		info *debug.BuildInfo
+		want string
+	}{
+		{
+			name: "module version",
+			info: &debug.BuildInfo{Main: debug.Module{Version: "v1.2.0"}},
+			want: "v1.2.0",
+		},
+		{
+			name: "local build",
+			info: &debug.BuildInfo{
+				Main: debug.Module{Version: "(devel)"},
+				Settings: []debug.BuildSetting{
+					{Key: "vcs.revision", Value: "0123456789abcdef0123"},
+					{Key: "vcs.modified", Value: "true"},
+				},
+			},
+			want: "0123456789ab+dirty",
+		},
+		{
+			name: "no information",
+			info: &debug.BuildInfo{Main: debug.Module{Version: "(devel)"}},
+			want: "unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if have := versionFromBuildInfo(tt.info).String(); have != tt.want {
+				t.Errorf("versionFromBuildInfo() = %q, want %q", have, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareSemver(t *testing.T) {
+	ordered := []string{
+		"invalid",
+		"v0.0.0-20240101000000-abcdefabcdef",
+		"v0.0.0-20250101000000-abcdefabcdef",
+		"v0.1.0",
+		"v1.0.0-rc.1",
+		"v1.0.0-rc.2",
+		"v1.0.0-rc.10",
+		"v1.0.0-rc.10.1",
+		"v1.0.0",
+		"v1.0.1+incompatible",
+		"v1.10.0",
+	}
+
+	for i := range ordered {
+		for j := range ordered {
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if have := compareSemver(ordered[i], ordered[j]); have != want {
+				t.Errorf("compareSemver(%q, %q) = %d, want %d", ordered[i], ordered[j], have, want)
+			}
+		}
+	}
+}
+
+// fakeRunner records commands and answers them from a map of command line
+// prefixes to output, failing unknown commands
+type fakeRunner struct {
+	commands []string
+	envs     [][]string
+	outputs  map[string]string
+}
+
+func (f *fakeRunner) run(_ context.Context, _ string, env []string, name string, args ...string) ([]byte, error) {
+	command := strings.Join(append([]string{name}, args...), " ")
+	f.commands = append(f.commands, command)
+	f.envs = append(f.envs, env)
+
+	for prefix, out := range f.outputs {
+		if strings.HasPrefix(command, prefix) {
+			return []byte(out), nil
+		}
+	}
+	return nil, errors.New("exit status 1")
+}
+
+func TestGitSource(t *testing.T) {
+	runner := &fakeRunner{outputs: map[string]string{
+		"git log -1":                        "bbbbbbbbbbbbbbbb 1735689600\n",
+		"git status":                        "",
+		"git merge-base --is-ancestor aaaa": "",
+		"git log --oneline":                 "bbbbbbb Fix things\nccccccc Add things\n",
+		"go install":                        "",
+	}}
+	source := &GitSource{Dir: "/src/go-scripts", Run: runner.run}
+	ctx := context.Background()
+
+	result, err := Update(ctx, source, &Version{Revision: "aaaa"}, "/bin", false)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if !result.Updated || !slices.Equal(result.Changes, []string{"bbbbbbb Fix things", "ccccccc Add things"}) {
+		t.Errorf("Update() = %+v", result)
+	}
+	if last := runner.commands[len(runner.commands)-1]; last != "go install -trimpath ./cmd/..." {
+		t.Errorf("last command = %q", last)
+	}
+	if env := runner.envs[len(runner.envs)-1]; !slices.Contains(env, "GOBIN=/bin") || !slices.Contains(env, "GOTOOLCHAIN=local") {
+		t.Errorf("go install env = %q", env)
+	}
+
+	// A build that isn't an ancestor of the checkout is not updated.
+	result, err = Update(ctx, source, &Version{Revision: "dddd"}, "/bin", false)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if result.Available || result.Updated {
+		t.Errorf("Update() of diverged build = %+v", result)
+	}
+}
+
+func writeTestProxy(t *testing.T, versions ...string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	versionDir := filepath.Join(dir, ModulePath, "@v")
+	if err := os.MkdirAll(versionDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(versionDir, "list"), []byte(strings.Join(versions, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info := `{"Version": "v1.2.0", "Time": "2025-01-01T00:00:00Z"}`
+	if err := os.WriteFile(filepath.Join(versionDir, "v1.2.0.info"), []byte(info), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestProxySource(t *testing.T) {
+	dir := writeTestProxy(t, "v1.0.0", "v1.2.0", "v1.1.0", "v1.2.0-rc.1")
+
+	source, err := NewSource(dir)
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+	proxy, ok := source.(*ProxySource)
+	if !ok {
+		t.Fatalf("NewSource() = %T, want *ProxySource", source)
+	}
+	runner := &fakeRunner{outputs: map[string]string{"go install": ""}}
+	proxy.Run = runner.run
+
+	result, err := Update(context.Background(), proxy, &Version{Version: "v1.0.0"}, "/bin", true)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if result.Latest.Version != "v1.2.0" || result.Latest.Time.IsZero() {
+		t.Errorf("Latest = %+v", result.Latest)
+	}
+	if want := []string{"v1.1.0", "v1.2.0-rc.1", "v1.2.0"}; !slices.Equal(result.Changes, want) {
+		t.Errorf("Changes = %q, want %q", result.Changes, want)
+	}
+	if result.Updated || len(runner.commands) != 0 {
+		t.Errorf("dry run installed: %+v, %q", result, runner.commands)
+	}
+
+	result, err = Update(context.Background(), proxy, &Version{Version: "v1.0.0"}, "/bin", false)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if !result.Updated || runner.commands[0] != "go install -trimpath "+ModulePath+"/cmd/...@v1.2.0" {
+		t.Errorf("Update() = %+v, commands %q", result, runner.commands)
+	}
+	if env := runner.envs[0]; !slices.Contains(env, "GOPROXY=file://"+filepath.ToSlash(dir)) || !slices.Contains(env, "GONOSUMDB="+ModulePath) {
+		t.Errorf("go install env = %q", env)
+	}
+
+	result, err = Update(context.Background(), proxy, &Version{Version: "v1.2.0"}, "/bin", false)
+	if err != nil || result.Available {
+		t.Errorf("Update() of latest version = %+v, %v", result, err)
+	}
+}
+
+func TestNewSourceUnknown(t *testing.T) {
+	if _, err := NewSource(t.TempDir()); err == nil {
+		t.Error("NewSource() expected error for an empty directory")
+	}
+}